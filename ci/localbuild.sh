@@ -44,6 +44,7 @@ driver_container_build_simple()
   docker build -t "${short_tag}" \
       --build-arg DRIVER_VERSION="${DRIVER_VERSION}" \
       --build-arg DRIVER_BRANCH="${DRIVER_BRANCH}" \
+      --build-context vgpu-util-src=vgpu/src \
       "${platform}"
 }
 
@@ -46,12 +46,20 @@ _gdrcopy_enabled() {
     return 1
 }
 
+# Run 'vgpu-util state compare' or 'vgpu-util state store' on the driver configuration: the driver version,
+# the module parameters in /drivers and the DRIVER_CONFIG_DIGEST env var of the driver config. The kernel module
+# type is given as requested, it is only resolved after the fast path check.
+_driver_state() {
+    vgpu-util state "${1}" --driver-version "${DRIVER_VERSION:-}" --kernel-type "" --kernel-release "${KERNEL_VERSION}" \
+        --feature "KERNEL_MODULE_TYPE=${KERNEL_MODULE_TYPE:-auto}" \
+        --feature "GPU_DIRECT_RDMA_ENABLED=${GPU_DIRECT_RDMA_ENABLED:-false}" \
+        --feature "DRIVER_CONFIG_DIGEST=${DRIVER_CONFIG_DIGEST:-}"
+}
+
 # Check if fast path should be used (driver already loaded with matching config)
-# Compares current digest from DRIVER_CONFIG_DIGEST env var with stored digest
+# Compares the configuration with the stored one and with the version and parameters of the loaded modules,
+# so that modules loaded by hand or by another driver are reloaded.
 _should_skip_kernel_module_reload() {
-    [ -f /sys/module/nvidia/refcnt ] && [ -f /run/nvidia/nvidia-driver.state ] || return 1
-    local current_digest="${DRIVER_CONFIG_DIGEST:-}"
-    [ -z "${current_digest}" ] && return 1
-    local stored_digest=$(cat /run/nvidia/nvidia-driver.state 2>/dev/null || echo "")
-    [ "${current_digest}" = "${stored_digest}" ]
+    [ -n "${DRIVER_CONFIG_DIGEST:-}" ] || return 1
+    _driver_state compare
 }
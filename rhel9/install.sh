@@ -39,13 +39,6 @@ dep_installer () {
         kmod
   fi
 
-  # Download unzboot as kernel images are compressed in the zboot format on RHEL 9 arm64
-  # unzboot is only available on the EPEL RPM repo
-  rpm --import  https://dl.fedoraproject.org/pub/epel/RPM-GPG-KEY-EPEL-9
-  dnf install -y https://dl.fedoraproject.org/pub/epel/epel-release-latest-9.noarch.rpm
-  dnf config-manager --enable epel
-  dnf install -y unzboot
-
   rm -rf /var/cache/yum/*
 }
 
@@ -11,12 +11,6 @@ export DIST="${3}"
 export LTS_KERNEL="${4}"
 export PLATFORM_SUFFIX="${5}"
 
-export REGCTL_VERSION=v0.11.5
-mkdir -p bin
-curl -sSLo bin/regctl https://github.com/regclient/regclient/releases/download/${REGCTL_VERSION}/regctl-linux-amd64
-chmod a+x bin/regctl
-export PATH=$(pwd)/bin:${PATH}
-
 # calculate kernel version of latest image
 prefix="kernel-version-${DRIVER_BRANCH}-${LTS_KERNEL}"
 suffix="${KERNEL_FLAVOR}-${DIST}"
@@ -29,15 +23,24 @@ if [ -n "$artifact_file" ]; then
     rm -f kernel_version.txt
 fi
 
-# calculate driver tag
-status_nvcr=0
-status_ghcr=0
+# look the driver image up in nvcr.io and ghcr.io for the platform
 PLATFORM=$(echo "${PLATFORM_SUFFIX}" | sed 's/-//')
 [ -z "$PLATFORM" ] && PLATFORM=amd64
-regctl manifest inspect nvcr.io/nvidia/driver:${DRIVER_BRANCH}-${KERNEL_VERSION}-${DIST} --platform=linux/${PLATFORM} > /dev/null 2>&1; status_nvcr=$?
-regctl manifest inspect ghcr.io/nvidia/driver:${DRIVER_BRANCH}-${KERNEL_VERSION}-${DIST} --platform=linux/${PLATFORM} > /dev/null 2>&1; status_ghcr=$?
+kernels_file=$(mktemp)
+jq -n --arg driver_branch "${DRIVER_BRANCH}" --arg flavor "${KERNEL_FLAVOR}" --arg dist "${DIST}" \
+    --arg lts_kernel "${LTS_KERNEL}" --arg platform "${PLATFORM}" --arg kernel_version "${KERNEL_VERSION:-}" \
+    '[{driver_branch: $driver_branch, flavor: $flavor, dist: $dist, lts_kernel: $lts_kernel, platform: $platform, kernel_version: $kernel_version}]' \
+    > "${kernels_file}"
+# a registry error counts as a missing image, as the image is then tested rather than skipped
+if plan=$(vgpu-util tags plan --kernels "${kernels_file}" --output json); then
+    missing=$(jq '.missing | length' <<<"${plan}")
+else
+    echo "WARNING: Failed to look up ${DRIVER_BRANCH}-${KERNEL_VERSION:-}-${DIST} for linux/${PLATFORM}"
+    missing=1
+fi
+rm -f "${kernels_file}"
 
-if [[ $status_nvcr -eq 0 || $status_ghcr -eq 0 ]]; then
+if [[ "${missing}" -eq 0 ]]; then
     export should_continue=false
 else
     export should_continue=true
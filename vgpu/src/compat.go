@@ -0,0 +1,235 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CatalogEvaluation is the result of evaluating every guest driver of a catalog
+// against a given vGPU host driver version and branch
+type CatalogEvaluation struct {
+	HostVersion string
+	HostBranch  string
+	// HostBranchInfo is the branch descriptor of the host driver branch
+	HostBranchInfo BranchDescriptor
+	// HostDriverInfo is the driver descriptor of the host driver, if listed in the catalog
+	HostDriverInfo DriverDescriptor
+	// GuestBranches is the number of guest branches passing the guest side branch rules
+	GuestBranches int
+	// Guests holds a verdict for every guest driver of the catalog, in catalog order
+	Guests []GuestDriverVerdict
+}
+
+// GuestDriverVerdict tells whether a guest driver is compatible and why not
+type GuestDriverVerdict struct {
+	Driver  DriverDescriptor
+	Reasons []string
+}
+
+// Compatible returns true if no catalog rule rejects the guest driver
+func (v GuestDriverVerdict) Compatible() bool {
+	return len(v.Reasons) == 0
+}
+
+// Compatible returns the guest drivers accepted by the catalog rules, in catalog order
+func (e *CatalogEvaluation) Compatible() []DriverDescriptor {
+	var drivers []DriverDescriptor
+	for _, verdict := range e.Guests {
+		if verdict.Compatible() {
+			drivers = append(drivers, verdict.Driver)
+		}
+	}
+	return drivers
+}
+
+// gpuRuleReason returns why allow / deny gpu lists reject the device, or an empty string.
+// GPU rules are not evaluated when no device is given.
+func gpuRuleReason(allow, deny []GPUDescriptor, pciDeviceInfo *PCIDeviceInfo) string {
+	if pciDeviceInfo == nil {
+		return ""
+	}
+	name := pciDeviceInfo.deviceID
+	if name == "" {
+		name = "with subsystem ID " + pciDeviceInfo.subsystemID
+	}
+	if len(allow) > 0 && !gpuListed(allow, pciDeviceInfo) {
+		return fmt.Sprintf("GPU %s is not in the allowed GPU list", name)
+	}
+	if len(deny) > 0 && gpuListed(deny, pciDeviceInfo) {
+		return fmt.Sprintf("GPU %s is in the denied GPU list", name)
+	}
+	return ""
+}
+
+// gpuListed returns whether a GPU list names the device. A device given by a single ID, e.g.
+// with 'host-info --gpu', is matched on that ID alone, as foundGPU requires both.
+func gpuListed(gpuList []GPUDescriptor, pciDeviceInfo *PCIDeviceInfo) bool {
+	if pciDeviceInfo.deviceID != "" && pciDeviceInfo.subsystemID != "" {
+		return foundGPU(gpuList, pciDeviceInfo)
+	}
+	for _, gpu := range gpuList {
+		if pciDeviceInfo.deviceID != "" && gpu.DevID != "" && normalizeDeviceID(gpu.DevID) == normalizeDeviceID(pciDeviceInfo.deviceID) {
+			return true
+		}
+		if pciDeviceInfo.subsystemID != "" && gpu.SSID != "" && normalizeDeviceID(gpu.SSID) == normalizeDeviceID(pciDeviceInfo.subsystemID) {
+			return true
+		}
+	}
+	return false
+}
+
+// cpuRuleReason returns why allow / deny cpu lists reject the guest CPU, or an empty string
+func cpuRuleReason(allow, deny []string) string {
+	if len(allow) > 0 && !foundCPU(allow) {
+		return fmt.Sprintf("CPU %s is not in the allowed CPU list", GuestCPU)
+	}
+	if len(deny) > 0 && foundCPU(deny) {
+		return fmt.Sprintf("CPU %s is in the denied CPU list", GuestCPU)
+	}
+	return ""
+}
+
+// EvaluateCatalog evaluates the catalog allow / deny and branch compatibility rules of every
+// guest driver against the given host driver version and branch. pciDeviceInfo is optional.
+func EvaluateCatalog(driverCatalog *VGPUDriverCatalog, hostVersion string, hostBranch string, pciDeviceInfo *PCIDeviceInfo) (*CatalogEvaluation, error) {
+	eval := &CatalogEvaluation{HostVersion: hostVersion, HostBranch: hostBranch}
+
+	// Process branch descriptors to select one that describes the installed host driver's branch, and
+	// the reason each guest branch is rejected, if any
+	guestBranchReasons := map[string]string{}
+	foundHostBranchInfo := false
+	for _, branch := range driverCatalog.Branch {
+		if branch.Type == "host" {
+			log.Debugf("checking host branch descriptor %s", branch.Name)
+			if branch.Name != hostBranch {
+				continue
+			}
+			if gpuRuleReason(branch.Allow.GPU, branch.Deny.GPU, pciDeviceInfo) != "" {
+				continue
+			}
+			if cpuRuleReason(branch.Allow.CPU, branch.Deny.CPU) != "" {
+				continue
+			}
+			if foundHostBranchInfo {
+				// the last matching descriptor is used, as before the catalog rules were shared
+				log.Warnf("Duplicate host branch info found for branch name %s", branch.Name)
+			}
+			eval.HostBranchInfo = branch
+			foundHostBranchInfo = true
+		} else if branch.Type == "guest" {
+			log.Debugf("checking guest branch descriptor %s", branch.Name)
+			reason := gpuRuleReason(branch.Allow.GPU, branch.Deny.GPU, pciDeviceInfo)
+			if reason == "" {
+				reason = cpuRuleReason(branch.Allow.CPU, branch.Deny.CPU)
+			}
+			if reason == "" && len(branch.Deny.Branch) > 0 && foundBranch(branch.Deny.Branch, hostBranch) {
+				log.Infof("host branch %s matches guest denied branch list for %s, ignore...", hostBranch, branch.Name)
+				reason = fmt.Sprintf("guest branch %s denies host branch %s", branch.Name, hostBranch)
+			}
+			if reason == "" && len(branch.Allow.Branch) > 0 && !foundBranch(branch.Allow.Branch, hostBranch) {
+				log.Infof("host branch %s doesn't match with guest allowed branch list for %s, ignore...", hostBranch, branch.Name)
+				reason = fmt.Sprintf("guest branch %s does not allow host branch %s", branch.Name, hostBranch)
+			}
+			if reason == "" {
+				eval.GuestBranches++
+			}
+			if previous, ok := guestBranchReasons[branch.Name]; ok && previous == "" {
+				// a previous descriptor of the same branch already accepted it
+				continue
+			}
+			guestBranchReasons[branch.Name] = reason
+		}
+	}
+
+	if !foundHostBranchInfo {
+		return nil, fmt.Errorf("Could not find matching host branch %s in catalog file", hostBranch)
+	}
+	log.Debugf("selected hostBranchInfo for %s", eval.HostBranchInfo.Name)
+
+	// Reject any guest branches made ineligible by the host branch's allow / deny lists.
+	for name, reason := range guestBranchReasons {
+		if reason != "" {
+			continue
+		}
+		if len(eval.HostBranchInfo.Allow.Branch) > 0 && !foundBranch(eval.HostBranchInfo.Allow.Branch, name) {
+			log.Debugf("Ignoring guest branch %s as not found in allowed list of host branch", name)
+			guestBranchReasons[name] = fmt.Sprintf("host branch %s does not allow guest branch %s", hostBranch, name)
+		} else if foundBranch(eval.HostBranchInfo.Deny.Branch, name) {
+			log.Debugf("Ignoring guest branch %s as found in denied list of host branch", name)
+			guestBranchReasons[name] = fmt.Sprintf("host branch %s denies guest branch %s", hostBranch, name)
+		}
+	}
+
+	// Find the driver descriptor of the host driver
+	for _, driver := range driverCatalog.Driver {
+		if driver.Type != "host" || driver.Branch != hostBranch || driver.Version != hostVersion {
+			continue
+		}
+		if cpuRuleReason(driver.Allow.CPU, driver.Deny.CPU) != "" {
+			continue
+		}
+		if gpuRuleReason(driver.Allow.GPU, driver.Deny.GPU, pciDeviceInfo) != "" {
+			continue
+		}
+		if eval.HostDriverInfo.Version != "" {
+			// already found driver info, log warning and skip
+			log.Warnf("Duplicate driver info found for branch name %s version %s", eval.HostDriverInfo.Branch, eval.HostDriverInfo.Version)
+			continue
+		}
+		eval.HostDriverInfo = driver
+	}
+
+	// Process guest driver descriptors
+	hostDriverInfo := eval.HostDriverInfo
+	for _, driver := range driverCatalog.Driver {
+		if driver.Type != "guest" {
+			continue
+		}
+		var reasons []string
+		if reason := cpuRuleReason(driver.Allow.CPU, driver.Deny.CPU); reason != "" {
+			reasons = append(reasons, reason)
+		}
+		if reason := gpuRuleReason(driver.Allow.GPU, driver.Deny.GPU, pciDeviceInfo); reason != "" {
+			reasons = append(reasons, reason)
+		}
+		if !foundLinuxOS(driver.OS) {
+			reasons = append(reasons, "driver is not supported on Linux")
+		}
+		if len(driver.Allow.Driver) > 0 && !foundDriver(driver.Allow.Driver, hostVersion) {
+			reasons = append(reasons, fmt.Sprintf("host driver %s is not in the allowed driver list", hostVersion))
+		}
+		if len(driver.Deny.Driver) > 0 && foundDriver(driver.Deny.Driver, hostVersion) {
+			reasons = append(reasons, fmt.Sprintf("host driver %s is in the denied driver list", hostVersion))
+		}
+		reason, ok := guestBranchReasons[driver.Branch]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("guest branch %s is not described in catalog file", driver.Branch))
+		} else if reason != "" {
+			reasons = append(reasons, reason)
+		}
+		if hostDriverInfo.Version != "" {
+			if len(hostDriverInfo.Allow.Driver) > 0 && !foundDriver(hostDriverInfo.Allow.Driver, driver.Version) {
+				reasons = append(reasons, fmt.Sprintf("host driver %s does not allow guest driver %s", hostVersion, driver.Version))
+			}
+			if len(hostDriverInfo.Deny.Driver) > 0 && foundDriver(hostDriverInfo.Deny.Driver, driver.Version) {
+				reasons = append(reasons, fmt.Sprintf("host driver %s denies guest driver %s", hostVersion, driver.Version))
+			}
+		}
+		eval.Guests = append(eval.Guests, GuestDriverVerdict{Driver: driver, Reasons: reasons})
+	}
+	return eval, nil
+}
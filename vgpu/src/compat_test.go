@@ -0,0 +1,313 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func guestDriver(version, branch, date string) DriverDescriptor {
+	return DriverDescriptor{Version: version, Branch: branch, Date: date, Type: "guest", OS: []string{"Linux"}}
+}
+
+func TestFindMatch(t *testing.T) {
+	a100 := &PCIDeviceInfo{deviceID: "0x20b0", subsystemID: "0x134f"}
+
+	baseCatalog := func() *VGPUDriverCatalog {
+		return &VGPUDriverCatalog{
+			Branch: []BranchDescriptor{
+				{Name: "r550", Type: "host"},
+				{Name: "r550", Type: "guest"},
+				{Name: "r535", Type: "guest"},
+			},
+			Driver: []DriverDescriptor{
+				guestDriver("535.183.01", "r535", "2024-06-01"),
+				guestDriver("550.90.07", "r550", "2024-06-04"),
+				guestDriver("550.54.15", "r550", "2024-02-22"),
+			},
+		}
+	}
+	allDrivers := []string{"535.183.01", "550.90.07", "550.54.15"}
+
+	testCases := []struct {
+		description string
+		catalog     func() *VGPUDriverCatalog
+		available   []string
+		device      *PCIDeviceInfo
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "first guest driver of the host branch",
+			catalog:     baseCatalog,
+			available:   allDrivers,
+			expected:    "550.90.07",
+		},
+		{
+			description: "unavailable guest drivers are skipped",
+			catalog:     baseCatalog,
+			available:   []string{"535.183.01", "550.54.15"},
+			expected:    "550.54.15",
+		},
+		{
+			description: "first compatible guest driver of another branch",
+			catalog:     baseCatalog,
+			available:   []string{"535.183.01"},
+			expected:    "535.183.01",
+		},
+		{
+			description: "no available driver",
+			catalog:     baseCatalog,
+			available:   []string{"470.256.02"},
+			expectError: true,
+		},
+		{
+			description: "no host branch in catalog",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[0].Name = "r535"
+				return c
+			},
+			available:   allDrivers,
+			expectError: true,
+		},
+		{
+			description: "no guest branch in catalog",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch = c.Branch[:1]
+				return c
+			},
+			available:   allDrivers,
+			expectError: true,
+		},
+		{
+			description: "guest branch denying the host branch",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[1].Deny.Branch = []string{"r550"}
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+		{
+			description: "guest branch not allowing the host branch",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[2].Allow.Branch = []string{"r535"}
+				return c
+			},
+			available:   []string{"535.183.01"},
+			expectError: true,
+		},
+		{
+			description: "host branch allow list restricting the guest branches",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[0].Allow.Branch = []string{"r535"}
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+		{
+			description: "host branch deny list",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[0].Deny.Branch = []string{"r550"}
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+		{
+			description: "duplicate host branch, the last descriptor is used",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch = append(c.Branch, BranchDescriptor{Name: "r550", Type: "host", Deny: DeniedBranch{Branch: []string{"r550"}}})
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+		{
+			description: "host driver denying a guest driver",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver = append(c.Driver, DriverDescriptor{Version: "550.90.05", Branch: "r550", Type: "host",
+					Deny: DenyDriverDescriptor{Driver: []Drivers{{Version: "550.90.07"}}}})
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "host driver allow list",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver = append(c.Driver, DriverDescriptor{Version: "550.90.05", Branch: "r550", Type: "host",
+					Allow: AllowDriverDescriptor{Driver: []Drivers{{Version: "535.183.01"}}}})
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+		{
+			description: "guest driver denying the host driver",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].Deny.Driver = []Drivers{{Version: "550.90.05"}}
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "guest driver not supported on Linux",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].OS = []string{"Windows"}
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "guest driver allowed on another CPU only",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].Allow.CPU = []string{"arm64"}
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "guest driver denying the device ID",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].Deny.GPU = []GPUDescriptor{{DevID: "0x20B0"}}
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "guest driver denying the subsystem ID of another device ID",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].Deny.GPU = []GPUDescriptor{{DevID: "0x2330", SSID: "0x134f"}}
+				return c
+			},
+			available: allDrivers,
+			expected:  "550.54.15",
+		},
+		{
+			description: "device ID alone denied without a subsystem ID",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Driver[1].Deny.GPU = []GPUDescriptor{{DevID: "0x20B0"}}
+				return c
+			},
+			available: allDrivers,
+			device:    &PCIDeviceInfo{deviceID: "0x20b0"},
+			expected:  "550.54.15",
+		},
+		{
+			description: "device ID alone allowed without a subsystem ID",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[0].Allow.GPU = []GPUDescriptor{{DevID: "0x20b0", SSID: "0x1450"}}
+				c.Driver[1].Allow.GPU = []GPUDescriptor{{DevID: "0x2330"}}
+				return c
+			},
+			available: allDrivers,
+			device:    &PCIDeviceInfo{deviceID: "20b0"},
+			expected:  "550.54.15",
+		},
+		{
+			description: "guest branch allowing other GPUs only",
+			catalog: func() *VGPUDriverCatalog {
+				c := baseCatalog()
+				c.Branch[1].Allow.GPU = []GPUDescriptor{{DevID: "0x2330", SSID: "0x16c1"}}
+				return c
+			},
+			available: allDrivers,
+			expected:  "535.183.01",
+		},
+	}
+
+	savedVersion, savedBranch := hostDriverVersion, hostDriverBranch
+	defer func() { hostDriverVersion, hostDriverBranch = savedVersion, savedBranch }()
+	hostDriverVersion, hostDriverBranch = "550.90.05", "r550"
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			device := tc.device
+			if device == nil {
+				device = a100
+			}
+			version, err := FindMatch(tc.catalog(), tc.available, device)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got version %q", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version != tc.expected {
+				t.Errorf("expected version %q, got %q", tc.expected, version)
+			}
+		})
+	}
+}
+
+func TestEvaluateCatalogWithoutSubsystemID(t *testing.T) {
+	catalog := &VGPUDriverCatalog{
+		Branch: []BranchDescriptor{
+			{Name: "r550", Type: "host"},
+			{Name: "r550", Type: "guest"},
+		},
+		Driver: []DriverDescriptor{
+			guestDriver("550.90.07", "r550", "2024-06-04"),
+			guestDriver("550.54.15", "r550", "2024-02-22"),
+			guestDriver("550.54.14", "r550", "2024-02-20"),
+		},
+	}
+	catalog.Driver[0].Allow.GPU = []GPUDescriptor{{DevID: "0x20b0", SSID: "0x134f"}}
+	catalog.Driver[1].Deny.GPU = []GPUDescriptor{{DevID: "0x20B0"}}
+	catalog.Driver[2].Allow.GPU = []GPUDescriptor{{DevID: "0x2330"}}
+
+	// as given by 'host-info --gpu'
+	eval, err := EvaluateCatalog(catalog, "550.90.05", "r550", &PCIDeviceInfo{deviceID: "0x20b0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var verdicts []string
+	for _, verdict := range eval.Guests {
+		verdicts = append(verdicts, verdict.Driver.Version+": "+strings.Join(verdict.Reasons, "; "))
+	}
+	expected := []string{
+		"550.90.07: ",
+		"550.54.15: GPU 0x20b0 is in the denied GPU list",
+		"550.54.14: GPU 0x20b0 is not in the allowed GPU list",
+	}
+	if strings.Join(verdicts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected verdicts:\n  %s", strings.Join(verdicts, "\n  "))
+	}
+}
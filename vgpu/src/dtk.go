@@ -0,0 +1,447 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DTKStateVersion is the version of the driver toolkit handshake state file
+	DTKStateVersion = 1
+	// DefaultDTKSharedDirectory indicates default location of the directory shared with the driver toolkit container
+	DefaultDTKSharedDirectory = "/mnt/shared-nvidia-driver-toolkit"
+	// DTKStateFileName is the name of the handshake state file in the shared directory
+	DTKStateFileName = "dtk-state.json"
+	// DTKEnvFileName is the name of the environment file in the shared directory
+	DTKEnvFileName = "env.json"
+	// dtkPollInterval is the interval between two reads of the state file when inotify is not available,
+	// and a safety net for missed notifications otherwise
+	dtkPollInterval = 15 * time.Second
+	// dtkBuildLogLines is the number of build log lines shown when the build failed
+	dtkBuildLogLines = 20
+)
+
+// shellNameRegex matches the names the shell accepts for variables
+var shellNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// DTKPhase is a step of the handshake between the driver container and the driver toolkit container
+type DTKPhase string
+
+const (
+	// DTKPhasePrepared is set by the driver container once the shared directory is populated
+	DTKPhasePrepared DTKPhase = "prepared"
+	// DTKPhaseBuildStarted is set by the driver toolkit container when it starts building
+	DTKPhaseBuildStarted DTKPhase = "build-started"
+	// DTKPhaseBuilt is set by the driver toolkit container once the modules are available in the shared directory
+	DTKPhaseBuilt DTKPhase = "built"
+	// DTKPhaseBroken is set by the driver toolkit container when its image does not match the node kernel
+	DTKPhaseBroken DTKPhase = "broken"
+	// DTKPhaseFailed is set by the driver toolkit container when the build failed
+	DTKPhaseFailed DTKPhase = "failed"
+)
+
+// dtkPhases lists the phases of a successful handshake in order
+var dtkPhases = []DTKPhase{DTKPhasePrepared, DTKPhaseBuildStarted, DTKPhaseBuilt}
+
+func (p DTKPhase) index() int {
+	for i, phase := range dtkPhases {
+		if phase == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// terminalFailure returns true for the phases ending the handshake without modules
+func (p DTKPhase) terminalFailure() bool {
+	return p == DTKPhaseBroken || p == DTKPhaseFailed
+}
+
+// DTKState is the versioned handshake state file
+type DTKState struct {
+	Version int      `json:"version"`
+	Phase   DTKPhase `json:"phase"`
+	Error   string   `json:"error,omitempty"`
+	// BuildLog is the path of the build log in the shared directory
+	BuildLog string `json:"buildLog,omitempty"`
+	// Components lists the additional modules built, e.g. nvidia-fs or gdrcopy
+	Components []string  `json:"components,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+type dtkOptions struct {
+	sharedDirectory string
+	phase           string
+	errorMessage    string
+	buildLog        string
+	components      cli.StringSlice
+	timeout         time.Duration
+	field           string
+}
+
+func newDTKCommand() *cli.Command {
+	opts := dtkOptions{}
+
+	sharedDirectoryFlag := &cli.StringFlag{
+		Name:        "shared-directory",
+		Usage:       "Directory shared between the driver and the driver toolkit containers",
+		Value:       DefaultDTKSharedDirectory,
+		Destination: &opts.sharedDirectory,
+		EnvVars:     []string{"DRIVER_TOOLKIT_SHARED_DIR"},
+	}
+	phaseFlag := &cli.StringFlag{
+		Name:        "phase",
+		Usage:       "Handshake phase, one of prepared, build-started, built, broken or failed",
+		Required:    true,
+		Destination: &opts.phase,
+	}
+
+	// Create the 'dtk set' subcommand
+	set := cli.Command{}
+	set.Name = "set"
+	set.Usage = "Record the handshake phase"
+	set.UsageText = "--phase [--error] [--build-log] [--component]"
+	set.Action = func(c *cli.Context) error {
+		return SetDTKPhase(c, &opts)
+	}
+	set.Flags = []cli.Flag{
+		sharedDirectoryFlag,
+		phaseFlag,
+		&cli.StringFlag{
+			Name:        "error",
+			Usage:       "Reason of a broken or failed build",
+			Destination: &opts.errorMessage,
+		},
+		&cli.StringFlag{
+			Name:        "build-log",
+			Usage:       "Build log, relative to the shared directory",
+			Destination: &opts.buildLog,
+		},
+		&cli.StringSliceFlag{
+			Name:        "component",
+			Usage:       "Additional module built, e.g. nvidia-fs or gdrcopy",
+			Destination: &opts.components,
+		},
+	}
+
+	// Create the 'dtk get' subcommand
+	get := cli.Command{}
+	get.Name = "get"
+	get.Usage = "Print the handshake state or one of its fields"
+	get.UsageText = "[--field]"
+	get.Action = func(c *cli.Context) error {
+		return GetDTKState(c, &opts)
+	}
+	get.Flags = []cli.Flag{
+		sharedDirectoryFlag,
+		&cli.StringFlag{
+			Name:        "field",
+			Usage:       "Only print this top level field",
+			Destination: &opts.field,
+		},
+	}
+
+	// Create the 'dtk wait' subcommand
+	wait := cli.Command{}
+	wait.Name = "wait"
+	wait.Usage = "Wait until the handshake reaches a phase, exit with 2 if the driver toolkit is broken and 1 if the build failed"
+	wait.UsageText = "--phase [--timeout]"
+	wait.Action = func(c *cli.Context) error {
+		return WaitDTKPhase(c, &opts)
+	}
+	wait.Flags = []cli.Flag{
+		sharedDirectoryFlag,
+		phaseFlag,
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Maximum time to wait, 0 to wait forever",
+			Destination: &opts.timeout,
+		},
+	}
+
+	// Create the 'dtk env save' and 'dtk env load' subcommands
+	save := cli.Command{}
+	save.Name = "save"
+	save.Usage = "Save the environment to the shared directory"
+	save.Action = func(c *cli.Context) error {
+		return SaveDTKEnv(c, &opts)
+	}
+	save.Flags = []cli.Flag{sharedDirectoryFlag}
+	load := cli.Command{}
+	load.Name = "load"
+	load.Usage = "Print the saved environment as shell assignments, to be evaluated with allexport set"
+	load.Action = func(c *cli.Context) error {
+		return LoadDTKEnv(c, &opts)
+	}
+	load.Flags = []cli.Flag{sharedDirectoryFlag}
+	env := cli.Command{}
+	env.Name = "env"
+	env.Usage = "Pass the environment of the driver container to the driver toolkit container"
+	env.Subcommands = []*cli.Command{&save, &load}
+
+	dtk := cli.Command{}
+	dtk.Name = "dtk"
+	dtk.Usage = "Coordinate the driver container with the OpenShift driver toolkit container"
+	dtk.Subcommands = []*cli.Command{&set, &get, &wait, &env}
+	return &dtk
+}
+
+// SetDTKPhase updates the handshake state file
+func SetDTKPhase(c *cli.Context, opts *dtkOptions) error {
+	phase := DTKPhase(opts.phase)
+	if phase.index() < 0 && !phase.terminalFailure() {
+		return fmt.Errorf("invalid phase %s", opts.phase)
+	}
+	stateFile := filepath.Join(opts.sharedDirectory, DTKStateFileName)
+	state, err := LoadDTKState(stateFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warnf("resetting unreadable handshake state: %v", err)
+	}
+	if state == nil || phase == DTKPhasePrepared {
+		state = &DTKState{}
+	}
+
+	state.Version = DTKStateVersion
+	state.Phase = phase
+	state.Error = opts.errorMessage
+	if opts.buildLog != "" {
+		state.BuildLog = opts.buildLog
+	}
+	for _, component := range opts.components.Value() {
+		if !containsString(state.Components, component) {
+			state.Components = append(state.Components, component)
+		}
+	}
+	sort.Strings(state.Components)
+	state.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(stateFile, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	log.Infof("driver toolkit handshake phase %s", phase)
+	return nil
+}
+
+// GetDTKState prints the handshake state file
+func GetDTKState(c *cli.Context, opts *dtkOptions) error {
+	stateFile := filepath.Join(opts.sharedDirectory, DTKStateFileName)
+	state, err := LoadDTKState(stateFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if opts.field == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	return printJSONField(data, opts.field, stateFile)
+}
+
+// WaitDTKPhase blocks until the handshake reaches the phase, or ends without modules
+func WaitDTKPhase(c *cli.Context, opts *dtkOptions) error {
+	phase := DTKPhase(opts.phase)
+	if phase.index() < 0 {
+		return fmt.Errorf("invalid phase %s, expected one of prepared, build-started or built", opts.phase)
+	}
+	stateFile := filepath.Join(opts.sharedDirectory, DTKStateFileName)
+
+	changes, err := watchDirectory(opts.sharedDirectory)
+	if err != nil {
+		log.Warnf("unable to watch %s, polling every %v: %v", opts.sharedDirectory, dtkPollInterval, err)
+	}
+	var timeout <-chan time.Time
+	if opts.timeout > 0 {
+		timeout = time.After(opts.timeout)
+	}
+	ticker := time.NewTicker(dtkPollInterval)
+	defer ticker.Stop()
+
+	current := DTKPhase("")
+	for {
+		state, err := LoadDTKState(stateFile)
+		if err != nil && !os.IsNotExist(err) {
+			log.Warnf("unable to read %s: %v", stateFile, err)
+		}
+		if state != nil {
+			if state.Phase.terminalFailure() {
+				return dtkFailure(opts.sharedDirectory, state)
+			}
+			if state.Phase.index() >= phase.index() {
+				fmt.Printf("%s Driver toolkit handshake reached phase %s\n", time.Now().Format(time.RFC3339), state.Phase)
+				return nil
+			}
+			if state.Phase != current {
+				current = state.Phase
+				fmt.Printf("%s Driver toolkit handshake in phase %s, waiting for %s ...\n", time.Now().Format(time.RFC3339), current, phase)
+			}
+		}
+
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+			}
+		case <-ticker.C:
+		case <-timeout:
+			return cli.Exit(fmt.Sprintf("timed out after %v waiting for driver toolkit phase %s", opts.timeout, phase), 1)
+		}
+	}
+}
+
+// dtkFailure reports why the driver toolkit container did not build the modules
+func dtkFailure(sharedDirectory string, state *DTKState) error {
+	reason := state.Error
+	if reason == "" {
+		reason = "no reason given"
+	}
+	if state.Phase == DTKPhaseBroken {
+		fmt.Printf("WARNING: broken driver toolkit detected: %s\n", reason)
+		return cli.Exit(fmt.Sprintf("driver toolkit broken: %s", reason), 2)
+	}
+
+	fmt.Printf("ERROR: driver toolkit build failed: %s\n", reason)
+	if state.BuildLog != "" {
+		buildLog := filepath.Join(sharedDirectory, state.BuildLog)
+		if data, err := os.ReadFile(buildLog); err == nil {
+			fmt.Printf("Last lines of %s:\n%s\n", buildLog, tailLines(string(data), dtkBuildLogLines))
+		}
+	}
+	return cli.Exit(fmt.Sprintf("driver toolkit build failed: %s", reason), 1)
+}
+
+// LoadDTKState reads the handshake state file
+func LoadDTKState(stateFile string) (*DTKState, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	state := &DTKState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", stateFile, err)
+	}
+	if state.Version != DTKStateVersion {
+		return nil, fmt.Errorf("unsupported version %d of %s", state.Version, stateFile)
+	}
+	return state, nil
+}
+
+// watchDirectory notifies of files created, written or renamed in the directory using inotify
+func watchDirectory(directory string) (<-chan struct{}, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := syscall.InotifyAddWatch(fd, directory, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO|syscall.IN_CREATE|syscall.IN_DELETE); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 4096)
+		for {
+			_, err := syscall.Read(fd, buf)
+			if err != nil {
+				if err == syscall.EINTR {
+					continue
+				}
+				log.Errorf("unable to read inotify events: %v", err)
+				close(changes)
+				return
+			}
+			select {
+			case changes <- struct{}{}:
+			default:
+				// a re-read is already pending
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// SaveDTKEnv writes the environment as a JSON object, so values need no quoting
+func SaveDTKEnv(c *cli.Context, opts *dtkOptions) error {
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(opts.sharedDirectory, DTKEnvFileName), append(data, '\n'), 0600)
+}
+
+// LoadDTKEnv prints the saved environment as single quoted shell assignments
+func LoadDTKEnv(c *cli.Context, opts *dtkOptions) error {
+	envFile := filepath.Join(opts.sharedDirectory, DTKEnvFileName)
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		return err
+	}
+	env := map[string]string{}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", envFile, err)
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !shellNameRegex.MatchString(key) {
+			log.Warnf("skipping environment variable %q, not a valid shell name", key)
+			continue
+		}
+		fmt.Printf("%s=%s\n", key, shellQuote(env[key]))
+	}
+	return nil
+}
+
+// shellQuote single quotes a value, closing and reopening the quotes around every escaped single quote
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
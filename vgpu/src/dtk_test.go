@@ -0,0 +1,201 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func setDTKPhase(t *testing.T, sharedDirectory string, phase DTKPhase, errorMessage string, buildLog string, components ...string) {
+	t.Helper()
+	opts := &dtkOptions{sharedDirectory: sharedDirectory, phase: string(phase), errorMessage: errorMessage, buildLog: buildLog}
+	for _, component := range components {
+		opts.components.Set(component)
+	}
+	if err := SetDTKPhase(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error setting phase %s: %v", phase, err)
+	}
+}
+
+func dtkExitCode(err error) int {
+	var exitCoder cli.ExitCoder
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+	return -1
+}
+
+func TestDTKPhaseOrdering(t *testing.T) {
+	dir := t.TempDir()
+	opts := &dtkOptions{sharedDirectory: dir, timeout: 100 * time.Millisecond}
+
+	// waiting without any state times out
+	opts.phase = string(DTKPhasePrepared)
+	captureStdout(t, func() {
+		if err := WaitDTKPhase(testContext(), opts); dtkExitCode(err) != 1 {
+			t.Errorf("expected a timeout with exit code 1, got %v", err)
+		}
+	})
+
+	setDTKPhase(t, dir, DTKPhasePrepared, "", "")
+	setDTKPhase(t, dir, DTKPhaseBuildStarted, "", "")
+	captureStdout(t, func() {
+		opts.phase = string(DTKPhasePrepared)
+		if err := WaitDTKPhase(testContext(), opts); err != nil {
+			t.Errorf("expected phase %s to be reached, got %v", opts.phase, err)
+		}
+		opts.phase = string(DTKPhaseBuilt)
+		if err := WaitDTKPhase(testContext(), opts); dtkExitCode(err) != 1 {
+			t.Errorf("expected a timeout waiting for phase %s, got %v", opts.phase, err)
+		}
+	})
+
+	// the built phase is noticed by a waiter started before it
+	opts.timeout = 5 * time.Second
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		built := &dtkOptions{sharedDirectory: dir, phase: string(DTKPhaseBuilt)}
+		built.components.Set("nvidia-fs")
+		if err := SetDTKPhase(testContext(), built); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+	captureStdout(t, func() {
+		if err := WaitDTKPhase(testContext(), opts); err != nil {
+			t.Errorf("expected phase %s to be reached, got %v", opts.phase, err)
+		}
+	})
+	setDTKPhase(t, dir, DTKPhaseBuilt, "", "", "gdrcopy")
+
+	stateFile := filepath.Join(dir, DTKStateFileName)
+	state, err := LoadDTKState(stateFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Phase != DTKPhaseBuilt || strings.Join(state.Components, ",") != "gdrcopy,nvidia-fs" {
+		t.Errorf("expected phase built with both components, got %+v", state)
+	}
+
+	// preparing the shared directory again starts a new handshake
+	setDTKPhase(t, dir, DTKPhasePrepared, "", "")
+	if state, err = LoadDTKState(stateFile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state.Phase != DTKPhasePrepared || len(state.Components) != 0 {
+		t.Errorf("expected a reset state in phase prepared, got %+v", state)
+	}
+
+	opts.phase = "installed"
+	if err := SetDTKPhase(testContext(), opts); err == nil {
+		t.Errorf("expected an error for an invalid phase")
+	}
+}
+
+func TestWaitDTKPhaseFailure(t *testing.T) {
+	testCases := []struct {
+		phase    DTKPhase
+		exitCode int
+		output   string
+	}{
+		{
+			phase:    DTKPhaseBroken,
+			exitCode: 2,
+			output:   "WARNING: broken driver toolkit detected: no vmlinuz",
+		},
+		{
+			phase:    DTKPhaseFailed,
+			exitCode: 1,
+			output:   "make: *** [modules] Error 2",
+		},
+	}
+
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "build.log"), []byte("building nvidia.ko\nmake: *** [modules] Error 2\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		setDTKPhase(t, dir, DTKPhasePrepared, "", "")
+		setDTKPhase(t, dir, DTKPhaseBuildStarted, "", "")
+		errorMessage := "no vmlinuz"
+		if tc.phase == DTKPhaseFailed {
+			errorMessage = "driver toolkit build exited with status 1"
+		}
+		setDTKPhase(t, dir, tc.phase, errorMessage, "build.log")
+
+		opts := &dtkOptions{sharedDirectory: dir, phase: string(DTKPhaseBuilt), timeout: 5 * time.Second}
+		var err error
+		output := captureStdout(t, func() {
+			err = WaitDTKPhase(testContext(), opts)
+		})
+		if code := dtkExitCode(err); code != tc.exitCode {
+			t.Errorf("phase %s: expected exit code %d, got %d (%v)", tc.phase, tc.exitCode, code, err)
+		}
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("phase %s: expected output to contain %q, got %q", tc.phase, tc.output, output)
+		}
+	}
+}
+
+func TestDTKEnvRoundTrip(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	values := map[string]string{
+		"DTK_TEST_QUOTES":    `it's "quoted"`,
+		"DTK_TEST_NEWLINES":  "first line\nsecond line\n",
+		"DTK_TEST_EXPANSION": "$HOME ${PATH} $(false) `false` \\",
+		"DTK_TEST_EMPTY":     "",
+	}
+	for key, value := range values {
+		t.Setenv(key, value)
+	}
+	t.Setenv("DTK-TEST-INVALID", "skipped")
+
+	dir := t.TempDir()
+	opts := &dtkOptions{sharedDirectory: dir}
+	if err := SaveDTKEnv(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var loadErr error
+	assignments := captureStdout(t, func() {
+		loadErr = LoadDTKEnv(testContext(), opts)
+	})
+	if loadErr != nil {
+		t.Fatalf("unexpected error: %v", loadErr)
+	}
+	if strings.Contains(assignments, "DTK-TEST-INVALID") {
+		t.Errorf("expected the invalid shell name to be skipped, got %q", assignments)
+	}
+
+	for key, value := range values {
+		script := "set -o allexport\neval \"$1\"\nset +o allexport\nprintf '%s' \"${" + key + "}\""
+		output, err := exec.Command(bash, "-c", script, "bash", assignments).Output()
+		if err != nil {
+			t.Fatalf("%s: unexpected error evaluating the assignments: %v", key, err)
+		}
+		if string(output) != value {
+			t.Errorf("%s: expected %q, got %q", key, value, string(output))
+		}
+	}
+}
@@ -0,0 +1,459 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultFirmwareSearchPath indicates default location of the firmware of the driver container, as seen by the host
+	DefaultFirmwareSearchPath = "/run/nvidia/driver/lib/firmware"
+	// DefaultFirmwareStateFile indicates default location of the search path configured by 'firmware set'
+	DefaultFirmwareStateFile = "/run/nvidia/firmware-path.json"
+	// firmwareSearchPathParameter is the custom firmware search path of the kernel, relative to sysfs
+	firmwareSearchPathParameter = "module/firmware_class/parameters/path"
+	// gspFirmwareParam disables GSP firmware when set to 0
+	gspFirmwareParam = "NVreg_EnableGpuFirmware"
+)
+
+const (
+	// FirmwarePathUnset is reported when no custom search path is configured
+	FirmwarePathUnset = "unset"
+	// FirmwarePathConfigured is reported when the search path is the one of the driver container
+	FirmwarePathConfigured = "configured"
+	// FirmwarePathConflict is reported when another search path is configured
+	FirmwarePathConflict = "conflict"
+	// FirmwarePathDisabled is reported when GSP firmware is disabled and no search path is needed
+	FirmwarePathDisabled = "disabled"
+)
+
+// FirmwareReport is the state of the firmware search path for a driver version
+type FirmwareReport struct {
+	DriverVersion string `json:"driverVersion"`
+	GSPEnabled    bool   `json:"gspEnabled"`
+	// SearchPath is the path the driver container provides its firmware in
+	SearchPath string `json:"searchPath"`
+	// Expected lists the GSP firmware files of the driver version, relative to a search path
+	Expected []string `json:"expected"`
+	// Missing lists the expected files SearchPath lacks
+	Missing []string `json:"missing"`
+	// Configured is the search path currently set in the kernel
+	Configured string `json:"configured"`
+	// ConfiguredFirmware lists the driver versions the configured path has GSP firmware for
+	ConfiguredFirmware []string `json:"configuredFirmware"`
+	// ConfiguredMissing lists the expected files the configured path lacks
+	ConfiguredMissing []string `json:"configuredMissing"`
+	Status            string   `json:"status"`
+}
+
+// Found returns whether the kernel finds the GSP firmware of the driver version
+func (r *FirmwareReport) Found() bool {
+	switch r.Status {
+	case FirmwarePathDisabled:
+		return true
+	case FirmwarePathUnset:
+		return false
+	}
+	return len(r.ConfiguredMissing) == 0
+}
+
+// FirmwarePathState is the search path configured by 'firmware set' and the one it replaced
+type FirmwarePathState struct {
+	Path          string    `json:"path"`
+	Previous      string    `json:"previous"`
+	DriverVersion string    `json:"driverVersion"`
+	ConfiguredAt  time.Time `json:"configuredAt"`
+}
+
+type firmwareOptions struct {
+	sysfsRoot  string
+	searchPath string
+	// firmwareDirectory is where the firmware of the search path is read from, the search path if empty
+	firmwareDirectory string
+	driverVersion     string
+	paramsDirectory   string
+	moduleParams      cli.StringSlice
+	stateFile         string
+	output            string
+	force             bool
+}
+
+func newFirmwareCommand() *cli.Command {
+	opts := firmwareOptions{}
+
+	commonFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+		},
+		&cli.StringFlag{
+			Name:        "path",
+			Aliases:     []string{"p"},
+			Usage:       "Firmware search path of the driver",
+			Value:       DefaultFirmwareSearchPath,
+			Destination: &opts.searchPath,
+		},
+		&cli.StringFlag{
+			Name:        "firmware-directory",
+			Usage:       "Directory the firmware of the search path is read from, e.g. /lib/firmware before the driver rootfs is mounted on the search path",
+			Destination: &opts.firmwareDirectory,
+		},
+		&cli.StringFlag{
+			Name:        "driver-version",
+			Destination: &opts.driverVersion,
+			EnvVars:     []string{"DRIVER_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "input-directory",
+			Aliases:     []string{"i"},
+			Usage:       "Directory containing the <module>.conf parameter files, nvidia.conf may disable GSP firmware",
+			Value:       DefaultModuleParamsDirectory,
+			Destination: &opts.paramsDirectory,
+		},
+		&cli.StringSliceFlag{
+			Name:        "module-param",
+			Usage:       "Additional nvidia module parameter, e.g. NVreg_EnableGpuFirmware=0",
+			Destination: &opts.moduleParams,
+		},
+	}
+	stateFileFlag := &cli.StringFlag{
+		Name:        "state-file",
+		Aliases:     []string{"s"},
+		Usage:       "File recording the search path replaced by 'firmware set'",
+		Value:       DefaultFirmwareStateFile,
+		Destination: &opts.stateFile,
+	}
+
+	// Create the 'firmware check' subcommand
+	check := cli.Command{}
+	check.Name = "check"
+	check.Usage = "Check that the kernel finds the GSP firmware of the driver version"
+	check.UsageText = "[--sysfs-root] [-p | --path] [--firmware-directory] [--driver-version] [-i | --input-directory] [--module-param] [-o | --output]"
+	check.Action = func(c *cli.Context) error {
+		return CheckFirmware(c, &opts)
+	}
+	check.Flags = append(commonFlags, &cli.StringFlag{
+		Name:        "output",
+		Aliases:     []string{"o"},
+		Usage:       "Output format, env or json",
+		Value:       "env",
+		Destination: &opts.output,
+	})
+
+	// Create the 'firmware set' subcommand
+	set := cli.Command{}
+	set.Name = "set"
+	set.Usage = "Configure the firmware search path of the kernel, recording the path it replaces"
+	set.UsageText = "[--sysfs-root] [-p | --path] [--firmware-directory] [--driver-version] [-i | --input-directory] [--module-param] [-s | --state-file] [--force]"
+	set.Action = func(c *cli.Context) error {
+		return SetFirmwarePath(c, &opts)
+	}
+	set.Flags = append(append([]cli.Flag{}, commonFlags...), stateFileFlag, &cli.BoolFlag{
+		Name:        "force",
+		Usage:       "Replace a search path lacking the GSP firmware of the driver version",
+		Destination: &opts.force,
+		EnvVars:     []string{"FIRMWARE_PATH_FORCE"},
+	})
+
+	// Create the 'firmware restore' subcommand
+	restore := cli.Command{}
+	restore.Name = "restore"
+	restore.Usage = "Restore the firmware search path replaced by 'firmware set'"
+	restore.UsageText = "[--sysfs-root] [-s | --state-file]"
+	restore.Action = func(c *cli.Context) error {
+		return RestoreFirmwarePath(c, &opts)
+	}
+	restore.Flags = []cli.Flag{commonFlags[0], stateFileFlag}
+
+	firmware := cli.Command{}
+	firmware.Name = "firmware"
+	firmware.Usage = "Manage the firmware search path of the NVIDIA GSP firmware"
+	firmware.Subcommands = []*cli.Command{&check, &set, &restore}
+	return &firmware
+}
+
+// CheckFirmware reports whether the GSP firmware of the driver version is found in the configured
+// search path, and exits with 1 when it is not
+func CheckFirmware(c *cli.Context, opts *firmwareOptions) error {
+	log.Infof("Starting 'firmware check' with %v", c.App.Name)
+
+	report, err := inspectFirmware(opts)
+	if err != nil {
+		return err
+	}
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		fmt.Printf("FW_SEARCH_PATH=%s\n", shellQuote(report.SearchPath))
+		fmt.Printf("FW_SEARCH_PATH_CONFIGURED=%s\n", shellQuote(report.Configured))
+		fmt.Printf("FW_SEARCH_PATH_STATUS=%s\n", report.Status)
+		fmt.Printf("GSP_FIRMWARE_ENABLED=%t\n", report.GSPEnabled)
+		fmt.Printf("GSP_FIRMWARE_PRESENT=%t\n", len(report.Missing) == 0)
+		fmt.Printf("GSP_FIRMWARE_FOUND=%t\n", report.Found())
+		fmt.Printf("CONFIGURED_PATH_NVIDIA_FIRMWARE=%s\n", shellQuote(strings.Join(report.ConfiguredFirmware, " ")))
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+
+	if report.GSPEnabled && len(report.Missing) > 0 {
+		return cli.Exit(fmt.Sprintf("GSP firmware missing from %s: %s", report.SearchPath, strings.Join(report.Missing, ", ")), 1)
+	}
+	if !report.Found() {
+		return cli.Exit(firmwareNotFoundMessage(report), 1)
+	}
+
+	log.Infof("Completed 'firmware check' with %v, search path %s", c.App.Name, report.Status)
+	return nil
+}
+
+// SetFirmwarePath configures the search path of the driver container unless GSP firmware is
+// disabled. A search path already holding the GSP firmware of the driver version is retained,
+// any other one is only replaced with --force. Failing to write the kernel parameter is only a
+// warning, as the driver may still find its firmware.
+func SetFirmwarePath(c *cli.Context, opts *firmwareOptions) error {
+	log.Infof("Starting 'firmware set' with %v", c.App.Name)
+
+	report, err := inspectFirmware(opts)
+	if err != nil {
+		return err
+	}
+	switch report.Status {
+	case FirmwarePathDisabled:
+		fmt.Printf("GSP firmware is disabled with %s=0, not configuring a firmware search path\n", gspFirmwareParam)
+		return nil
+	case FirmwarePathConfigured:
+		fmt.Printf("Firmware search path is already configured: %s\n", report.Configured)
+		return nil
+	}
+	if len(report.Missing) > 0 {
+		return fmt.Errorf("GSP firmware missing from %s: %s", report.SearchPath, strings.Join(report.Missing, ", "))
+	}
+
+	parameterFile := filepath.Join(opts.sysfsRoot, firmwareSearchPathParameter)
+	if report.Status == FirmwarePathConflict {
+		fmt.Printf("WARNING: A search path is already configured in %s: %s\n", parameterFile, report.Configured)
+		if report.Found() {
+			fmt.Printf("         It contains the GSP firmware of driver %s, retaining the current configuration\n", report.DriverVersion)
+			return nil
+		}
+		if !opts.force {
+			return fmt.Errorf("%s, replace it with --force", firmwareNotFoundMessage(report))
+		}
+		fmt.Printf("         It lacks the GSP firmware of driver %s, replacing it\n", report.DriverVersion)
+	}
+
+	state := &FirmwarePathState{
+		Path:          report.SearchPath,
+		Previous:      report.Configured,
+		DriverVersion: report.DriverVersion,
+		ConfiguredAt:  time.Now().UTC(),
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the state is written first, a path set without it could not be restored
+	if err := writeFileAtomic(opts.stateFile, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Configuring the following firmware search path in '%s': %s\n", parameterFile, report.SearchPath)
+	if err := writeFirmwareSearchPath(parameterFile, report.SearchPath); err != nil {
+		os.Remove(opts.stateFile)
+		fmt.Printf("WARNING: Failed to configure the firmware search path: %v\n", err)
+		log.Warnf("unable to configure the firmware search path %s: %v", state.Path, err)
+		return nil
+	}
+
+	log.Infof("Completed 'firmware set' with %v, replaced %q with %q", c.App.Name, state.Previous, state.Path)
+	return nil
+}
+
+// RestoreFirmwarePath sets back the search path 'firmware set' replaced, unless it was
+// changed since
+func RestoreFirmwarePath(c *cli.Context, opts *firmwareOptions) error {
+	log.Infof("Starting 'firmware restore' with %v", c.App.Name)
+
+	data, err := os.ReadFile(opts.stateFile)
+	if os.IsNotExist(err) {
+		fmt.Println("No firmware search path to restore")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", opts.stateFile, err)
+	}
+	var state FirmwarePathState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", opts.stateFile, err)
+	}
+
+	parameterFile := filepath.Join(opts.sysfsRoot, firmwareSearchPathParameter)
+	current := readSysfsString(parameterFile)
+	if current != state.Path {
+		fmt.Printf("WARNING: The firmware search path was changed to %q since it was configured, not restoring %q\n", current, state.Previous)
+	} else {
+		fmt.Printf("Restoring the firmware search path in '%s': %q\n", parameterFile, state.Previous)
+		if err := writeFirmwareSearchPath(parameterFile, state.Previous); err != nil {
+			return fmt.Errorf("unable to restore the firmware search path: %v", err)
+		}
+	}
+	if err := os.Remove(opts.stateFile); err != nil {
+		return fmt.Errorf("unable to remove %s: %v", opts.stateFile, err)
+	}
+
+	log.Infof("Completed 'firmware restore' with %v", c.App.Name)
+	return nil
+}
+
+// inspectFirmware compares the configured search path with the one of the driver container
+func inspectFirmware(opts *firmwareOptions) (*FirmwareReport, error) {
+	if opts.driverVersion == "" {
+		return nil, fmt.Errorf("driver version is required")
+	}
+	enabled, err := gspFirmwareEnabled(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FirmwareReport{
+		DriverVersion: opts.driverVersion,
+		GSPEnabled:    enabled,
+		SearchPath:    filepath.Clean(opts.searchPath),
+		Expected:      ExpectedGSPFirmware(opts.driverVersion),
+		Configured:    readSysfsString(filepath.Join(opts.sysfsRoot, firmwareSearchPathParameter)),
+	}
+	firmwareDirectory := report.SearchPath
+	if opts.firmwareDirectory != "" {
+		firmwareDirectory = opts.firmwareDirectory
+	}
+	report.Missing = missingFiles(firmwareDirectory, report.Expected)
+
+	switch {
+	case !enabled:
+		report.Status = FirmwarePathDisabled
+	case report.Configured == "":
+		report.Status = FirmwarePathUnset
+	case filepath.Clean(report.Configured) == report.SearchPath:
+		report.Status = FirmwarePathConfigured
+	default:
+		report.Status = FirmwarePathConflict
+	}
+	switch report.Status {
+	case FirmwarePathConfigured:
+		report.ConfiguredFirmware = nvidiaFirmwareVersions(firmwareDirectory)
+		report.ConfiguredMissing = report.Missing
+	case FirmwarePathConflict:
+		report.ConfiguredFirmware = nvidiaFirmwareVersions(report.Configured)
+		report.ConfiguredMissing = missingFiles(report.Configured, report.Expected)
+	}
+	return report, nil
+}
+
+// gspFirmwareEnabled returns false when the nvidia module parameters disable GSP firmware
+func gspFirmwareEnabled(opts *firmwareOptions) (bool, error) {
+	params, err := loadModuleParamsFile(filepath.Join(opts.paramsDirectory, "nvidia.conf"))
+	if err != nil {
+		return false, err
+	}
+	for _, field := range opts.moduleParams.Value() {
+		param, err := ParseModuleParam(field)
+		if err != nil {
+			return false, err
+		}
+		param.Source = "--module-param"
+		params = append(params, param)
+	}
+	enabled := true
+	// the last value wins, as with modprobe
+	for _, param := range params {
+		if param.key() == gspFirmwareParam {
+			enabled = strings.Trim(param.Value, `"`) != "0"
+		}
+	}
+	return enabled, nil
+}
+
+// ExpectedGSPFirmware returns the GSP firmware files of a driver version, relative to a
+// firmware search path. R510 to R520 ship a single image, later branches one per GPU family.
+func ExpectedGSPFirmware(driverVersion string) []string {
+	// 580.65.06-grid installs its firmware as 580.65.06
+	version, _, _ := strings.Cut(driverVersion, "-")
+	dir := filepath.Join("nvidia", version)
+	if major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0]); err == nil && major < 525 {
+		return []string{filepath.Join(dir, "gsp.bin")}
+	}
+	return []string{filepath.Join(dir, "gsp_ga10x.bin"), filepath.Join(dir, "gsp_tu10x.bin")}
+}
+
+// missingFiles returns the files missing from a directory
+func missingFiles(dir string, files []string) []string {
+	missing := []string{}
+	for _, file := range files {
+		if !fileExists(filepath.Join(dir, file)) {
+			missing = append(missing, file)
+		}
+	}
+	return missing
+}
+
+// nvidiaFirmwareVersions returns the driver versions a search path has GSP firmware for
+func nvidiaFirmwareVersions(searchPath string) []string {
+	matches, _ := filepath.Glob(filepath.Join(searchPath, "nvidia", "*", "gsp*.bin"))
+	var versions []string
+	for _, match := range matches {
+		versions = append(versions, filepath.Base(filepath.Dir(match)))
+	}
+	versions = uniqueStrings(versions)
+	sort.Strings(versions)
+	return versions
+}
+
+func firmwareNotFoundMessage(report *FirmwareReport) string {
+	if report.Status == FirmwarePathUnset {
+		return "no firmware search path is configured, GSP firmware won't be found"
+	}
+	found := "no NVIDIA firmware"
+	if len(report.ConfiguredFirmware) > 0 {
+		found = "the GSP firmware of driver " + strings.Join(report.ConfiguredFirmware, ", ")
+	}
+	return fmt.Sprintf("the configured firmware search path %s has %s but not %s, GSP firmware won't be found",
+		report.Configured, found, report.DriverVersion)
+}
+
+// writeFirmwareSearchPath writes the kernel parameter in place, sysfs files can't be replaced.
+// An empty path is written as a newline, which the firmware loader strips.
+func writeFirmwareSearchPath(parameterFile string, path string) error {
+	if path == "" {
+		path = "\n"
+	}
+	return os.WriteFile(parameterFile, []byte(path), 0644)
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testFirmwareDriverVersion = "580.65.06"
+
+// writeFirmware installs the GSP firmware of a driver version in a search path
+func writeFirmware(t *testing.T, searchPath string, driverVersion string) {
+	t.Helper()
+	for _, file := range ExpectedGSPFirmware(driverVersion) {
+		if err := writeFileAtomic(filepath.Join(searchPath, file), []byte("gsp"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFirmwareSearchPath(t *testing.T) {
+	testCases := []struct {
+		description string
+		configured  string
+		// hostFirmware is the driver version the configured search path has GSP firmware for
+		hostFirmware string
+		params       string
+		status       string
+		found        bool
+		expectError  bool
+		// expected is the search path configured by 'firmware set'
+		expected string
+	}{
+		{description: "unset", status: FirmwarePathUnset, expected: "driver"},
+		{description: "configured", configured: "driver", status: FirmwarePathConfigured, found: true, expected: "driver"},
+		{description: "conflict with firmware", configured: "host", hostFirmware: testFirmwareDriverVersion, status: FirmwarePathConflict, found: true, expected: "host"},
+		{description: "conflict without firmware", configured: "host", hostFirmware: "570.172.08", status: FirmwarePathConflict, expectError: true, expected: "host"},
+		{description: "disabled", params: "NVreg_EnableGpuFirmware=0\n", status: FirmwarePathDisabled, found: true},
+	}
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		sysfsRoot := filepath.Join(dir, "sys")
+		parameterFile := filepath.Join(sysfsRoot, firmwareSearchPathParameter)
+		paths := map[string]string{"driver": filepath.Join(dir, "run", "nvidia", "driver", "lib", "firmware"), "host": filepath.Join(dir, "host", "firmware")}
+		// the firmware is read from the container before the driver rootfs is mounted on the search path
+		firmwareDirectory := filepath.Join(dir, "lib", "firmware")
+		writeFirmware(t, firmwareDirectory, testFirmwareDriverVersion)
+		if tc.hostFirmware != "" {
+			writeFirmware(t, paths["host"], tc.hostFirmware)
+		}
+		if err := writeFileAtomic(parameterFile, []byte(paths[tc.configured]+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paramsDirectory := filepath.Join(dir, "drivers")
+		if err := writeFileAtomic(filepath.Join(paramsDirectory, "nvidia.conf"), []byte(tc.params), 0644); err != nil {
+			t.Fatal(err)
+		}
+		opts := &firmwareOptions{
+			sysfsRoot:         sysfsRoot,
+			searchPath:        paths["driver"],
+			firmwareDirectory: firmwareDirectory,
+			driverVersion:     testFirmwareDriverVersion + "-grid",
+			paramsDirectory:   paramsDirectory,
+			stateFile:         filepath.Join(dir, "run", "nvidia", "firmware-path.json"),
+		}
+
+		report, err := inspectFirmware(opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.description, err)
+		}
+		if report.Status != tc.status || report.Found() != tc.found || len(report.Missing) != 0 {
+			t.Errorf("%s: unexpected report %+v", tc.description, report)
+		}
+
+		captureStdout(t, func() { err = SetFirmwarePath(testContext(), opts) })
+		if tc.expectError != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tc.description, err)
+		}
+		if configured := readSysfsString(parameterFile); configured != paths[tc.expected] {
+			t.Errorf("%s: expected search path %q, got %q", tc.description, paths[tc.expected], configured)
+		}
+		// only a replaced search path is restored
+		if _, err := os.Stat(opts.stateFile); (err == nil) != (tc.status == FirmwarePathUnset) {
+			t.Errorf("%s: unexpected state file, %v", tc.description, err)
+		}
+		captureStdout(t, func() { err = RestoreFirmwarePath(testContext(), opts) })
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+		}
+		if configured := readSysfsString(parameterFile); configured != paths[tc.configured] {
+			t.Errorf("%s: expected restored search path %q, got %q", tc.description, paths[tc.configured], configured)
+		}
+	}
+}
+
+func TestSetFirmwarePathWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	sysfsRoot := filepath.Join(dir, "sys")
+	// a directory can't be written, as a read-only sysfs
+	if err := os.MkdirAll(filepath.Join(sysfsRoot, firmwareSearchPathParameter), 0755); err != nil {
+		t.Fatal(err)
+	}
+	searchPath := filepath.Join(dir, "firmware")
+	writeFirmware(t, searchPath, testFirmwareDriverVersion)
+	opts := &firmwareOptions{
+		sysfsRoot:       sysfsRoot,
+		searchPath:      searchPath,
+		driverVersion:   testFirmwareDriverVersion,
+		paramsDirectory: filepath.Join(dir, "drivers"),
+		stateFile:       filepath.Join(dir, "firmware-path.json"),
+	}
+
+	var err error
+	output := captureStdout(t, func() { err = SetFirmwarePath(testContext(), opts) })
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "WARNING: Failed to configure the firmware search path") {
+		t.Errorf("expected a warning, got %q", output)
+	}
+	if _, err := os.Stat(opts.stateFile); !os.IsNotExist(err) {
+		t.Errorf("expected no state file, got %v", err)
+	}
+}
+
+func TestGSPFirmwareEnabled(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFileAtomic(filepath.Join(dir, "nvidia.conf"), []byte("NVreg_EnableGpuFirmware=0 NVreg_OpenRmEnableUnsupportedGpus=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		moduleParams []string
+		enabled      bool
+		expectError  bool
+	}{
+		{nil, false, false},
+		// the last value wins
+		{[]string{"NVreg_EnableGpuFirmware=1"}, true, false},
+		{[]string{`NVreg_EnableGpuFirmware="0"`}, false, false},
+		{[]string{"=1"}, false, true},
+	}
+	for _, tc := range testCases {
+		opts := &firmwareOptions{paramsDirectory: dir}
+		for _, param := range tc.moduleParams {
+			opts.moduleParams.Set(param)
+		}
+		enabled, err := gspFirmwareEnabled(opts)
+		if tc.expectError != (err != nil) || enabled != tc.enabled {
+			t.Errorf("%v: expected %t, got %t with error %v", tc.moduleParams, tc.enabled, enabled, err)
+		}
+	}
+}
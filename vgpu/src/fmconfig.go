@@ -0,0 +1,413 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultFabricManagerConfigFile indicates default location of the fabric manager config file
+	DefaultFabricManagerConfigFile = "/usr/share/nvidia/nvswitch/fabricmanager.cfg"
+	// FabricManagerEnvPrefix is the prefix of the environment variables overriding fabric manager config keys
+	FabricManagerEnvPrefix = "NVFM_CONFIG_"
+)
+
+var fmConfigKeyRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// fmValueKind is the type of value a fabric manager config key accepts
+type fmValueKind int
+
+const (
+	fmString fmValueKind = iota
+	fmInt
+	fmBool
+	fmIP
+)
+
+// fmKeySpec describes the values accepted for a known fabric manager config key
+type fmKeySpec struct {
+	kind     fmValueKind
+	min, max int64
+}
+
+// fmKnownKeys lists the fabric manager config keys and the values they accept
+var fmKnownKeys = map[string]fmKeySpec{
+	"LOG_LEVEL":                    {kind: fmInt, min: 0, max: 4},
+	"LOG_FILE_NAME":                {kind: fmString},
+	"LOG_APPEND_TO_LOG":            {kind: fmBool},
+	"LOG_FILE_MAX_SIZE":            {kind: fmInt, min: 1, max: 1 << 31},
+	"LOG_USE_SYSLOG":               {kind: fmBool},
+	"LOG_MAX_ROTATE_COUNT":         {kind: fmInt, min: 0, max: 1 << 31},
+	"DAEMONIZE":                    {kind: fmBool},
+	"BIND_INTERFACE_IP":            {kind: fmIP},
+	"STARTING_TCP_PORT":            {kind: fmInt, min: 1, max: 65535},
+	"UNIX_SOCKET_PATH":             {kind: fmString},
+	"FABRIC_MODE":                  {kind: fmInt, min: 0, max: 2},
+	"FABRIC_MODE_RESTART":          {kind: fmBool},
+	"STATE_FILE_NAME":              {kind: fmString},
+	"FM_CMD_BIND_INTERFACE":        {kind: fmIP},
+	"FM_CMD_PORT_NUMBER":           {kind: fmInt, min: 1, max: 65535},
+	"FM_CMD_UNIX_SOCKET_PATH":      {kind: fmString},
+	"FM_STAY_RESIDENT_ON_FAILURES": {kind: fmBool},
+	"ACCESS_LINK_FAILURE_MODE":     {kind: fmInt, min: 0, max: 1},
+	"TRUNK_LINK_FAILURE_MODE":      {kind: fmInt, min: 0, max: 1},
+	"NVSWITCH_FAILURE_MODE":        {kind: fmInt, min: 0, max: 1},
+	"ABORT_CUDA_JOBS_ON_FM_EXIT":   {kind: fmBool},
+	"TOPOLOGY_FILE_PATH":           {kind: fmString},
+	"DATABASE_PATH":                {kind: fmString},
+	"SIMULATION_MODE":              {kind: fmBool},
+}
+
+// FMConfigOverride is a value to set in the fabric manager config
+type FMConfigOverride struct {
+	Key   string
+	Value string
+	// Source is where the override comes from, used in messages
+	Source string
+}
+
+// FMConfig is a fabric manager config file, kept line by line so comments and ordering are preserved
+type FMConfig struct {
+	Lines []string
+}
+
+// FMConfigChange is a line replaced or appended by an override
+type FMConfigChange struct {
+	// Line is the 0-based index of the line in the original file, or -1 for an appended line
+	Line     int
+	Key      string
+	OldValue string
+	NewValue string
+}
+
+type fmConfigOptions struct {
+	configFile    string
+	overridesFile string
+	noEnv         bool
+	diff          bool
+	dryRun        bool
+}
+
+func newFMConfigCommand() *cli.Command {
+	opts := fmConfigOptions{}
+
+	// Create the 'fmconfig apply' subcommand
+	apply := cli.Command{}
+	apply.Name = "apply"
+	apply.Usage = "Apply validated overrides to the fabric manager config file"
+	apply.UsageText = "[-c | --config] [-f | --overrides] [--no-env] [--diff] [--dry-run]"
+	apply.Action = func(c *cli.Context) error {
+		return ApplyFMConfig(c, &opts)
+	}
+	apply.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Aliases:     []string{"c"},
+			Usage:       "Fabric manager config file",
+			Value:       DefaultFabricManagerConfigFile,
+			Destination: &opts.configFile,
+			EnvVars:     []string{"FM_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "overrides",
+			Aliases:     []string{"f"},
+			Usage:       "YAML file mapping config keys to values, overridden by " + FabricManagerEnvPrefix + "* environment variables",
+			Destination: &opts.overridesFile,
+		},
+		&cli.BoolFlag{
+			Name:        "no-env",
+			Usage:       "Ignore the " + FabricManagerEnvPrefix + "* environment variables",
+			Destination: &opts.noEnv,
+		},
+		&cli.BoolFlag{
+			Name:        "diff",
+			Usage:       "Print the changes as a unified diff",
+			Destination: &opts.diff,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Validate the overrides without writing the config file",
+			Destination: &opts.dryRun,
+		},
+	}
+
+	fmConfig := cli.Command{}
+	fmConfig.Name = "fmconfig"
+	fmConfig.Usage = "Manage the NVIDIA fabric manager config"
+	fmConfig.Subcommands = []*cli.Command{&apply}
+	return &fmConfig
+}
+
+// ApplyFMConfig applies the overrides from the YAML file and the environment to the fabric manager config file
+func ApplyFMConfig(c *cli.Context, opts *fmConfigOptions) error {
+	log.Infof("Starting 'fmconfig apply' with %v", c.App.Name)
+
+	var overrides []FMConfigOverride
+	if opts.overridesFile != "" {
+		fromFile, err := loadFMConfigOverrides(opts.overridesFile)
+		if err != nil {
+			return err
+		}
+		overrides = append(overrides, fromFile...)
+	}
+	if !opts.noEnv {
+		overrides = append(overrides, fmConfigOverridesFromEnv(os.Environ())...)
+	}
+	if len(overrides) == 0 {
+		log.Infof("No fabric manager config overrides")
+		return nil
+	}
+
+	info, err := os.Stat(opts.configFile)
+	if os.IsNotExist(err) {
+		fmt.Printf("WARNING: %s not found; cannot configure Fabric Manager\n", opts.configFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(opts.configFile)
+	if err != nil {
+		return fmt.Errorf("unable to read fabric manager config %s: %v", opts.configFile, err)
+	}
+	config := ParseFMConfig(string(data))
+	original := ParseFMConfig(string(data))
+
+	for _, override := range overrides {
+		if err := validateFMConfigValue(override.Key, override.Value); err != nil {
+			return fmt.Errorf("invalid fabric manager config override from %s: %v", override.Source, err)
+		}
+		if _, known := fmKnownKeys[override.Key]; !known {
+			log.Warnf("Unknown fabric manager config key %s from %s", override.Key, override.Source)
+			fmt.Printf("WARNING: unknown fabric manager config key %s\n", override.Key)
+		}
+	}
+	var changes []FMConfigChange
+	for _, override := range overrides {
+		fmt.Printf("Setting Fabric Manager config %s=%s in %s\n", override.Key, override.Value, opts.configFile)
+		changes = append(changes, config.Set(override.Key, override.Value)...)
+	}
+	// existing entries are validated as well, so a broken file fails here rather than at daemon start
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid fabric manager config %s: %v", opts.configFile, err)
+	}
+
+	if opts.diff {
+		fmt.Print(DiffFMConfig(opts.configFile, original, config))
+	}
+	if opts.dryRun || !config.changed(original) {
+		return nil
+	}
+	if err := writeFileAtomic(opts.configFile, []byte(config.String()), info.Mode().Perm()); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		log.Infof("set %s=%s (was %q) in %s", change.Key, change.NewValue, change.OldValue, opts.configFile)
+	}
+
+	log.Infof("Completed 'fmconfig apply' with %v", c.App.Name)
+	return nil
+}
+
+// loadFMConfigOverrides reads a YAML mapping of config keys to scalar values
+func loadFMConfigOverrides(filename string) ([]FMConfigOverride, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read fabric manager config overrides %s: %v", filename, err)
+	}
+	values := map[string]string{}
+	if err := yaml.UnmarshalStrict(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse fabric manager config overrides %s: %v", filename, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var overrides []FMConfigOverride
+	for _, key := range keys {
+		overrides = append(overrides, FMConfigOverride{Key: key, Value: values[key], Source: filename})
+	}
+	return overrides, nil
+}
+
+// fmConfigOverridesFromEnv returns the overrides given as NVFM_CONFIG_<KEY>=<value> environment variables
+func fmConfigOverridesFromEnv(environ []string) []FMConfigOverride {
+	var overrides []FMConfigOverride
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, FabricManagerEnvPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, FabricManagerEnvPrefix)
+		if key == "" {
+			fmt.Printf("WARNING: ignoring environment variable '%s=' with an empty config field name\n", name)
+			continue
+		}
+		overrides = append(overrides, FMConfigOverride{Key: key, Value: value, Source: "environment variable " + name})
+	}
+	sort.SliceStable(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	return overrides
+}
+
+// validateFMConfigValue checks a key is well formed and, for known keys, the type and range of its value
+func validateFMConfigValue(key string, value string) error {
+	if !fmConfigKeyRegex.MatchString(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("value of %s contains a line break", key)
+	}
+	spec, ok := fmKnownKeys[key]
+	if !ok {
+		return nil
+	}
+	switch spec.kind {
+	case fmInt, fmBool:
+		min, max := spec.min, spec.max
+		if spec.kind == fmBool {
+			min, max = 0, 1
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < min || n > max {
+			return fmt.Errorf("%s=%s is invalid, expected an integer from %d to %d", key, value, min, max)
+		}
+	case fmIP:
+		if value != "" && net.ParseIP(value) == nil {
+			return fmt.Errorf("%s=%s is invalid, expected an IP address", key, value)
+		}
+	}
+	return nil
+}
+
+// ParseFMConfig splits a fabric manager config into lines
+func ParseFMConfig(data string) *FMConfig {
+	data = strings.TrimSuffix(data, "\n")
+	if data == "" {
+		return &FMConfig{}
+	}
+	return &FMConfig{Lines: strings.Split(data, "\n")}
+}
+
+// parseFMConfigLine returns the key and value of a key=value line, comments and blank lines have no key.
+// Both are trimmed, so KEY = 1 and CRLF lines read like KEY=1, the raw line is only kept for rewriting.
+func parseFMConfigLine(line string) (string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(trimmed, "=")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(value), true
+}
+
+// lineEnding returns the carriage return ending the lines of a CRLF file, which Lines keep
+func (f *FMConfig) lineEnding() string {
+	if len(f.Lines) > 0 && strings.HasSuffix(f.Lines[0], "\r") {
+		return "\r"
+	}
+	return ""
+}
+
+// Set replaces the value of every entry of the key, or appends an entry if there is none
+func (f *FMConfig) Set(key string, value string) []FMConfigChange {
+	var changes []FMConfigChange
+	found := false
+	for i, line := range f.Lines {
+		k, v, ok := parseFMConfigLine(line)
+		if !ok || k != key {
+			continue
+		}
+		found = true
+		if v != value {
+			ending := ""
+			if strings.HasSuffix(line, "\r") {
+				ending = "\r"
+			}
+			f.Lines[i] = key + "=" + value + ending
+			changes = append(changes, FMConfigChange{Line: i, Key: key, OldValue: v, NewValue: value})
+		}
+	}
+	if found {
+		return changes
+	}
+	ending := f.lineEnding()
+	f.Lines = append(f.Lines, ending, key+"="+value+ending)
+	return []FMConfigChange{{Line: -1, Key: key, NewValue: value}}
+}
+
+// Validate checks the value of every known key
+func (f *FMConfig) Validate() error {
+	var invalid []string
+	for i, line := range f.Lines {
+		key, value, ok := parseFMConfigLine(line)
+		if !ok {
+			continue
+		}
+		if err := validateFMConfigValue(key, value); err != nil {
+			invalid = append(invalid, fmt.Sprintf("line %d: %v", i+1, err))
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+func (f *FMConfig) String() string {
+	if len(f.Lines) == 0 {
+		return ""
+	}
+	return strings.Join(f.Lines, "\n") + "\n"
+}
+
+func (f *FMConfig) changed(original *FMConfig) bool {
+	return f.String() != original.String()
+}
+
+// DiffFMConfig returns a unified diff of the changes made to the config. Overrides only
+// replace lines in place or append lines at the end, so every changed line is its own hunk.
+func DiffFMConfig(filename string, original *FMConfig, updated *FMConfig) string {
+	if !updated.changed(original) {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", filename, filename)
+	for i, line := range original.Lines {
+		if i < len(updated.Lines) && updated.Lines[i] != line {
+			fmt.Fprintf(&b, "@@ -%d +%d @@\n-%s\n+%s\n", i+1, i+1, line, updated.Lines[i])
+		}
+	}
+	if appended := updated.Lines[len(original.Lines):]; len(appended) > 0 {
+		fmt.Fprintf(&b, "@@ -%d,0 +%d,%d @@\n", len(original.Lines), len(original.Lines)+1, len(appended))
+		for _, line := range appended {
+			fmt.Fprintf(&b, "+%s\n", line)
+		}
+	}
+	return b.String()
+}
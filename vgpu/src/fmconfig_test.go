@@ -0,0 +1,103 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// stockFMConfig is an excerpt of the fabricmanager.cfg shipped with the driver, with the
+// spacing found in edited copies
+const stockFMConfig = `# NVIDIA Fabric Manager configuration file.
+# Note: This configuration file is read during Fabric Manager service startup. So, Fabric Manager
+# service restart is required for new settings to take effect.
+
+# Description: Fabric Manager logging levels
+# Possible Values:
+#    0  - All the logging is disabled
+#    1  - Set log level to CRITICAL and above
+#    2  - Set log level to ERROR and above
+#    3  - Set log level to WARNING and above
+#    4  - Set log level to INFO and above
+LOG_LEVEL=4 
+
+# Description: Filename for Fabric Manager logs
+LOG_FILE_NAME=/var/log/fabricmanager.log
+
+# Description: Fabric Manager Operating Mode
+FABRIC_MODE = 0
+
+# Description: Network interface to listen for Global and Local Fabric Manager communication
+BIND_INTERFACE_IP=127.0.0.1
+`
+
+func TestFMConfigValidate(t *testing.T) {
+	testCases := []struct {
+		description string
+		data        string
+		expectError bool
+	}{
+		{"stock config", stockFMConfig, false},
+		{"CRLF config", strings.ReplaceAll(stockFMConfig, "\n", "\r\n"), false},
+		{"value out of range", "LOG_LEVEL=5\n", true},
+		{"not an integer", "FABRIC_MODE=shared\n", true},
+		{"invalid IP address", "BIND_INTERFACE_IP=localhost\n", true},
+		{"unknown key", "FM_SOMETHING_NEW=1\n", false},
+		{"invalid key", "LOG LEVEL=1\n", true},
+	}
+	for _, tc := range testCases {
+		err := ParseFMConfig(tc.data).Validate()
+		if tc.expectError && err == nil {
+			t.Errorf("%s: expected an error", tc.description)
+		}
+		if !tc.expectError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+		}
+	}
+}
+
+func TestFMConfigSet(t *testing.T) {
+	config := ParseFMConfig(stockFMConfig)
+
+	// an equal value, ignoring the spacing, leaves the line untouched
+	if changes := config.Set("LOG_LEVEL", "4"); len(changes) != 0 {
+		t.Errorf("unexpected changes %v", changes)
+	}
+	changes := config.Set("FABRIC_MODE", "1")
+	if len(changes) != 1 || changes[0].OldValue != "0" || changes[0].NewValue != "1" {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	changes = config.Set("FABRIC_MODE_RESTART", "1")
+	if len(changes) != 1 || changes[0].Line != -1 {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+	expected := strings.Replace(stockFMConfig, "FABRIC_MODE = 0", "FABRIC_MODE=1", 1) + "\nFABRIC_MODE_RESTART=1\n"
+	if config.String() != expected {
+		t.Errorf("unexpected config:\n%s", config.String())
+	}
+	if !strings.Contains(config.String(), "LOG_LEVEL=4 \n") {
+		t.Errorf("untouched line rewritten")
+	}
+
+	crlf := ParseFMConfig(strings.ReplaceAll(stockFMConfig, "\n", "\r\n"))
+	crlf.Set("FABRIC_MODE", "1")
+	crlf.Set("FABRIC_MODE_RESTART", "1")
+	if expected := strings.ReplaceAll(expected, "\n", "\r\n"); crlf.String() != expected {
+		t.Errorf("CRLF line endings not kept:\n%q", crlf.String())
+	}
+	if err := crlf.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
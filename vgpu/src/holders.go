@@ -0,0 +1,282 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultProcRoot indicates default mount point of procfs
+	DefaultProcRoot = "/proc"
+	// NvidiaDevicePrefix is the prefix of the NVIDIA device nodes
+	NvidiaDevicePrefix = "/dev/nvidia"
+	// DefaultPodLogRoot indicates default location of the kubelet pod log directories, named <namespace>_<name>_<uid>
+	DefaultPodLogRoot = "/var/log/pods"
+)
+
+var (
+	// NVIDIA user space driver libraries, e.g. libcuda.so.550.90.07 or libnvidia-ml.so.1
+	nvidiaLibraryRegex = regexp.MustCompile(`/(libcuda|libnvidia-[a-z0-9-]+|libnvcuvid|libnvoptix|libGLX_nvidia|libEGL_nvidia|libGLESv2_nvidia)\.so[.0-9]*$`)
+	// container id as the last 64 hex digits of a cgroup path element, e.g.
+	// cri-containerd-<id>.scope, crio-<id>.scope, docker-<id>.scope or <id>
+	containerIDRegex = regexp.MustCompile(`(?:^|[-/])([0-9a-f]{64})(?:\.scope)?$`)
+	// pod uid of systemd (kubepods-besteffort-pod<uid>.slice) or cgroupfs (pod<uid>) cgroup drivers
+	podUIDRegex = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+)
+
+// GPUHolder is a process keeping NVIDIA device nodes open or NVIDIA libraries mapped
+type GPUHolder struct {
+	PID         int      `json:"pid"`
+	Command     string   `json:"command"`
+	Devices     []string `json:"devices,omitempty"`
+	Libraries   []string `json:"libraries,omitempty"`
+	Cgroup      string   `json:"cgroup,omitempty"`
+	ContainerID string   `json:"containerID,omitempty"`
+	PodUID      string   `json:"podUID,omitempty"`
+	// PodNamespace and PodName are resolved from the kubelet pod log directory of PodUID
+	PodNamespace string `json:"podNamespace,omitempty"`
+	PodName      string `json:"podName,omitempty"`
+}
+
+type holdersOptions struct {
+	procRoot   string
+	podLogRoot string
+	output     string
+}
+
+func newHoldersCommand() *cli.Command {
+	opts := holdersOptions{}
+
+	// Create the 'holders' subcommand
+	holders := cli.Command{}
+	holders.Name = "holders"
+	holders.Usage = "Report processes having NVIDIA device nodes open or NVIDIA libraries mapped"
+	holders.UsageText = "[--proc-root] [--pod-log-root] [-o | --output]"
+	holders.Action = func(c *cli.Context) error {
+		return Holders(c, &opts)
+	}
+	holders.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "proc-root",
+			Usage:       "Mount point of procfs, use the host procfs to see processes of other containers",
+			Value:       DefaultProcRoot,
+			Destination: &opts.procRoot,
+			EnvVars:     []string{"VGPU_PROC_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "pod-log-root",
+			Usage:       "Directory of the kubelet pod log directories, used to name the pods",
+			Value:       DefaultPodLogRoot,
+			Destination: &opts.podLogRoot,
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, table or json",
+			Value:       "table",
+			Destination: &opts.output,
+		},
+	}
+	return &holders
+}
+
+// Holders prints the processes holding the GPU
+func Holders(c *cli.Context, opts *holdersOptions) error {
+	log.Infof("Starting 'holders' with %v", c.App.Name)
+
+	holders, err := FindGPUHolders(opts.procRoot, opts.podLogRoot)
+	if err != nil {
+		return fmt.Errorf("unable to scan processes in %s: %v", opts.procRoot, err)
+	}
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(holders, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "PID\tCOMMAND\tCONTAINER\tPOD\tDEVICES\tLIBRARIES")
+		for _, holder := range holders {
+			containerID := holder.ContainerID
+			if len(containerID) > 12 {
+				containerID = containerID[:12]
+			}
+			pod := holder.PodUID
+			if holder.PodName != "" {
+				pod = holder.PodNamespace + "/" + holder.PodName
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\n", holder.PID, holder.Command, orDash(containerID), orDash(pod), orDash(strings.Join(holder.Devices, ",")), len(holder.Libraries))
+		}
+		w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+
+	log.Infof("Completed 'holders' with %v, found %d processes", c.App.Name, len(holders))
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// FindGPUHolders scans all processes under procRoot for open NVIDIA device nodes and mapped NVIDIA libraries
+func FindGPUHolders(procRoot string, podLogRoot string) ([]GPUHolder, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	var holders []GPUHolder
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		procDir := filepath.Join(procRoot, entry.Name())
+		devices := openNvidiaDevices(procDir)
+		libraries := mappedNvidiaLibraries(procDir)
+		if len(devices) == 0 && len(libraries) == 0 {
+			continue
+		}
+		holder := GPUHolder{PID: pid, Command: readSysfsString(filepath.Join(procDir, "comm")), Devices: devices, Libraries: libraries}
+		holder.Cgroup = readCgroupPath(procDir)
+		holder.ContainerID, holder.PodUID = parseCgroupPath(holder.Cgroup)
+		if holder.PodUID != "" {
+			holder.PodNamespace, holder.PodName = resolvePodName(podLogRoot, holder.PodUID)
+		}
+		holders = append(holders, holder)
+	}
+	sort.Slice(holders, func(i, j int) bool { return holders[i].PID < holders[j].PID })
+	return holders, nil
+}
+
+// openNvidiaDevices returns the NVIDIA device nodes opened by a process. Processes
+// exiting during the scan or not readable are ignored.
+func openNvidiaDevices(procDir string) []string {
+	fds, err := os.ReadDir(filepath.Join(procDir, "fd"))
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var devices []string
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(procDir, "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(target, NvidiaDevicePrefix) || seen[target] {
+			continue
+		}
+		seen[target] = true
+		devices = append(devices, target)
+	}
+	sort.Strings(devices)
+	return devices
+}
+
+// mappedNvidiaLibraries returns the NVIDIA libraries mapped by a process
+func mappedNvidiaLibraries(procDir string) []string {
+	maps, err := os.ReadFile(filepath.Join(procDir, "maps"))
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var libraries []string
+	for _, line := range strings.Split(string(maps), "\n") {
+		// address perms offset dev inode pathname
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		library := fields[5]
+		if seen[library] || !nvidiaLibraryRegex.MatchString(library) {
+			continue
+		}
+		seen[library] = true
+		libraries = append(libraries, library)
+	}
+	sort.Strings(libraries)
+	return libraries
+}
+
+// readCgroupPath returns the cgroup v2 path of a process, or the first v1 path found. On hybrid
+// hosts the unified hierarchy is usually left at "/" and the v1 controllers hold the container path.
+func readCgroupPath(procDir string) string {
+	data, err := os.ReadFile(filepath.Join(procDir, "cgroup"))
+	if err != nil {
+		return ""
+	}
+	var unified, fallback string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			unified = fields[2]
+			continue
+		}
+		if fallback == "" && fields[2] != "/" {
+			fallback = fields[2]
+		}
+	}
+	if unified != "" && (unified != "/" || fallback == "") {
+		return unified
+	}
+	return fallback
+}
+
+// parseCgroupPath extracts the container id and pod uid from a cgroup path
+func parseCgroupPath(cgroup string) (string, string) {
+	var containerID, podUID string
+	for _, element := range strings.Split(cgroup, "/") {
+		if m := containerIDRegex.FindStringSubmatch(element); m != nil {
+			containerID = m[1]
+		}
+		if m := podUIDRegex.FindStringSubmatch(element); m != nil {
+			podUID = strings.ReplaceAll(m[1], "_", "-")
+		}
+	}
+	return containerID, podUID
+}
+
+// resolvePodName returns the namespace and name of a pod from its kubelet log directory,
+// <namespace>_<name>_<uid>. Neither can contain '_', unlike the environment of the pod
+// which can't be trusted for it.
+func resolvePodName(podLogRoot string, podUID string) (string, string) {
+	matches, _ := filepath.Glob(filepath.Join(podLogRoot, "*_*_"+podUID))
+	if len(matches) != 1 {
+		return "", ""
+	}
+	parts := strings.SplitN(filepath.Base(matches[0]), "_", 3)
+	return parts[0], parts[1]
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindGPUHolders(t *testing.T) {
+	const (
+		containerID = "4f1c2b8e9d7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e"
+		podUID      = "0f8b6d2c-1a3e-4b5d-8c7f-9e0a1b2c3d4e"
+	)
+	procRoot := t.TempDir()
+	podLogRoot := t.TempDir()
+	os.MkdirAll(filepath.Join(podLogRoot, "gpu-operator_cuda-vectoradd_"+podUID), 0755)
+
+	writeProcess := func(pid string, comm string, devices []string, maps string, cgroup string) {
+		dir := filepath.Join(procRoot, pid)
+		os.MkdirAll(filepath.Join(dir, "fd"), 0755)
+		os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "maps"), []byte(maps), 0644)
+		os.WriteFile(filepath.Join(dir, "cgroup"), []byte(cgroup), 0644)
+		// the holder name in the environment is not trusted
+		os.WriteFile(filepath.Join(dir, "environ"), []byte("HOSTNAME=worker-1\x00"), 0644)
+		for i, device := range devices {
+			os.Symlink(device, filepath.Join(dir, "fd", string(rune('3'+i))))
+		}
+	}
+	writeProcess("4242", "vectorAdd", []string{"/dev/nvidia0", "/dev/nvidiactl", "/dev/nvidia0"},
+		"7f0000000000-7f0000001000 r-xp 00000000 08:01 1234 /usr/lib/x86_64-linux-gnu/libcuda.so.550.90.07\n",
+		"0::/kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod"+
+			"0f8b6d2c_1a3e_4b5d_8c7f_9e0a1b2c3d4e.slice/cri-containerd-"+containerID+".scope\n")
+	writeProcess("100", "nvidia-persiste", []string{"/dev/nvidiactl"}, "", "0::/system.slice/nvidia-persistenced.service\n")
+	writeProcess("200", "bash", []string{"/dev/null"}, "7f0000000000-7f0000001000 r-xp 00000000 08:01 1 /usr/lib/libc.so.6\n", "0::/\n")
+	writeProcess("300", "nvidia-smi", nil,
+		"7f0000000000-7f0000001000 r-xp 00000000 08:01 1 /usr/lib/x86_64-linux-gnu/libnvidia-ml.so.1\n",
+		"0::/\n12:devices:/kubepods/besteffort/pod7a1b2c3d-0000-4000-8000-000000000001/"+containerID+"\n")
+
+	holders, err := FindGPUHolders(procRoot, podLogRoot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(holders) != 3 {
+		t.Fatalf("expected 3 holders, got %+v", holders)
+	}
+
+	persistenced, smi, vectorAdd := holders[0], holders[1], holders[2]
+	if persistenced.PID != 100 || persistenced.PodUID != "" || persistenced.ContainerID != "" {
+		t.Errorf("unexpected host process %+v", persistenced)
+	}
+	if smi.PodUID != "7a1b2c3d-0000-4000-8000-000000000001" || smi.PodName != "" || smi.ContainerID != containerID || len(smi.Libraries) != 1 {
+		t.Errorf("unexpected pod without log directory %+v", smi)
+	}
+	if vectorAdd.PodUID != podUID || vectorAdd.PodNamespace != "gpu-operator" || vectorAdd.PodName != "cuda-vectoradd" {
+		t.Errorf("unexpected pod %+v", vectorAdd)
+	}
+	if vectorAdd.ContainerID != containerID || len(vectorAdd.Devices) != 2 || vectorAdd.Command != "vectorAdd" {
+		t.Errorf("unexpected container process %+v", vectorAdd)
+	}
+}
+
+func TestReadCgroupPath(t *testing.T) {
+	testCases := []struct {
+		description string
+		cgroup      string
+		expected    string
+	}{
+		{
+			description: "cgroup v2",
+			cgroup:      "0::/kubepods.slice/cri-containerd-abc.scope\n",
+			expected:    "/kubepods.slice/cri-containerd-abc.scope",
+		},
+		{
+			description: "cgroup v1",
+			cgroup:      "12:cpuset:/\n11:devices:/kubepods/besteffort/pod1/abc\n10:memory:/kubepods/besteffort/pod1/abc\n",
+			expected:    "/kubepods/besteffort/pod1/abc",
+		},
+		{
+			description: "hybrid with the unified hierarchy at the root",
+			cgroup:      "12:devices:/kubepods/besteffort/pod1/abc\n1:name=systemd:/kubepods/besteffort/pod1/abc\n0::/\n",
+			expected:    "/kubepods/besteffort/pod1/abc",
+		},
+		{
+			description: "hybrid with a unified path",
+			cgroup:      "12:devices:/kubepods/besteffort/pod1/abc\n0::/kubepods/besteffort/pod1/abc\n",
+			expected:    "/kubepods/besteffort/pod1/abc",
+		},
+		{
+			description: "host process",
+			cgroup:      "12:devices:/\n0::/\n",
+			expected:    "/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			procDir := t.TempDir()
+			os.WriteFile(filepath.Join(procDir, "cgroup"), []byte(tc.cgroup), 0644)
+			if path := readCgroupPath(procDir); path != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, path)
+			}
+		})
+	}
+}
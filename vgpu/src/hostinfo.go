@@ -0,0 +1,151 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+type hostInfoOptions struct {
+	sysfsRoot string
+	branch    string
+	gpu       string
+	verbose   bool
+}
+
+func newHostInfoCommand() *cli.Command {
+	opts := hostInfoOptions{}
+
+	// Create the 'host-info' subcommand
+	hostInfo := cli.Command{}
+	hostInfo.Name = "host-info"
+	hostInfo.Usage = "Report the vGPU manager version and branch advertised to guests and the compatible guest drivers"
+	hostInfo.UsageText = "[-c | --catalog-file] [--branch] [--gpu] [--verbose] [--sysfs-root]"
+	hostInfo.Action = func(c *cli.Context) error {
+		return HostInfo(c, &opts)
+	}
+	hostInfo.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "catalog-file",
+			Aliases:     []string{"c"},
+			Usage:       "vGPU driver catalog file",
+			Value:       DefaultCatalogFile,
+			Destination: &catalogFile,
+			EnvVars:     []string{"VGPU_DRIVER_CATALOG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "branch",
+			Usage:       "vGPU manager branch, looked up from the catalog host drivers if not set",
+			Destination: &opts.branch,
+			EnvVars:     []string{"VGPU_HOST_DRIVER_BRANCH"},
+		},
+		&cli.StringFlag{
+			Name:        "gpu",
+			Usage:       "Evaluate GPU allow / deny rules for this PCI device id, e.g. 0x2236",
+			Destination: &opts.gpu,
+		},
+		&cli.BoolFlag{
+			Name:        "verbose",
+			Aliases:     []string{"v"},
+			Usage:       "Also list incompatible guest drivers with the reason",
+			Destination: &opts.verbose,
+		},
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+	}
+	return &hostInfo
+}
+
+// HostInfo reports the version and branch of the loaded vGPU manager, and the guest drivers of the catalog compatible with it
+func HostInfo(c *cli.Context, opts *hostInfoOptions) error {
+	log.Infof("Starting 'host-info' with %v", c.App.Name)
+
+	version, err := GetHostDriverVersion(opts.sysfsRoot)
+	if err != nil {
+		return err
+	}
+
+	driverCatalog, err := LoadCatalog()
+	if err != nil {
+		return fmt.Errorf("unable to load catalog file: %v", err)
+	}
+
+	branch := strings.ToUpper(opts.branch)
+	if branch == "" {
+		branch, err = FindHostDriverBranch(driverCatalog, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	var pciDeviceInfo *PCIDeviceInfo
+	if opts.gpu != "" {
+		pciDeviceInfo = &PCIDeviceInfo{name: opts.gpu, vendor: NvidiaVendorID, deviceID: strings.ToLower(opts.gpu)}
+	}
+
+	eval, err := EvaluateCatalog(driverCatalog, version, branch, pciDeviceInfo)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate catalog for host driver version %s branch %s: %v", version, branch, err)
+	}
+
+	var compatible []string
+	for _, driver := range eval.Compatible() {
+		compatible = append(compatible, driver.Version)
+	}
+	log.Infof("Found %d guest drivers compatible with host version %s branch %s", len(compatible), version, branch)
+
+	fmt.Printf("HOST_DRIVER_VERSION=%s\n", version)
+	fmt.Printf("HOST_DRIVER_BRANCH=%s\n", branch)
+	fmt.Printf("COMPATIBLE_GUEST_DRIVERS=%s\n", strings.Join(compatible, ","))
+	if opts.verbose {
+		for _, verdict := range eval.Guests {
+			if !verdict.Compatible() {
+				fmt.Printf("# %s (%s): %s\n", verdict.Driver.Version, verdict.Driver.Branch, strings.Join(verdict.Reasons, "; "))
+			}
+		}
+	}
+
+	log.Infof("Completed 'host-info' with %v", c.App.Name)
+	return nil
+}
+
+// GetHostDriverVersion returns the version of the loaded NVIDIA kernel module
+func GetHostDriverVersion(sysfsRoot string) (string, error) {
+	versionFile := filepath.Join(sysfsRoot, "module", "nvidia", "version")
+	version := readSysfsString(versionFile)
+	if version == "" {
+		return "", fmt.Errorf("unable to read vGPU manager version from %s, is the nvidia module loaded?", versionFile)
+	}
+	return strings.ToUpper(version), nil
+}
+
+// FindHostDriverBranch returns the branch of a host driver version as described in the catalog
+func FindHostDriverBranch(driverCatalog *VGPUDriverCatalog, version string) (string, error) {
+	for _, driver := range driverCatalog.Driver {
+		if driver.Type == "host" && driver.Version == version {
+			return strings.ToUpper(driver.Branch), nil
+		}
+	}
+	return "", fmt.Errorf("host driver version %s is not described in catalog file, please specify the branch", version)
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// KernelTypeProprietary is the directory of the proprietary kernel modules in the driver package
+	KernelTypeProprietary = "kernel"
+	// KernelTypeOpen is the directory of the open kernel modules in the driver package
+	KernelTypeOpen = "kernel-open"
+	// openModulesFirstBranch is the first driver branch shipping the open kernel modules
+	openModulesFirstBranch = 515
+	// openModulesDefaultBranch is the first driver branch recommending the open kernel modules
+	openModulesDefaultBranch = 560
+)
+
+// GPUArchitecture is an NVIDIA GPU architecture, ordered by release
+type GPUArchitecture int
+
+const (
+	// ArchUnknown is a device id not found in the architecture table
+	ArchUnknown GPUArchitecture = iota
+	ArchKepler
+	ArchMaxwell
+	ArchPascal
+	ArchVolta
+	ArchTuring
+	ArchAmpere
+	ArchHopper
+	ArchAda
+	ArchBlackwell
+)
+
+var gpuArchitectureNames = map[GPUArchitecture]string{
+	ArchUnknown:   "Unknown",
+	ArchKepler:    "Kepler",
+	ArchMaxwell:   "Maxwell",
+	ArchPascal:    "Pascal",
+	ArchVolta:     "Volta",
+	ArchTuring:    "Turing",
+	ArchAmpere:    "Ampere",
+	ArchHopper:    "Hopper",
+	ArchAda:       "Ada",
+	ArchBlackwell: "Blackwell",
+}
+
+func (a GPUArchitecture) String() string {
+	return gpuArchitectureNames[a]
+}
+
+// SupportsOpenModules returns true if the open kernel modules can drive the architecture, Turing and later
+func (a GPUArchitecture) SupportsOpenModules() bool {
+	return a >= ArchTuring
+}
+
+// RequiresOpenModules returns true if only the open kernel modules can drive the architecture, Blackwell and later
+func (a GPUArchitecture) RequiresOpenModules() bool {
+	return a >= ArchBlackwell
+}
+
+// gpuArchitectureRanges maps PCI device id ranges to architectures. Device ids are allocated
+// by chip, and the chips of an architecture mostly use contiguous ranges. The first matching
+// range wins.
+var gpuArchitectureRanges = []struct {
+	first, last uint16
+	arch        GPUArchitecture
+}{
+	{0x0fc0, 0x103f, ArchKepler},    // GK107, GK110
+	{0x1180, 0x12ff, ArchKepler},    // GK104, GK106, GK208
+	{0x1340, 0x13ff, ArchMaxwell},   // GM108, GM107, GM204
+	{0x15f0, 0x15ff, ArchPascal},    // GP100, within the GM206 and GM200 range
+	{0x1400, 0x17ff, ArchMaxwell},   // GM206, GM200
+	{0x1b00, 0x1d7f, ArchPascal},    // GP102, GP104, GP106, GP107, GP108
+	{0x1d80, 0x1dff, ArchVolta},     // GV100
+	{0x1e00, 0x1fff, ArchTuring},    // TU102, TU104, TU106, TU117
+	{0x2080, 0x20ff, ArchAmpere},    // GA100
+	{0x2180, 0x21ff, ArchTuring},    // TU116
+	{0x2200, 0x22ff, ArchAmpere},    // GA102
+	{0x2300, 0x23ff, ArchHopper},    // GH100
+	{0x2400, 0x25ff, ArchAmpere},    // GA103, GA104, GA106, GA107
+	{0x2600, 0x28ff, ArchAda},       // AD102, AD103, AD104, AD106, AD107
+	{0x2900, 0x29ff, ArchBlackwell}, // GB100
+	{0x2b00, 0x2fff, ArchBlackwell}, // GB202, GB203, GB205, GB206, GB207
+	{0x3100, 0x32ff, ArchBlackwell}, // GB110
+}
+
+// ClassifyGPU returns the architecture of an NVIDIA GPU from its PCI device id, e.g. 0x2330.
+// Device ids past the last known range are newer GPUs, assumed to behave as the latest architecture.
+func ClassifyGPU(deviceID string) GPUArchitecture {
+	id, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(deviceID), "0x"), 16, 16)
+	if err != nil {
+		return ArchUnknown
+	}
+	last := gpuArchitectureRanges[len(gpuArchitectureRanges)-1]
+	if uint16(id) > last.last {
+		return last.arch
+	}
+	for _, r := range gpuArchitectureRanges {
+		if uint16(id) >= r.first && uint16(id) <= r.last {
+			return r.arch
+		}
+	}
+	return ArchUnknown
+}
+
+// ClassifiedGPU is a GPU found on the node with its architecture
+type ClassifiedGPU struct {
+	Address      string `json:"address"`
+	DeviceID     string `json:"deviceID"`
+	Architecture string `json:"architecture"`
+	arch         GPUArchitecture
+}
+
+// KernelTypeResolution is the kernel module type picked for the GPUs of the node
+type KernelTypeResolution struct {
+	KernelType string          `json:"kernelType"`
+	Reason     string          `json:"reason"`
+	GPUs       []ClassifiedGPU `json:"gpus"`
+}
+
+type moduleTypeOptions struct {
+	sysfsRoot        string
+	kernelModuleType string
+	driverBranch     string
+	recommendedType  string
+	output           string
+}
+
+func newKernelCommand() *cli.Command {
+	kernel := cli.Command{}
+	kernel.Name = "kernel"
+	kernel.Usage = "Inspect the kernel and pick the kernel modules to build"
+	kernel.Subcommands = []*cli.Command{
+		newModuleTypeCommand(),
+		newKernelParseCommand(),
+		newVersionStringCommand(),
+	}
+	return &kernel
+}
+
+func newModuleTypeCommand() *cli.Command {
+	opts := moduleTypeOptions{}
+
+	// Create the 'kernel module-type' subcommand
+	moduleType := cli.Command{}
+	moduleType.Name = "module-type"
+	moduleType.Usage = "Pick the kernel or kernel-open modules for the GPUs of the node"
+	moduleType.UsageText = "[--kernel-module-type] [--driver-branch] [--recommended] [--sysfs-root] [-o | --output]"
+	moduleType.Action = func(c *cli.Context) error {
+		return ResolveModuleType(c, &opts)
+	}
+	moduleType.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "kernel-module-type",
+			Usage:       "Requested kernel module type, auto, open or proprietary",
+			Value:       "auto",
+			Destination: &opts.kernelModuleType,
+			EnvVars:     []string{"KERNEL_MODULE_TYPE"},
+		},
+		&cli.StringFlag{
+			Name:        "driver-branch",
+			Usage:       "Driver branch, e.g. 570",
+			Destination: &opts.driverBranch,
+			EnvVars:     []string{"DRIVER_BRANCH"},
+		},
+		&cli.StringFlag{
+			Name:        "recommended",
+			Usage:       "Kernel module type recommended by 'nvidia-installer --print-recommended-kernel-module-type', open or proprietary",
+			Destination: &opts.recommendedType,
+		},
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env or json",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+	}
+	return &moduleType
+}
+
+// ResolveModuleType prints the kernel module type to build for the GPUs of the node
+func ResolveModuleType(c *cli.Context, opts *moduleTypeOptions) error {
+	log.Infof("Starting 'kernel module-type' with %v", c.App.Name)
+
+	gpus, err := FindClassifiedGPUs(opts.sysfsRoot)
+	if err != nil {
+		return err
+	}
+	branch := 0
+	if opts.driverBranch != "" {
+		if branch, err = strconv.Atoi(opts.driverBranch); err != nil {
+			return fmt.Errorf("invalid driver branch %q", opts.driverBranch)
+		}
+	}
+
+	resolution, err := ResolveKernelType(opts.kernelModuleType, branch, opts.recommendedType, gpus)
+	if err != nil {
+		return err
+	}
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(resolution, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		fmt.Printf("KERNEL_TYPE=%s\n", resolution.KernelType)
+		fmt.Printf("# %s\n", resolution.Reason)
+		for _, gpu := range resolution.GPUs {
+			fmt.Printf("# %s %s %s\n", gpu.Address, gpu.DeviceID, gpu.Architecture)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+
+	log.Infof("Completed 'kernel module-type' with %v, picked %s: %s", c.App.Name, resolution.KernelType, resolution.Reason)
+	return nil
+}
+
+// FindClassifiedGPUs returns the NVIDIA GPUs of the node with their architecture
+func FindClassifiedGPUs(sysfsRoot string) ([]ClassifiedGPU, error) {
+	devices, err := ScanPCIDevices(filepath.Join(sysfsRoot, "bus", "pci", "devices"), func(d *PCIDevice) bool {
+		return d.Vendor == NvidiaVendorID && strings.HasPrefix(d.Class, PciClassDisplayController)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan PCI devices: %v", err)
+	}
+	gpus := []ClassifiedGPU{}
+	for _, device := range devices {
+		arch := ClassifyGPU(device.Device)
+		gpus = append(gpus, ClassifiedGPU{Address: device.Address, DeviceID: device.Device, Architecture: arch.String(), arch: arch})
+	}
+	return gpus, nil
+}
+
+// ResolveKernelType picks the kernel module type for the requested type, the driver branch
+// and the GPUs present. An explicitly requested type unsupported by one of the GPUs is an error.
+func ResolveKernelType(requested string, branch int, recommended string, gpus []ClassifiedGPU) (*KernelTypeResolution, error) {
+	var needsProprietary, needsOpen []string
+	for _, gpu := range gpus {
+		switch {
+		case gpu.arch == ArchUnknown:
+			log.Warnf("unknown architecture for GPU %s with device id %s", gpu.Address, gpu.DeviceID)
+		case !gpu.arch.SupportsOpenModules():
+			needsProprietary = append(needsProprietary, fmt.Sprintf("%s (%s)", gpu.Address, gpu.arch))
+		case gpu.arch.RequiresOpenModules():
+			needsOpen = append(needsOpen, fmt.Sprintf("%s (%s)", gpu.Address, gpu.arch))
+		}
+	}
+	resolution := &KernelTypeResolution{GPUs: gpus}
+	if len(needsProprietary) > 0 && len(needsOpen) > 0 {
+		return nil, fmt.Errorf("no kernel module type supports all GPUs: %s require the proprietary modules and %s require the open modules",
+			strings.Join(needsProprietary, ", "), strings.Join(needsOpen, ", "))
+	}
+
+	switch requested {
+	case "proprietary":
+		if len(needsOpen) > 0 {
+			return nil, fmt.Errorf("KERNEL_MODULE_TYPE=proprietary is not supported by %s, use the open kernel modules", strings.Join(needsOpen, ", "))
+		}
+		resolution.KernelType, resolution.Reason = KernelTypeProprietary, "proprietary kernel modules requested"
+	case "open":
+		if len(needsProprietary) > 0 {
+			return nil, fmt.Errorf("KERNEL_MODULE_TYPE=open is not supported by pre-Turing GPUs %s, use the proprietary kernel modules", strings.Join(needsProprietary, ", "))
+		}
+		if branch > 0 && branch < openModulesFirstBranch {
+			return nil, fmt.Errorf("KERNEL_MODULE_TYPE=open is not supported by driver branch %d, open kernel modules are available from %d", branch, openModulesFirstBranch)
+		}
+		resolution.KernelType, resolution.Reason = KernelTypeOpen, "open kernel modules requested"
+	case "auto", "":
+		switch {
+		case len(needsProprietary) > 0:
+			resolution.KernelType = KernelTypeProprietary
+			resolution.Reason = fmt.Sprintf("pre-Turing GPUs %s require the proprietary kernel modules", strings.Join(needsProprietary, ", "))
+		case len(needsOpen) > 0:
+			resolution.KernelType = KernelTypeOpen
+			resolution.Reason = fmt.Sprintf("GPUs %s require the open kernel modules", strings.Join(needsOpen, ", "))
+		case recommended == "open" || recommended == "proprietary":
+			resolution.KernelType = KernelTypeProprietary
+			if recommended == "open" {
+				resolution.KernelType = KernelTypeOpen
+			}
+			resolution.Reason = fmt.Sprintf("%s kernel modules recommended by nvidia-installer", recommended)
+		case branch >= openModulesDefaultBranch:
+			resolution.KernelType = KernelTypeOpen
+			resolution.Reason = fmt.Sprintf("open kernel modules are the default from driver branch %d", openModulesDefaultBranch)
+		default:
+			resolution.KernelType = KernelTypeProprietary
+			resolution.Reason = fmt.Sprintf("proprietary kernel modules are the default before driver branch %d", openModulesDefaultBranch)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for the KERNEL_MODULE_TYPE variable: %s", requested)
+	}
+	return resolution, nil
+}
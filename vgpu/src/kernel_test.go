@@ -0,0 +1,194 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyGPU(t *testing.T) {
+	testCases := []struct {
+		deviceID string
+		expected GPUArchitecture
+	}{
+		{"0x0fbf", ArchUnknown},
+		{"0x0fc0", ArchKepler},
+		{"0x103f", ArchKepler},
+		{"0x1040", ArchUnknown},
+		{"0x13ff", ArchMaxwell},
+		{"0x1400", ArchMaxwell},
+		// GP100 within the Maxwell range
+		{"0x15ef", ArchMaxwell},
+		{"0x15f0", ArchPascal},
+		{"0x15ff", ArchPascal},
+		{"0x1600", ArchMaxwell},
+		{"0x1d7f", ArchPascal},
+		{"0x1d80", ArchVolta},
+		{"0x1DB6", ArchVolta},
+		{"0x1dff", ArchVolta},
+		{"0x1e00", ArchTuring},
+		{"1eb8", ArchTuring},
+		{"0x2080", ArchAmpere},
+		{"0x20b0", ArchAmpere},
+		{"0x2180", ArchTuring},
+		{"0x2236", ArchAmpere},
+		{"0x2330", ArchHopper},
+		{"0x2400", ArchAmpere},
+		{"0x25ff", ArchAmpere},
+		{"0x2600", ArchAda},
+		{"0x28ff", ArchAda},
+		{"0x2900", ArchBlackwell},
+		{"0x2a00", ArchUnknown},
+		{"0x2b85", ArchBlackwell},
+		{"0x32ff", ArchBlackwell},
+		// newer than the last known range
+		{"0x3300", ArchBlackwell},
+		{"", ArchUnknown},
+		{"0xgpu", ArchUnknown},
+	}
+
+	for _, tc := range testCases {
+		if arch := ClassifyGPU(tc.deviceID); arch != tc.expected {
+			t.Errorf("%q: expected %s, got %s", tc.deviceID, tc.expected, arch)
+		}
+	}
+}
+
+func TestResolveKernelType(t *testing.T) {
+	gpu := func(address, deviceID string) ClassifiedGPU {
+		arch := ClassifyGPU(deviceID)
+		return ClassifiedGPU{Address: address, DeviceID: deviceID, Architecture: arch.String(), arch: arch}
+	}
+	volta := gpu("0000:3b:00.0", "0x1db6")
+	turing := gpu("0000:5e:00.0", "0x1eb8")
+	ampere := gpu("0000:86:00.0", "0x20b0")
+	blackwell := gpu("0000:af:00.0", "0x2b85")
+	unknown := gpu("0000:d8:00.0", "0x2a00")
+
+	testCases := []struct {
+		description string
+		requested   string
+		branch      int
+		recommended string
+		gpus        []ClassifiedGPU
+		expected    string
+		expectError string
+	}{
+		{
+			description: "auto with Volta and Ampere picks proprietary",
+			requested:   "auto",
+			branch:      570,
+			recommended: "open",
+			gpus:        []ClassifiedGPU{volta, ampere},
+			expected:    KernelTypeProprietary,
+		},
+		{
+			description: "auto with Volta and Blackwell",
+			requested:   "auto",
+			branch:      570,
+			gpus:        []ClassifiedGPU{volta, blackwell},
+			expectError: "no kernel module type supports all GPUs",
+		},
+		{
+			description: "proprietary with Volta and Blackwell",
+			requested:   "proprietary",
+			branch:      570,
+			gpus:        []ClassifiedGPU{volta, blackwell},
+			expectError: "no kernel module type supports all GPUs",
+		},
+		{
+			description: "open on pre-Turing",
+			requested:   "open",
+			branch:      570,
+			gpus:        []ClassifiedGPU{turing, volta},
+			expectError: "not supported by pre-Turing GPUs 0000:3b:00.0 (Volta)",
+		},
+		{
+			description: "open before branch 515",
+			requested:   "open",
+			branch:      510,
+			gpus:        []ClassifiedGPU{ampere},
+			expectError: "not supported by driver branch 510",
+		},
+		{
+			description: "open from branch 515",
+			requested:   "open",
+			branch:      515,
+			gpus:        []ClassifiedGPU{ampere},
+			expected:    KernelTypeOpen,
+		},
+		{
+			description: "proprietary on Blackwell",
+			requested:   "proprietary",
+			branch:      570,
+			gpus:        []ClassifiedGPU{blackwell},
+			expectError: "use the open kernel modules",
+		},
+		{
+			description: "auto with Blackwell picks open",
+			requested:   "auto",
+			branch:      570,
+			recommended: "proprietary",
+			gpus:        []ClassifiedGPU{ampere, blackwell},
+			expected:    KernelTypeOpen,
+		},
+		{
+			description: "auto follows the recommendation",
+			requested:   "auto",
+			branch:      570,
+			recommended: "proprietary",
+			gpus:        []ClassifiedGPU{ampere},
+			expected:    KernelTypeProprietary,
+		},
+		{
+			description: "auto without recommendation from branch 560",
+			requested:   "auto",
+			branch:      560,
+			gpus:        []ClassifiedGPU{ampere, unknown},
+			expected:    KernelTypeOpen,
+		},
+		{
+			description: "auto without recommendation before branch 560",
+			requested:   "",
+			branch:      550,
+			gpus:        []ClassifiedGPU{turing},
+			expected:    KernelTypeProprietary,
+		},
+		{
+			description: "invalid request",
+			requested:   "closed",
+			gpus:        []ClassifiedGPU{ampere},
+			expectError: "invalid value for the KERNEL_MODULE_TYPE variable",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			resolution, err := ResolveKernelType(tc.requested, tc.branch, tc.recommended, tc.gpus)
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Fatalf("expected error %q, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resolution.KernelType != tc.expected {
+				t.Errorf("expected %s, got %s: %s", tc.expected, resolution.KernelType, resolution.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultDriverPIDFile indicates default location of the pidfile the driver container holds locked
+	DefaultDriverPIDFile = "/run/nvidia/nvidia-driver.pid"
+	// DefaultKernelUpdateHook indicates default location of the kernel postinst.d hook
+	DefaultKernelUpdateHook = "/run/kernel/postinst.d/update-nvidia-driver"
+	// DefaultKernelHookBinary indicates default location of the copy of vgpu-util run by the hook on the host
+	DefaultKernelHookBinary = "/run/nvidia/kernel-hook/vgpu-util"
+	// DefaultKernelHookLogDirectory indicates default location of the kernel update logs
+	DefaultKernelHookLogDirectory = "/run/nvidia/kernel-hook"
+	// DefaultKernelHookTimeout is the time the kernel install waits for the driver container to update,
+	// including the wait for the updates of the other kernels installed at the same time
+	DefaultKernelHookTimeout = 15 * time.Minute
+	// kernelHookWaitDelay is the time given to the processes started by the update to close its output once killed
+	kernelHookWaitDelay = 10 * time.Second
+	// kernelHookLockInterval is the interval between two attempts to take the hook lock
+	kernelHookLockInterval = time.Second
+	// kernelHookLogTail is the number of output lines reported when the update fails
+	kernelHookLogTail = 20
+)
+
+const (
+	// KernelUpdateSucceeded is reported when the driver container updated for the new kernel
+	KernelUpdateSucceeded = "succeeded"
+	// KernelUpdateFailed is reported when the update failed or timed out
+	KernelUpdateFailed = "failed"
+	// KernelUpdateSkipped is reported when no driver container is running
+	KernelUpdateSkipped = "skipped"
+)
+
+// KernelUpdateResult is the outcome of the kernel update hook
+type KernelUpdateResult struct {
+	KernelRelease string    `json:"kernelRelease"`
+	Outcome       string    `json:"outcome"`
+	Message       string    `json:"message,omitempty"`
+	DriverPID     int       `json:"driverPID,omitempty"`
+	DriverVersion string    `json:"driverVersion,omitempty"`
+	ExitCode      int       `json:"exitCode"`
+	LogFile       string    `json:"logFile,omitempty"`
+	StartedAt     time.Time `json:"startedAt"`
+	FinishedAt    time.Time `json:"finishedAt"`
+}
+
+type kernelHookOptions struct {
+	kernelRelease string
+	pidFile       string
+	statusFile    string
+	logDirectory  string
+	command       string
+	hookFile      string
+	binary        string
+	timeout       time.Duration
+	// executable is the vgpu-util copied to the host, the running binary if not set
+	executable string
+}
+
+func newKernelHookCommand() *cli.Command {
+	opts := kernelHookOptions{}
+
+	timeoutFlag := &cli.DurationFlag{
+		Name:        "timeout",
+		Usage:       "Time given to the driver container to update",
+		Value:       DefaultKernelHookTimeout,
+		Destination: &opts.timeout,
+	}
+
+	// Create the 'kernel-hook run' subcommand
+	run := cli.Command{}
+	run.Name = "run"
+	run.Usage = "Update the running driver container for a newly installed kernel, never failing the kernel install"
+	run.UsageText = "[--kernel] [--pid-file] [--status-file] [--log-dir] [--command] [--timeout] [KERNEL_RELEASE]"
+	run.Action = func(c *cli.Context) error {
+		return RunKernelHook(c, &opts)
+	}
+	run.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "kernel",
+			Usage:       "Kernel release installed, the first argument if not set",
+			Destination: &opts.kernelRelease,
+		},
+		&cli.StringFlag{
+			Name:        "pid-file",
+			Usage:       "Pidfile the driver container holds locked",
+			Value:       DefaultDriverPIDFile,
+			Destination: &opts.pidFile,
+		},
+		&cli.StringFlag{
+			Name:        "status-file",
+			Usage:       "Driver status document the outcome is recorded in",
+			Value:       DefaultDriverStatusFile,
+			Destination: &opts.statusFile,
+			EnvVars:     []string{"DRIVER_STATUS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "log-dir",
+			Usage:       "Directory the output of the update is written to",
+			Value:       DefaultKernelHookLogDirectory,
+			Destination: &opts.logDirectory,
+		},
+		&cli.StringFlag{
+			Name:        "command",
+			Usage:       "Driver container entrypoint run with 'update --kernel'",
+			Value:       "nvidia-driver",
+			Destination: &opts.command,
+		},
+		timeoutFlag,
+	}
+
+	// Create the 'kernel-hook install' subcommand
+	install := cli.Command{}
+	install.Name = "install"
+	install.Usage = "Copy vgpu-util to the host and write the kernel postinst.d hook calling 'kernel-hook run'"
+	install.UsageText = "[--hook-file] [--binary] [--timeout]"
+	install.Action = func(c *cli.Context) error {
+		return InstallKernelHook(c, &opts)
+	}
+	install.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "hook-file",
+			Usage:       "Hook to write, nothing is written if its directory does not exist",
+			Value:       DefaultKernelUpdateHook,
+			Destination: &opts.hookFile,
+		},
+		&cli.StringFlag{
+			Name:        "binary",
+			Usage:       "Path on the host vgpu-util is copied to, outside of the driver container rootfs",
+			Value:       DefaultKernelHookBinary,
+			Destination: &opts.binary,
+		},
+		timeoutFlag,
+	}
+
+	hook := cli.Command{}
+	hook.Name = "kernel-hook"
+	hook.Usage = "Update the driver container when the host installs a new kernel"
+	hook.Subcommands = []*cli.Command{&run, &install}
+	return &hook
+}
+
+// InstallKernelHook copies vgpu-util to the host and writes a postinst.d hook running 'kernel-hook run'
+// with the copy, which remains when the driver container rootfs is unmounted. The hook waits at most
+// --timeout for the update and prints the outcome to the kernel install output, but never fails so
+// that the host kernel install is never failed by the driver container.
+func InstallKernelHook(c *cli.Context, opts *kernelHookOptions) error {
+	if _, err := os.Stat(filepath.Dir(opts.hookFile)); os.IsNotExist(err) {
+		log.Infof("%s does not exist, not writing the kernel update hook", filepath.Dir(opts.hookFile))
+		return nil
+	}
+
+	executable := opts.executable
+	if executable == "" {
+		var err error
+		if executable, err = os.Executable(); err != nil {
+			return fmt.Errorf("unable to find the vgpu-util executable: %v", err)
+		}
+	}
+	data, err := os.ReadFile(executable)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", executable, err)
+	}
+	if err := writeFileAtomic(opts.binary, data, 0755); err != nil {
+		return err
+	}
+
+	hook := fmt.Sprintf(`#!/bin/sh
+# Written by vgpu-util kernel-hook install, the outcome is also recorded in the driver status document
+[ -x %[1]s ] || exit 0
+echo "Updating the NVIDIA driver for kernel $1, waiting at most %[2]v"
+%[1]s kernel-hook run --kernel "$1" --timeout %[2]v </dev/null || true
+exit 0
+`, shellQuote(opts.binary), opts.timeout)
+	if err := writeFileAtomic(opts.hookFile, []byte(hook), 0755); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote kernel update hook %s running %s\n", opts.hookFile, opts.binary)
+	return nil
+}
+
+// RunKernelHook updates the driver container for the new kernel and records the outcome.
+// Failures are reported on stderr and in the driver status document, but never returned.
+func RunKernelHook(c *cli.Context, opts *kernelHookOptions) error {
+	log.Infof("Starting 'kernel-hook run' with %v", c.App.Name)
+
+	kernelRelease := opts.kernelRelease
+	if kernelRelease == "" {
+		kernelRelease = c.Args().First()
+	}
+	result := &KernelUpdateResult{KernelRelease: kernelRelease, StartedAt: time.Now().UTC()}
+	if kernelRelease == "" {
+		result.Outcome = KernelUpdateFailed
+		result.Message = "no kernel release given"
+	} else {
+		runKernelUpdate(opts, result)
+	}
+	result.FinishedAt = time.Now().UTC()
+
+	switch result.Outcome {
+	case KernelUpdateFailed:
+		log.Errorf("kernel update to %s failed: %s", kernelRelease, result.Message)
+		fmt.Fprintf(os.Stderr, "ERROR: Failed to update the NVIDIA driver for kernel %s: %s\n", kernelRelease, result.Message)
+		if result.LogFile != "" {
+			fmt.Fprintf(os.Stderr, "See %s for the full output\n", result.LogFile)
+		}
+	case KernelUpdateSkipped:
+		log.Infof("kernel update to %s skipped: %s", kernelRelease, result.Message)
+		fmt.Printf("Skipping NVIDIA driver update for kernel %s: %s\n", kernelRelease, result.Message)
+	default:
+		fmt.Printf("Updated the NVIDIA driver for kernel %s\n", kernelRelease)
+	}
+
+	if err := updateDriverStatusKernelUpdate(opts.statusFile, result); err != nil {
+		log.Warnf("unable to record the kernel update in the driver status: %v", err)
+	}
+
+	log.Infof("Completed 'kernel-hook run' with %v", c.App.Name)
+	return nil
+}
+
+func runKernelUpdate(opts *kernelHookOptions, result *KernelUpdateResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	pid, err := lockedDriverPID(opts.pidFile)
+	if err != nil {
+		result.Outcome = KernelUpdateSkipped
+		result.Message = err.Error()
+		return
+	}
+	result.DriverPID = pid
+
+	env, err := readProcessEnviron(pid)
+	if err != nil {
+		result.Outcome = KernelUpdateFailed
+		result.Message = fmt.Sprintf("unable to read the driver container environment: %v", err)
+		return
+	}
+	for _, entry := range env {
+		if strings.HasPrefix(entry, "DRIVER_VERSION=") {
+			result.DriverVersion = strings.TrimPrefix(entry, "DRIVER_VERSION=")
+		}
+	}
+
+	// Serialize the hooks, the host may install several kernels in one transaction
+	unlock, err := lockKernelHook(ctx, opts.logDirectory)
+	if err != nil {
+		result.Outcome = KernelUpdateFailed
+		result.Message = err.Error()
+		return
+	}
+	defer unlock()
+
+	result.LogFile = filepath.Join(opts.logDirectory, "update-"+result.KernelRelease+".log")
+	logFile, err := os.OpenFile(result.LogFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		result.Outcome = KernelUpdateFailed
+		result.Message = fmt.Sprintf("unable to create %s: %v", result.LogFile, err)
+		return
+	}
+	defer logFile.Close()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "nsenter", "-t", strconv.Itoa(pid), "-m", "--", opts.command, "update", "--kernel", result.KernelRelease)
+	cmd.Env = env
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// do not wait for the children of a killed update still holding the output open
+	cmd.WaitDelay = kernelHookWaitDelay
+	log.Infof("running %s for driver container %d", strings.Join(cmd.Args, " "), pid)
+	err = cmd.Run()
+	if _, werr := logFile.Write(output.Bytes()); werr != nil {
+		log.Warnf("unable to write %s: %v", result.LogFile, werr)
+	}
+
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Outcome = KernelUpdateFailed
+		result.Message = fmt.Sprintf("timed out after %v", opts.timeout)
+	case err != nil && result.ExitCode == 0:
+		result.Outcome = KernelUpdateFailed
+		result.Message = err.Error()
+	case err != nil:
+		result.Outcome = KernelUpdateFailed
+		result.Message = fmt.Sprintf("%v: %s", err, tailLines(output.String(), kernelHookLogTail))
+	default:
+		result.Outcome = KernelUpdateSucceeded
+	}
+}
+
+// lockedDriverPID returns the pid of the driver container, which holds an exclusive flock on
+// its pidfile for as long as it runs. A pidfile nobody holds locked is left over by a dead container.
+func lockedDriverPID(pidFile string) (int, error) {
+	f, err := os.Open(pidFile)
+	if os.IsNotExist(err) {
+		return 0, fmt.Errorf("no driver container running, %s does not exist", pidFile)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == nil {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		return 0, fmt.Errorf("no driver container running, %s is not locked", pidFile)
+	}
+	if !errors.Is(err, syscall.EWOULDBLOCK) {
+		return 0, fmt.Errorf("unable to check the lock of %s: %v", pidFile, err)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid %q in %s", strings.TrimSpace(string(data)), pidFile)
+	}
+	return pid, nil
+}
+
+// readProcessEnviron returns the environment of a process, dropping the entries which are not
+// valid variable assignments
+func readProcessEnviron(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return nil, err
+	}
+	var env []string
+	for _, entry := range strings.Split(string(data), "\x00") {
+		name := strings.SplitN(entry, "=", 2)[0]
+		if !strings.Contains(entry, "=") || !shellNameRegex.MatchString(name) {
+			continue
+		}
+		env = append(env, entry)
+	}
+	if len(env) == 0 {
+		return nil, fmt.Errorf("empty environment for process %d", pid)
+	}
+	return env, nil
+}
+
+// lockKernelHook takes an exclusive flock on the hook lock file, waiting for the running hook to complete
+// until the context is done
+func lockKernelHook(ctx context.Context, logDirectory string) (func(), error) {
+	if err := os.MkdirAll(logDirectory, 0755); err != nil {
+		return nil, err
+	}
+	lockFile := filepath.Join(logDirectory, ".lock")
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, fmt.Errorf("unable to lock %s: %v", lockFile, err)
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, fmt.Errorf("timed out waiting for the update of another kernel to complete")
+		case <-time.After(kernelHookLockInterval):
+		}
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// tailLines returns the last n lines of s
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInstallKernelHook(t *testing.T) {
+	dir := t.TempDir()
+	executable := writeStub(t, dir, "vgpu-util", `echo "$@"; echo "ERROR: Failed to update the NVIDIA driver" >&2; exit 1`)
+	binary := filepath.Join(dir, "host", "vgpu-util")
+	hookFile := filepath.Join(dir, "postinst.d", "update-nvidia-driver")
+
+	// nothing is written when the host has no postinst.d directory
+	opts := &kernelHookOptions{hookFile: hookFile, binary: binary, executable: executable, timeout: time.Minute}
+	if err := InstallKernelHook(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(hookFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no hook written")
+	}
+
+	os.MkdirAll(filepath.Dir(hookFile), 0755)
+	if err := InstallKernelHook(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the hook runs the copy of vgpu-util, which remains when the driver rootfs is unmounted
+	os.Remove(executable)
+	if info, err := os.Stat(binary); err != nil || info.Mode().Perm()&0111 == 0 {
+		t.Fatalf("expected an executable copy of vgpu-util in %s: %v", binary, err)
+	}
+
+	// the update runs in the foreground and its outcome is part of the hook output, but never fails the hook
+	output, err := exec.Command(hookFile, "6.8.0-45-generic").CombinedOutput()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range []string{"kernel-hook run --kernel 6.8.0-45-generic --timeout 1m0s", "ERROR: Failed to update the NVIDIA driver"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("expected the hook output to contain %q, got %q", expected, output)
+		}
+	}
+
+	// a missing binary is not an error
+	os.Remove(binary)
+	if err := exec.Command(hookFile, "6.8.0-45-generic").Run(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLockKernelHookTimeout(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockKernelHook(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := lockKernelHook(ctx, dir); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout while the lock is held, got %v", err)
+	}
+}
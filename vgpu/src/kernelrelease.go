@@ -0,0 +1,218 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DistroRHEL is RHEL and the distributions using its kernel, e.g. RHCOS
+	DistroRHEL = "rhel"
+	// DistroUbuntu is Ubuntu
+	DistroUbuntu = "ubuntu"
+	// DistroDebian is Debian
+	DistroDebian = "debian"
+	// DistroUnknown is a kernel release not following a known distribution scheme
+	DistroUnknown = "unknown"
+)
+
+var (
+	// upstream version, e.g. 5.14.0 in 5.14.0-427.13.1.el9_4.x86_64
+	kernelUpstreamRegex = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?`)
+	// 427.13.1.el9_4.x86_64, 284.30.1.rt14.315.el9_2.x86_64, 1160.el7.x86_64 or 362.8.1.el9_3.x86_64+rt
+	rhelLocalVersionRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)*?)(?:\.rt([0-9.]+?))?\.el(\d+)(?:_(\d+))?(?:\.(x86_64|aarch64|ppc64le|s390x))?(?:\+([a-z0-9]+))?$`)
+	// 105-generic, 1015-azure-fde or 18-cloud-amd64
+	debianLocalVersionRegex = regexp.MustCompile(`^(\d+)-([a-z][a-z0-9-]*)$`)
+	// Debian flavors end with the architecture, Ubuntu flavors never do
+	debianArchRegex = regexp.MustCompile(`(?:^|-)(amd64|arm64|armmp|armmp-lpae|686|686-pae|ppc64el|s390x)$`)
+)
+
+// rhelMinorByABI maps the ABI major of RHEL GA kernels to the minor release, used when
+// the kernel release does not carry the minor, e.g. 4.18.0-305.el8.x86_64
+var rhelMinorByABI = map[int]map[int]int{
+	8: {80: 0, 147: 1, 193: 2, 240: 3, 305: 4, 348: 5, 372: 6, 425: 7, 477: 8, 513: 9, 553: 10},
+	9: {70: 0, 162: 1, 284: 2, 362: 3, 427: 4, 503: 5, 570: 6},
+}
+
+// KernelRelease is a 'uname -r' string split into its parts
+type KernelRelease struct {
+	Release         string `json:"release"`
+	UpstreamVersion string `json:"upstreamVersion"`
+	Major           int    `json:"major"`
+	Minor           int    `json:"minor"`
+	Patch           int    `json:"patch"`
+	// ABI is the distribution revision, e.g. 427.13.1 on RHEL or 105 on Ubuntu
+	ABI         string `json:"abi,omitempty"`
+	Distro      string `json:"distro"`
+	DistroMajor string `json:"distroMajor,omitempty"`
+	DistroMinor string `json:"distroMinor,omitempty"`
+	// Flavor is the kernel variant, e.g. generic, aws, azure-fde, nvidia-64k, rt or debug
+	Flavor   string `json:"flavor,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	RealTime bool   `json:"realTime"`
+}
+
+// DistroVersion returns major.minor, or major if the minor is unknown
+func (k *KernelRelease) DistroVersion() string {
+	if k.DistroMinor == "" {
+		return k.DistroMajor
+	}
+	return k.DistroMajor + "." + k.DistroMinor
+}
+
+// ParseKernelRelease parses the kernel release of RHEL (and RHCOS), Ubuntu and Debian kernels.
+// Other kernels only get their upstream version parsed.
+//
+//	4.18.0-513.9.1.el8_9.x86_64            rhel 8.9
+//	4.18.0-372.105.1.rt7.266.el8_6.x86_64  rhel 8.6, rt
+//	5.14.0-362.8.1.el9_3.x86_64+rt         rhel 9.3, rt
+//	4.18.0-305.el8.x86_64                  rhel 8.4, minor from the ABI
+//	6.8.0-1015-azure-fde                   ubuntu, azure-fde
+//	6.8.0-1009-nvidia-64k                  ubuntu, nvidia-64k on arm64
+//	6.1.0-18-cloud-amd64                   debian, cloud-amd64 on amd64
+func ParseKernelRelease(release string) (*KernelRelease, error) {
+	release = strings.TrimSpace(release)
+	m := kernelUpstreamRegex.FindStringSubmatch(release)
+	if m == nil {
+		return nil, fmt.Errorf("invalid kernel release %q", release)
+	}
+	k := &KernelRelease{Release: release, UpstreamVersion: m[0], Distro: DistroUnknown}
+	k.Major, _ = strconv.Atoi(m[1])
+	k.Minor, _ = strconv.Atoi(m[2])
+	k.Patch, _ = strconv.Atoi(m[3])
+
+	local := strings.TrimPrefix(release[len(m[0]):], "-")
+	if local == "" {
+		return k, nil
+	}
+
+	if m := rhelLocalVersionRegex.FindStringSubmatch(local); m != nil {
+		k.Distro = DistroRHEL
+		k.ABI = m[1]
+		k.DistroMajor = m[3]
+		k.DistroMinor = m[4]
+		k.Arch = m[5]
+		k.Flavor = m[6]
+		if m[2] != "" {
+			k.Flavor = "rt"
+		}
+		k.RealTime = k.Flavor == "rt"
+		if k.DistroMinor == "" {
+			major, _ := strconv.Atoi(k.DistroMajor)
+			abiMajor, _ := strconv.Atoi(strings.SplitN(k.ABI, ".", 2)[0])
+			if minor, ok := rhelMinorByABI[major][abiMajor]; ok {
+				k.DistroMinor = strconv.Itoa(minor)
+			}
+		}
+		return k, nil
+	}
+
+	if m := debianLocalVersionRegex.FindStringSubmatch(local); m != nil {
+		k.ABI = m[1]
+		k.Flavor = m[2]
+		if arch := debianArchRegex.FindStringSubmatch(k.Flavor); arch != nil {
+			k.Distro = DistroDebian
+			k.Arch = arch[1]
+		} else {
+			k.Distro = DistroUbuntu
+			if strings.HasSuffix(k.Flavor, "-64k") {
+				k.Arch = "arm64"
+			}
+		}
+		k.RealTime = k.Flavor == "realtime" || strings.HasPrefix(k.Flavor, "rt-") || strings.HasPrefix(k.Flavor, "realtime-")
+		return k, nil
+	}
+
+	k.ABI = local
+	return k, nil
+}
+
+type kernelParseOptions struct {
+	release string
+	output  string
+}
+
+func newKernelParseCommand() *cli.Command {
+	opts := kernelParseOptions{}
+
+	// Create the 'kernel parse' subcommand
+	parse := cli.Command{}
+	parse.Name = "parse"
+	parse.Usage = "Split a kernel release into upstream version, ABI, distribution, flavor and architecture"
+	parse.UsageText = "[-r | --release] [-o | --output]"
+	parse.Action = func(c *cli.Context) error {
+		return ParseKernel(c, &opts)
+	}
+	parse.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "release",
+			Aliases:     []string{"r"},
+			Usage:       "Kernel release, the running kernel if not set",
+			Destination: &opts.release,
+			EnvVars:     []string{"KERNEL_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env or json",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+	}
+	return &parse
+}
+
+// ParseKernel prints the parts of a kernel release
+func ParseKernel(c *cli.Context, opts *kernelParseOptions) error {
+	release, err := kernelReleaseOrRunning(opts.release)
+	if err != nil {
+		return err
+	}
+	k, err := ParseKernelRelease(release)
+	if err != nil {
+		return err
+	}
+	log.Debugf("parsed kernel release %s: distro %s %s, flavor %q", k.Release, k.Distro, k.DistroVersion(), k.Flavor)
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(k, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		fmt.Printf("KERNEL_RELEASE=%s\n", k.Release)
+		fmt.Printf("KERNEL_UPSTREAM_VERSION=%s\n", k.UpstreamVersion)
+		fmt.Printf("KERNEL_ABI=%s\n", k.ABI)
+		fmt.Printf("KERNEL_DISTRO=%s\n", k.Distro)
+		fmt.Printf("KERNEL_DISTRO_MAJOR=%s\n", k.DistroMajor)
+		fmt.Printf("KERNEL_DISTRO_MINOR=%s\n", k.DistroMinor)
+		fmt.Printf("KERNEL_DISTRO_VERSION=%s\n", k.DistroVersion())
+		fmt.Printf("KERNEL_FLAVOR=%s\n", k.Flavor)
+		fmt.Printf("KERNEL_ARCH=%s\n", k.Arch)
+		fmt.Printf("KERNEL_REALTIME=%t\n", k.RealTime)
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseKernelRelease(t *testing.T) {
+	testCases := []struct {
+		release  string
+		expected KernelRelease
+	}{
+		// RHEL 7
+		{"3.10.0-1160.el7.x86_64", KernelRelease{UpstreamVersion: "3.10.0", Major: 3, Minor: 10, ABI: "1160", Distro: DistroRHEL, DistroMajor: "7", Arch: "x86_64"}},
+		{"3.10.0-1160.119.1.el7.x86_64", KernelRelease{UpstreamVersion: "3.10.0", Major: 3, Minor: 10, ABI: "1160.119.1", Distro: DistroRHEL, DistroMajor: "7", Arch: "x86_64"}},
+		{"3.10.0-1160.rt56.1131.el7.x86_64", KernelRelease{UpstreamVersion: "3.10.0", Major: 3, Minor: 10, ABI: "1160", Distro: DistroRHEL, DistroMajor: "7", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		// RHEL 8
+		{"4.18.0-513.9.1.el8_9.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "513.9.1", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "9", Arch: "x86_64"}},
+		{"4.18.0-553.el8_10.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "553", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "10", Arch: "x86_64"}},
+		{"4.18.0-372.105.1.rt7.266.el8_6.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "372.105.1", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "6", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		{"4.18.0-513.9.1.el8_9.x86_64+debug", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "513.9.1", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "9", Arch: "x86_64", Flavor: "debug"}},
+		{"4.18.0-477.10.1.el8_8.aarch64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "477.10.1", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "8", Arch: "aarch64"}},
+		// RHEL 8 GA kernels, the minor comes from the ABI
+		{"4.18.0-80.el8.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "80", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "0", Arch: "x86_64"}},
+		{"4.18.0-305.el8.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "305", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "4", Arch: "x86_64"}},
+		{"4.18.0-305.rt7.72.el8.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "305", Distro: DistroRHEL, DistroMajor: "8", DistroMinor: "4", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		{"4.18.0-999.el8.x86_64", KernelRelease{UpstreamVersion: "4.18.0", Major: 4, Minor: 18, ABI: "999", Distro: DistroRHEL, DistroMajor: "8", Arch: "x86_64"}},
+		// RHEL 9 and RHCOS
+		{"5.14.0-427.13.1.el9_4.x86_64", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "427.13.1", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "4", Arch: "x86_64"}},
+		{"5.14.0-362.8.1.el9_3.x86_64+rt", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "362.8.1", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "3", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		{"5.14.0-284.30.1.rt14.315.el9_2.x86_64", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "284.30.1", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "2", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		{"5.14.0-427.13.1.el9_4.aarch64+64k", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "427.13.1", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "4", Arch: "aarch64", Flavor: "64k"}},
+		{"5.14.0-503.11.1.el9_5.x86_64+debug", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "503.11.1", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "5", Arch: "x86_64", Flavor: "debug"}},
+		{"5.14.0-70.el9.x86_64", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "70", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "0", Arch: "x86_64"}},
+		{"5.14.0-427.el9.x86_64+rt", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "427", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "4", Arch: "x86_64", Flavor: "rt", RealTime: true}},
+		{"5.14.0-570.el9.aarch64+64k", KernelRelease{UpstreamVersion: "5.14.0", Major: 5, Minor: 14, ABI: "570", Distro: DistroRHEL, DistroMajor: "9", DistroMinor: "6", Arch: "aarch64", Flavor: "64k"}},
+		{"6.12.0-55.9.1.el10_0.x86_64", KernelRelease{UpstreamVersion: "6.12.0", Major: 6, Minor: 12, ABI: "55.9.1", Distro: DistroRHEL, DistroMajor: "10", DistroMinor: "0", Arch: "x86_64"}},
+		// Ubuntu
+		{"5.15.0-105-generic", KernelRelease{UpstreamVersion: "5.15.0", Major: 5, Minor: 15, ABI: "105", Distro: DistroUbuntu, Flavor: "generic"}},
+		{"6.8.0-45-generic", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "45", Distro: DistroUbuntu, Flavor: "generic"}},
+		{"5.15.0-1063-aws", KernelRelease{UpstreamVersion: "5.15.0", Major: 5, Minor: 15, ABI: "1063", Distro: DistroUbuntu, Flavor: "aws"}},
+		{"6.8.0-1015-azure", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "1015", Distro: DistroUbuntu, Flavor: "azure"}},
+		{"6.8.0-1015-azure-fde", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "1015", Distro: DistroUbuntu, Flavor: "azure-fde"}},
+		{"6.8.0-1009-nvidia", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "1009", Distro: DistroUbuntu, Flavor: "nvidia"}},
+		{"6.8.0-1009-nvidia-64k", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "1009", Distro: DistroUbuntu, Flavor: "nvidia-64k", Arch: "arm64"}},
+		{"5.15.0-105-lowlatency", KernelRelease{UpstreamVersion: "5.15.0", Major: 5, Minor: 15, ABI: "105", Distro: DistroUbuntu, Flavor: "lowlatency"}},
+		{"5.15.0-1032-realtime", KernelRelease{UpstreamVersion: "5.15.0", Major: 5, Minor: 15, ABI: "1032", Distro: DistroUbuntu, Flavor: "realtime", RealTime: true}},
+		{"6.8.0-1012-oracle", KernelRelease{UpstreamVersion: "6.8.0", Major: 6, Minor: 8, ABI: "1012", Distro: DistroUbuntu, Flavor: "oracle"}},
+		// Debian
+		{"6.1.0-18-amd64", KernelRelease{UpstreamVersion: "6.1.0", Major: 6, Minor: 1, ABI: "18", Distro: DistroDebian, Flavor: "amd64", Arch: "amd64"}},
+		{"6.1.0-18-cloud-amd64", KernelRelease{UpstreamVersion: "6.1.0", Major: 6, Minor: 1, ABI: "18", Distro: DistroDebian, Flavor: "cloud-amd64", Arch: "amd64"}},
+		{"6.1.0-18-rt-amd64", KernelRelease{UpstreamVersion: "6.1.0", Major: 6, Minor: 1, ABI: "18", Distro: DistroDebian, Flavor: "rt-amd64", Arch: "amd64", RealTime: true}},
+		{"6.1.0-18-arm64", KernelRelease{UpstreamVersion: "6.1.0", Major: 6, Minor: 1, ABI: "18", Distro: DistroDebian, Flavor: "arm64", Arch: "arm64"}},
+		{"5.10.0-28-cloud-arm64", KernelRelease{UpstreamVersion: "5.10.0", Major: 5, Minor: 10, ABI: "28", Distro: DistroDebian, Flavor: "cloud-arm64", Arch: "arm64"}},
+		// other kernels
+		{"6.9.1", KernelRelease{UpstreamVersion: "6.9.1", Major: 6, Minor: 9, Patch: 1, Distro: DistroUnknown}},
+		{"6.6.30-custom", KernelRelease{UpstreamVersion: "6.6.30", Major: 6, Minor: 6, Patch: 30, ABI: "custom", Distro: DistroUnknown}},
+		{"6.1.90+", KernelRelease{UpstreamVersion: "6.1.90", Major: 6, Minor: 1, Patch: 90, ABI: "+", Distro: DistroUnknown}},
+		{" 5.15.0-105-generic\n", KernelRelease{UpstreamVersion: "5.15.0", Major: 5, Minor: 15, ABI: "105", Distro: DistroUbuntu, Flavor: "generic"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.release, func(t *testing.T) {
+			k, err := ParseKernelRelease(tc.release)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := tc.expected
+			expected.Release = k.Release
+			if *k != expected {
+				t.Errorf("expected %+v\ngot      %+v", expected, *k)
+			}
+		})
+	}
+}
+
+func TestParseKernelReleaseMalformed(t *testing.T) {
+	for _, release := range []string{"", "  ", "linux", "v6.8.0", "6", "6.", "generic-6.8.0", "el9.x86_64"} {
+		if k, err := ParseKernelRelease(release); err == nil {
+			t.Errorf("%q: expected an error, got %+v", release, *k)
+		}
+	}
+}
+
+func TestKernelReleaseDistroVersion(t *testing.T) {
+	for release, expected := range map[string]string{
+		"4.18.0-513.9.1.el8_9.x86_64": "8.9",
+		"3.10.0-1160.el7.x86_64":      "7",
+		"5.15.0-105-generic":          "",
+	} {
+		k, err := ParseKernelRelease(release)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if k.DistroVersion() != expected {
+			t.Errorf("%s: expected distro version %q, got %q", release, expected, k.DistroVersion())
+		}
+	}
+}
@@ -0,0 +1,641 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// The xz and lzma decoders only go as far as reading a kernel image: the integrity checks are
+// not verified and the branch converters of xz are not reversed. The converters only rewrite the
+// operands of call and branch instructions, which never changes the printable text of the banner.
+
+const (
+	lzmaProbBits         = 11
+	lzmaProbInit         = 1 << (lzmaProbBits - 1)
+	lzmaMoveBits         = 5
+	lzmaStates           = 12
+	lzmaPosStatesMax     = 1 << 4
+	lzmaLiteralCoderSize = 0x300
+	lzmaMatchLenMin      = 2
+	lzmaDistStates       = 4
+	lzmaDistSlots        = 64
+	lzmaDistModelStart   = 4
+	lzmaDistModelEnd     = 14
+	lzmaFullDistances    = 1 << (lzmaDistModelEnd / 2)
+	lzmaAlignBits        = 4
+	// lzmaEndMarker is the distance of the end of payload marker
+	lzmaEndMarker = 0xFFFFFFFF
+	// lzmaAloneHeaderSize is the size of the properties, dictionary size and uncompressed size of a .lzma file
+	lzmaAloneHeaderSize = 13
+	// lzmaAloneStep is the amount decoded at once from a .lzma file, which has no chunks
+	lzmaAloneStep = 1 << 16
+
+	xzStreamHeaderSize = 12
+	xzFilterLZMA2      = 0x21
+	xzFilterX86        = 0x04
+	xzFilterRISCV      = 0x0b
+)
+
+var xzHeaderMagic = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+
+// xzCheckSizes is the size of the integrity check following each block, by check type
+var xzCheckSizes = [16]int{0, 4, 4, 4, 8, 8, 8, 16, 16, 16, 32, 32, 32, 64, 64, 64}
+
+// rangeDecoder decodes the bits of a LZMA payload, the end of the input reads as zeros and is
+// reported by overrun
+type rangeDecoder struct {
+	in   []byte
+	pos  int
+	rng  uint32
+	code uint32
+}
+
+func (rc *rangeDecoder) init(in []byte) error {
+	if len(in) < 5 || in[0] != 0 {
+		return errors.New("lzma: invalid range coder header")
+	}
+	rc.in = in
+	rc.pos = 5
+	rc.rng = 0xFFFFFFFF
+	rc.code = binary.BigEndian.Uint32(in[1:5])
+	return nil
+}
+
+func (rc *rangeDecoder) overrun() bool {
+	return rc.pos > len(rc.in)
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < 1<<24 {
+		var b byte
+		if rc.pos < len(rc.in) {
+			b = rc.in[rc.pos]
+		}
+		rc.pos++
+		rc.rng <<= 8
+		rc.code = rc.code<<8 | uint32(b)
+	}
+}
+
+// bit decodes a bit with its probability, and adapts the probability
+func (rc *rangeDecoder) bit(prob *uint16) uint32 {
+	rc.normalize()
+	bound := (rc.rng >> lzmaProbBits) * uint32(*prob)
+	if rc.code < bound {
+		rc.rng = bound
+		*prob += (1<<lzmaProbBits - *prob) >> lzmaMoveBits
+		return 0
+	}
+	rc.rng -= bound
+	rc.code -= bound
+	*prob -= *prob >> lzmaMoveBits
+	return 1
+}
+
+// bittree decodes a value most significant bit first, the probabilities indexed by the bits decoded so far
+func (rc *rangeDecoder) bittree(probs []uint16, bits uint) uint32 {
+	symbol := uint32(1)
+	for i := uint(0); i < bits; i++ {
+		symbol = symbol<<1 | rc.bit(&probs[symbol])
+	}
+	return symbol - 1<<bits
+}
+
+// bittreeReverse decodes a value least significant bit first
+func (rc *rangeDecoder) bittreeReverse(probs []uint16, bits uint) uint32 {
+	symbol := uint32(1)
+	var result uint32
+	for i := uint(0); i < bits; i++ {
+		b := rc.bit(&probs[symbol])
+		symbol = symbol<<1 | b
+		result |= b << i
+	}
+	return result
+}
+
+// direct decodes bits of fixed probability one half
+func (rc *rangeDecoder) direct(bits uint) uint32 {
+	var result uint32
+	for ; bits > 0; bits-- {
+		rc.normalize()
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		mask := 0 - (rc.code >> 31)
+		rc.code += rc.rng & mask
+		result = result<<1 + mask + 1
+	}
+	return result
+}
+
+func resetProbs(probs []uint16) {
+	for i := range probs {
+		probs[i] = lzmaProbInit
+	}
+}
+
+type lzmaLenDecoder struct {
+	choice  uint16
+	choice2 uint16
+	low     [lzmaPosStatesMax][1 << 3]uint16
+	mid     [lzmaPosStatesMax][1 << 3]uint16
+	high    [1 << 8]uint16
+}
+
+func (l *lzmaLenDecoder) reset() {
+	l.choice = lzmaProbInit
+	l.choice2 = lzmaProbInit
+	for i := range l.low {
+		resetProbs(l.low[i][:])
+		resetProbs(l.mid[i][:])
+	}
+	resetProbs(l.high[:])
+}
+
+func (l *lzmaLenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&l.choice) == 0 {
+		return rc.bittree(l.low[posState][:], 3) + lzmaMatchLenMin
+	}
+	if rc.bit(&l.choice2) == 0 {
+		return rc.bittree(l.mid[posState][:], 3) + lzmaMatchLenMin + 8
+	}
+	return rc.bittree(l.high[:], 8) + lzmaMatchLenMin + 16
+}
+
+// lzmaDecoder decodes LZMA symbols into the history
+type lzmaDecoder struct {
+	rc rangeDecoder
+	h  *decodeHistory
+	// dictStart is the history position of the last dictionary reset, the positions count from it
+	dictStart  int64
+	lc, lp, pb uint
+	state      uint32
+	rep        [4]uint32
+	isMatch    [lzmaStates][lzmaPosStatesMax]uint16
+	isRep      [lzmaStates]uint16
+	isRepG0    [lzmaStates]uint16
+	isRepG1    [lzmaStates]uint16
+	isRepG2    [lzmaStates]uint16
+	isRep0Long [lzmaStates][lzmaPosStatesMax]uint16
+	distSlot   [lzmaDistStates][lzmaDistSlots]uint16
+	// distSpecial is indexed from 1 like the bit trees, its first entry is unused
+	distSpecial [lzmaFullDistances - lzmaDistModelEnd + 1]uint16
+	distAlign   [1 << lzmaAlignBits]uint16
+	matchLen    lzmaLenDecoder
+	repLen      lzmaLenDecoder
+	literal     []uint16
+}
+
+// setProperties sets the literal context bits, literal position bits and position bits
+func (d *lzmaDecoder) setProperties(props byte) error {
+	if props >= 9*5*5 {
+		return fmt.Errorf("lzma: invalid properties %#x", props)
+	}
+	d.lc = uint(props % 9)
+	props /= 9
+	d.lp = uint(props % 5)
+	d.pb = uint(props / 5)
+	d.literal = make([]uint16, lzmaLiteralCoderSize<<(d.lc+d.lp))
+	return nil
+}
+
+func (d *lzmaDecoder) resetState() {
+	d.state = 0
+	d.rep = [4]uint32{}
+	for i := range d.isMatch {
+		resetProbs(d.isMatch[i][:])
+		resetProbs(d.isRep0Long[i][:])
+	}
+	resetProbs(d.isRep[:])
+	resetProbs(d.isRepG0[:])
+	resetProbs(d.isRepG1[:])
+	resetProbs(d.isRepG2[:])
+	for i := range d.distSlot {
+		resetProbs(d.distSlot[i][:])
+	}
+	resetProbs(d.distSpecial[:])
+	resetProbs(d.distAlign[:])
+	d.matchLen.reset()
+	d.repLen.reset()
+	resetProbs(d.literal)
+}
+
+// decode decodes symbols until at least soft bytes are produced, failing if more than hard bytes
+// would be. It returns true when the end marker is decoded.
+func (d *lzmaDecoder) decode(soft int, hard int) (bool, error) {
+	h := d.h
+	start := h.total
+	pbMask := uint32(1)<<d.pb - 1
+	lpMask := uint32(1)<<d.lp - 1
+	for h.total-start < int64(soft) {
+		if d.rc.overrun() {
+			return false, errors.New("lzma: truncated payload")
+		}
+		produced := int(h.total - start)
+		pos := uint32(h.total - d.dictStart)
+		posState := pos & pbMask
+
+		if d.rc.bit(&d.isMatch[d.state][posState]) == 0 {
+			if produced+1 > hard {
+				return false, errors.New("lzma: payload larger than expected")
+			}
+			probs := d.literal[lzmaLiteralCoderSize*(((pos&lpMask)<<d.lc)+uint32(d.get(0))>>(8-d.lc)):]
+			symbol := uint32(1)
+			if d.state < 7 {
+				symbol = d.rc.bittree(probs, 8) | 0x100
+			} else {
+				matchByte := uint32(d.get(d.rep[0])) << 1
+				offset := uint32(0x100)
+				for symbol < 0x100 {
+					matchBit := matchByte & offset
+					matchByte <<= 1
+					if d.rc.bit(&probs[offset+matchBit+symbol]) == 1 {
+						symbol = symbol<<1 | 1
+						offset = matchBit
+					} else {
+						symbol <<= 1
+						offset &= ^matchBit
+					}
+				}
+			}
+			h.put(byte(symbol))
+			switch {
+			case d.state < 4:
+				d.state = 0
+			case d.state < 10:
+				d.state -= 3
+			default:
+				d.state -= 6
+			}
+			continue
+		}
+
+		var length uint32
+		if d.rc.bit(&d.isRep[d.state]) == 0 {
+			// a match at a new distance
+			if d.state < 7 {
+				d.state = 7
+			} else {
+				d.state = 10
+			}
+			length = d.matchLen.decode(&d.rc, posState)
+			dist := d.decodeDistance(length)
+			if dist == lzmaEndMarker {
+				return true, nil
+			}
+			d.rep[3], d.rep[2], d.rep[1], d.rep[0] = d.rep[2], d.rep[1], d.rep[0], dist
+		} else {
+			if d.rc.bit(&d.isRepG0[d.state]) == 0 {
+				if d.rc.bit(&d.isRep0Long[d.state][posState]) == 0 {
+					// a single byte at the last distance
+					if d.state < 7 {
+						d.state = 9
+					} else {
+						d.state = 11
+					}
+					if err := d.copyMatch(1, produced, hard); err != nil {
+						return false, err
+					}
+					continue
+				}
+			} else {
+				var dist uint32
+				if d.rc.bit(&d.isRepG1[d.state]) == 0 {
+					dist = d.rep[1]
+				} else {
+					if d.rc.bit(&d.isRepG2[d.state]) == 0 {
+						dist = d.rep[2]
+					} else {
+						dist = d.rep[3]
+						d.rep[3] = d.rep[2]
+					}
+					d.rep[2] = d.rep[1]
+				}
+				d.rep[1] = d.rep[0]
+				d.rep[0] = dist
+			}
+			if d.state < 7 {
+				d.state = 8
+			} else {
+				d.state = 11
+			}
+			length = d.repLen.decode(&d.rc, posState)
+		}
+		if err := d.copyMatch(length, produced, hard); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func (d *lzmaDecoder) decodeDistance(length uint32) uint32 {
+	distState := length - lzmaMatchLenMin
+	if distState >= lzmaDistStates {
+		distState = lzmaDistStates - 1
+	}
+	slot := d.rc.bittree(d.distSlot[distState][:], 6)
+	if slot < lzmaDistModelStart {
+		return slot
+	}
+	limit := uint(slot>>1) - 1
+	dist := 2 | slot&1
+	if slot < lzmaDistModelEnd {
+		dist <<= limit
+		return dist + d.rc.bittreeReverse(d.distSpecial[dist-slot:], limit)
+	}
+	dist = dist<<(limit-lzmaAlignBits) | d.rc.direct(limit-lzmaAlignBits)
+	return dist<<lzmaAlignBits + d.rc.bittreeReverse(d.distAlign[:], lzmaAlignBits)
+}
+
+// get returns the byte dist+1 bytes back in the dictionary, zero before its start
+func (d *lzmaDecoder) get(dist uint32) byte {
+	if int64(dist) >= d.h.total-d.dictStart {
+		return 0
+	}
+	return d.h.get(dist)
+}
+
+func (d *lzmaDecoder) copyMatch(length uint32, produced int, hard int) error {
+	if produced+int(length) > hard {
+		return errors.New("lzma: payload larger than expected")
+	}
+	if int64(d.rep[0]) >= d.h.total-d.dictStart {
+		return errors.New("lzma: match distance out of the dictionary")
+	}
+	return d.h.copyMatch(int64(d.rep[0])+1, int(length))
+}
+
+// lzma2Decoder decodes the chunks of a LZMA2 payload, each either stored or LZMA compressed
+type lzma2Decoder struct {
+	in        []byte
+	pos       int
+	lzma      lzmaDecoder
+	haveProps bool
+}
+
+func newLZMA2Decoder(in []byte, h *decodeHistory) *lzma2Decoder {
+	d := &lzma2Decoder{in: in}
+	d.lzma.h = h
+	return d
+}
+
+// fill decodes the next chunk, returning io.EOF after the end of payload chunk
+func (d *lzma2Decoder) fill() error {
+	if d.pos >= len(d.in) {
+		return errors.New("lzma2: truncated payload")
+	}
+	control := d.in[d.pos]
+	switch {
+	case control == 0x00:
+		d.pos++
+		return io.EOF
+	case control == 0x01 || control == 0x02:
+		if control == 0x01 {
+			d.lzma.dictStart = d.lzma.h.total
+		}
+		if d.pos+3 > len(d.in) {
+			return errors.New("lzma2: truncated chunk header")
+		}
+		size := int(binary.BigEndian.Uint16(d.in[d.pos+1:])) + 1
+		d.pos += 3
+		if d.pos+size > len(d.in) {
+			return errors.New("lzma2: truncated stored chunk")
+		}
+		d.lzma.h.write(d.in[d.pos : d.pos+size])
+		d.pos += size
+		return nil
+	case control >= 0x80:
+		if d.pos+5 > len(d.in) {
+			return errors.New("lzma2: truncated chunk header")
+		}
+		unpacked := int(control&0x1f)<<16 + int(binary.BigEndian.Uint16(d.in[d.pos+1:])) + 1
+		compressed := int(binary.BigEndian.Uint16(d.in[d.pos+3:])) + 1
+		d.pos += 5
+		reset := (control >> 5) & 3
+		if reset >= 2 {
+			if d.pos >= len(d.in) {
+				return errors.New("lzma2: truncated chunk header")
+			}
+			if err := d.lzma.setProperties(d.in[d.pos]); err != nil {
+				return err
+			}
+			if d.lzma.lc+d.lzma.lp > 4 {
+				return errors.New("lzma2: invalid properties")
+			}
+			d.pos++
+			d.haveProps = true
+		} else if !d.haveProps {
+			return errors.New("lzma2: first chunk without properties")
+		}
+		if reset == 3 {
+			d.lzma.dictStart = d.lzma.h.total
+		}
+		if reset >= 1 {
+			d.lzma.resetState()
+		}
+		if d.pos+compressed > len(d.in) {
+			return errors.New("lzma2: truncated compressed chunk")
+		}
+		if err := d.lzma.rc.init(d.in[d.pos : d.pos+compressed]); err != nil {
+			return err
+		}
+		start := d.lzma.h.total
+		end, err := d.lzma.decode(unpacked, unpacked)
+		if err != nil {
+			return err
+		}
+		if end || d.lzma.h.total-start != int64(unpacked) || d.lzma.rc.overrun() {
+			return errors.New("lzma2: corrupt compressed chunk")
+		}
+		d.pos += compressed
+		return nil
+	default:
+		return fmt.Errorf("lzma2: invalid chunk control %#x", control)
+	}
+}
+
+// xzDecoder decodes the blocks of a xz stream, each holding a LZMA2 payload
+type xzDecoder struct {
+	in        []byte
+	pos       int
+	checkSize int
+	h         *decodeHistory
+	block     *lzma2Decoder
+	// blockStart is the offset of the current block, its size is padded to a multiple of four
+	blockStart int
+}
+
+// openXz decompresses a xz stream
+func openXz(payload []byte) (io.ReadCloser, error) {
+	if len(payload) < xzStreamHeaderSize || !bytes.HasPrefix(payload, xzHeaderMagic) {
+		return nil, errors.New("xz: invalid stream header")
+	}
+	flags := payload[6:8]
+	if flags[0] != 0 || flags[1] > 0x0f || crc32.ChecksumIEEE(flags) != binary.LittleEndian.Uint32(payload[8:12]) {
+		return nil, errors.New("xz: invalid stream flags")
+	}
+	d := &xzDecoder{in: payload, pos: xzStreamHeaderSize, checkSize: xzCheckSizes[flags[1]], h: newDecodeHistory()}
+	return &decodeReader{h: d.h, fill: d.fill}, nil
+}
+
+func (d *xzDecoder) fill() error {
+	if d.block == nil {
+		if err := d.readBlockHeader(); err != nil {
+			return err
+		}
+	}
+	err := d.block.fill()
+	if err != io.EOF {
+		return err
+	}
+
+	// skip the padding and the check, the next block or the index follow
+	d.pos += d.block.pos
+	d.pos += (4 - (d.pos-d.blockStart)%4) % 4
+	d.pos += d.checkSize
+	d.block = nil
+	return nil
+}
+
+// readBlockHeader starts the LZMA2 decoding of the next block, returning io.EOF at the index
+func (d *xzDecoder) readBlockHeader() error {
+	if d.pos >= len(d.in) {
+		return errors.New("xz: truncated stream")
+	}
+	if d.in[d.pos] == 0 {
+		return io.EOF
+	}
+	size := (int(d.in[d.pos]) + 1) * 4
+	if d.pos+size > len(d.in) {
+		return errors.New("xz: truncated block header")
+	}
+	header := d.in[d.pos : d.pos+size]
+	if crc32.ChecksumIEEE(header[:size-4]) != binary.LittleEndian.Uint32(header[size-4:]) {
+		return errors.New("xz: corrupt block header")
+	}
+	flags := header[1]
+	if flags&0x3c != 0 {
+		return fmt.Errorf("xz: unsupported block flags %#x", flags)
+	}
+
+	r := bytes.NewReader(header[2 : size-4])
+	if flags&0x40 != 0 {
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return errors.New("xz: invalid compressed size")
+		}
+	}
+	if flags&0x80 != 0 {
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return errors.New("xz: invalid uncompressed size")
+		}
+	}
+	filters := int(flags&0x03) + 1
+	for i := 0; i < filters; i++ {
+		id, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errors.New("xz: invalid filter flags")
+		}
+		propsSize, err := binary.ReadUvarint(r)
+		if err != nil || propsSize > uint64(r.Len()) {
+			return errors.New("xz: invalid filter flags")
+		}
+		props := make([]byte, propsSize)
+		r.Read(props)
+
+		if i < filters-1 {
+			if id < xzFilterX86 || id > xzFilterRISCV {
+				return fmt.Errorf("xz: unsupported filter %#x", id)
+			}
+			continue
+		}
+		if id != xzFilterLZMA2 || propsSize != 1 || props[0] > 40 {
+			return fmt.Errorf("xz: unsupported last filter %#x", id)
+		}
+		d.h.window = lzma2DictionarySize(props[0])
+	}
+
+	d.blockStart = d.pos
+	d.pos += size
+	d.block = newLZMA2Decoder(d.in[d.pos:], d.h)
+	return nil
+}
+
+// lzma2DictionarySize decodes the dictionary size property of the LZMA2 filter
+func lzma2DictionarySize(b byte) int {
+	if b == 40 {
+		return math.MaxUint32
+	}
+	return (2 | int(b&1)) << (b/2 + 11)
+}
+
+// lzmaAloneDecoder decodes a .lzma file: the properties, the dictionary size and the uncompressed
+// size, unknown when all ones, then a single LZMA payload
+type lzmaAloneDecoder struct {
+	lzma      lzmaDecoder
+	remaining int64
+}
+
+// openLZMA decompresses a .lzma file, as written by 'xz --format=lzma'
+func openLZMA(payload []byte) (io.ReadCloser, error) {
+	if len(payload) < lzmaAloneHeaderSize {
+		return nil, errors.New("lzma: truncated header")
+	}
+	d := &lzmaAloneDecoder{remaining: math.MaxInt64}
+	if err := d.lzma.setProperties(payload[0]); err != nil {
+		return nil, err
+	}
+	d.lzma.resetState()
+	d.lzma.h = newDecodeHistory()
+	d.lzma.h.window = int(binary.LittleEndian.Uint32(payload[1:5]))
+	if size := binary.LittleEndian.Uint64(payload[5:13]); size != math.MaxUint64 {
+		if size > math.MaxInt64 {
+			return nil, errors.New("lzma: invalid uncompressed size")
+		}
+		d.remaining = int64(size)
+	}
+	if err := d.lzma.rc.init(payload[lzmaAloneHeaderSize:]); err != nil {
+		return nil, err
+	}
+	return &decodeReader{h: d.lzma.h, fill: d.fill}, nil
+}
+
+func (d *lzmaAloneDecoder) fill() error {
+	if d.remaining == 0 {
+		return io.EOF
+	}
+	hard := math.MaxInt
+	if d.remaining < int64(hard) {
+		hard = int(d.remaining)
+	}
+	soft := lzmaAloneStep
+	if soft > hard {
+		soft = hard
+	}
+	start := d.lzma.h.total
+	end, err := d.lzma.decode(soft, hard)
+	d.remaining -= d.lzma.h.total - start
+	if err != nil {
+		return err
+	}
+	if end {
+		d.remaining = 0
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readDecompressFixture reads a testdata/decompress payload, each the same input compressed with
+// the tool named by its extension. decoder-blocks.xz is split in 64KiB blocks with SHA-256 checks.
+func readDecompressFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "decompress", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testDecompressInput returns the input of the testdata/decompress payloads
+func testDecompressInput(t *testing.T) []byte {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(readDecompressFixture(t, "decoder.gz")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testDecompress checks a decoder against the input of the testdata/decompress payloads, and that
+// it fails on the payload truncated
+func testDecompress(t *testing.T, open func(payload []byte) (io.ReadCloser, error), fixture string) {
+	t.Helper()
+	payload := readDecompressFixture(t, fixture)
+	r, err := open(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, testDecompressInput(t)) {
+		t.Errorf("unexpected output of %d bytes", len(data))
+	}
+
+	r, err = open(payload[:len(payload)/2])
+	if err == nil {
+		_, err = io.ReadAll(r)
+	}
+	if err == nil {
+		t.Errorf("expected an error for the truncated payload")
+	}
+}
+
+func TestOpenXz(t *testing.T) {
+	for _, fixture := range []string{"decoder.xz", "decoder-blocks.xz"} {
+		t.Run(fixture, func(t *testing.T) {
+			testDecompress(t, openXz, fixture)
+		})
+	}
+
+	payload := readDecompressFixture(t, "decoder.xz")
+	for _, data := range [][]byte{
+		nil,
+		payload[:xzStreamHeaderSize-1],
+		// a corrupt stream header
+		append(append([]byte{}, payload[:7]...), 0xff, 0xff, 0xff, 0xff, 0xff),
+	} {
+		if _, err := openXz(data); err == nil {
+			t.Errorf("expected an error for %x", data)
+		}
+	}
+}
+
+func TestOpenLZMA(t *testing.T) {
+	testDecompress(t, openLZMA, "decoder.lzma")
+
+	// the uncompressed size unknown, the end marker ends the payload
+	payload := append([]byte{}, readDecompressFixture(t, "decoder.lzma")...)
+	for i := 5; i < lzmaAloneHeaderSize; i++ {
+		payload[i] = 0xff
+	}
+	r, err := openLZMA(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, testDecompressInput(t)) {
+		t.Errorf("unexpected output of %d bytes", len(data))
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		// invalid properties
+		{0xe1, 0x00, 0x00, 0x80, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// a range coder not starting with a zero byte
+		{0x5d, 0x00, 0x00, 0x80, 0x00, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x00, 0x00, 0x00, 0x00},
+	} {
+		if _, err := openLZMA(data); err == nil {
+			t.Errorf("expected an error for %x", data)
+		}
+	}
+}
+
+func TestLZMA2DictionarySize(t *testing.T) {
+	testCases := []struct {
+		props    byte
+		expected int
+	}{
+		{0, 4 << 10},
+		{1, 6 << 10},
+		{18, 2 << 20},
+		{22, 8 << 20},
+		{28, 64 << 20},
+		{40, 0xffffffff},
+	}
+	for _, tc := range testCases {
+		if size := lzma2DictionarySize(tc.props); size != tc.expected {
+			t.Errorf("props %d: expected %d, got %d", tc.props, tc.expected, size)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultMatrixConfig indicates default location of the build matrix config, relative to the repository root
+	DefaultMatrixConfig = ".github/precompiled-matrix-config.json"
+)
+
+// Matrix dimensions, as named in the exclusion rules and the GitHub Actions matrices
+const (
+	DimensionDriverBranch = "driver_branch"
+	DimensionFlavor       = "flavor"
+	DimensionDist         = "dist"
+	DimensionLTSKernel    = "lts_kernel"
+	DimensionPlatform     = "platform"
+)
+
+// matrixDimensions lists the dimensions of every matrix, in expansion order
+var matrixDimensions = map[string][]string{
+	// the precompiled driver images
+	"precompiled-build": {DimensionDriverBranch, DimensionFlavor, DimensionDist, DimensionLTSKernel},
+	// the end-to-end tests of the precompiled images
+	"precompiled-e2e": {DimensionDist, DimensionLTSKernel},
+	// the kernels looked up by build-kernel-matrix.sh
+	"kernel": {DimensionDist, DimensionLTSKernel, DimensionPlatform, DimensionFlavor, DimensionDriverBranch},
+}
+
+// MatrixRule excludes the combinations matching all of its dimensions
+type MatrixRule map[string]string
+
+// PlatformConstraint restricts the combinations built for a platform
+type PlatformConstraint struct {
+	// Dists lists the only distributions built for the platform, all if empty
+	Dists []string `json:"dist,omitempty"`
+	// ExcludeFlavors lists the kernel flavors not available on the platform
+	ExcludeFlavors []string `json:"exclude_flavors,omitempty"`
+	// Suffix is appended to the image tags and the matrix files of the platform, e.g. -arm64
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// MatrixConfig is the build matrix config shared by the GitHub Actions workflows and this command
+type MatrixConfig struct {
+	DriverBranches []string `json:"driver_branch"`
+	KernelFlavors  []string `json:"kernel_flavors"`
+	Dists          []string `json:"dist"`
+	LTSKernels     []string `json:"lts_kernel"`
+	Platforms      []string `json:"platforms"`
+	// ExcludeBuildPairs excludes driver branches from distributions in the kernel matrix
+	ExcludeBuildPairs       []MatrixRule                  `json:"exclude_build_matrix_pairs"`
+	ExcludePrecompiledBuild []MatrixRule                  `json:"exclude_precompiled_build_matrix"`
+	ExcludePrecompiledE2E   []MatrixRule                  `json:"exclude_precompiled_e2e_matrix"`
+	PlatformConstraints     map[string]PlatformConstraint `json:"platform_constraints,omitempty"`
+}
+
+// values returns the values of a dimension
+func (m *MatrixConfig) values(dimension string) []string {
+	switch dimension {
+	case DimensionDriverBranch:
+		return m.DriverBranches
+	case DimensionFlavor:
+		return m.KernelFlavors
+	case DimensionDist:
+		return m.Dists
+	case DimensionLTSKernel:
+		return m.LTSKernels
+	case DimensionPlatform:
+		return m.Platforms
+	}
+	return nil
+}
+
+// matrixRules are exclusion rules, with the config key they are listed under
+type matrixRules struct {
+	key   string
+	rules []MatrixRule
+}
+
+// rules returns the exclusion rules of a matrix. The kernels are only looked up for the
+// combinations built, so the kernel matrix also excludes what the precompiled build does.
+func (m *MatrixConfig) rules(matrix string) []matrixRules {
+	precompiledBuild := matrixRules{"exclude_precompiled_build_matrix", m.ExcludePrecompiledBuild}
+	switch matrix {
+	case "precompiled-build":
+		return []matrixRules{precompiledBuild}
+	case "precompiled-e2e":
+		return []matrixRules{{"exclude_precompiled_e2e_matrix", m.ExcludePrecompiledE2E}}
+	case "kernel":
+		return []matrixRules{{"exclude_build_matrix_pairs", m.ExcludeBuildPairs}, precompiledBuild}
+	}
+	return nil
+}
+
+// Validate checks the dimensions are set without duplicates and the rules and constraints only
+// name known dimensions and values
+func (m *MatrixConfig) Validate() error {
+	var errs []string
+	known := map[string]map[string]bool{}
+	for _, dimension := range []string{DimensionDriverBranch, DimensionFlavor, DimensionDist, DimensionLTSKernel, DimensionPlatform} {
+		known[dimension] = map[string]bool{}
+		if len(m.values(dimension)) == 0 {
+			errs = append(errs, fmt.Sprintf("no %s", dimension))
+		}
+		for _, value := range m.values(dimension) {
+			if known[dimension][value] {
+				errs = append(errs, fmt.Sprintf("duplicate %s %q", dimension, value))
+			}
+			known[dimension][value] = true
+		}
+	}
+
+	for _, matrix := range matrixNames() {
+		for _, set := range m.rules(matrix) {
+			for i, rule := range set.rules {
+				if len(rule) == 0 {
+					errs = append(errs, fmt.Sprintf("%s[%d] is empty and would exclude everything", set.key, i))
+				}
+				for dimension, value := range rule {
+					if !containsString(matrixDimensions[matrix], dimension) {
+						errs = append(errs, fmt.Sprintf("%s[%d]: %s is not a dimension of the %s matrix", set.key, i, dimension, matrix))
+					} else if !known[dimension][value] {
+						errs = append(errs, fmt.Sprintf("%s[%d]: unknown %s %q", set.key, i, dimension, value))
+					}
+				}
+			}
+		}
+	}
+
+	for platform, constraint := range m.PlatformConstraints {
+		if !known[DimensionPlatform][platform] {
+			errs = append(errs, fmt.Sprintf("platform_constraints: unknown platform %q", platform))
+		}
+		for _, dist := range constraint.Dists {
+			if !known[DimensionDist][dist] {
+				errs = append(errs, fmt.Sprintf("platform_constraints.%s: unknown dist %q", platform, dist))
+			}
+		}
+		for _, flavor := range constraint.ExcludeFlavors {
+			if !known[DimensionFlavor][flavor] {
+				errs = append(errs, fmt.Sprintf("platform_constraints.%s: unknown flavor %q", platform, flavor))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		// the rules shared by several matrices are checked once per matrix
+		errs = uniqueStrings(errs)
+		sort.Strings(errs)
+		return fmt.Errorf("invalid matrix config:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// Explain returns why a combination is excluded from a matrix, nothing if it is included
+func (m *MatrixConfig) Explain(matrix string, combination map[string]string) []string {
+	var reasons []string
+	for _, set := range m.rules(matrix) {
+		for i, rule := range set.rules {
+			if rule.matches(combination) {
+				reasons = append(reasons, fmt.Sprintf("excluded by %s[%d] %s", set.key, i, rule))
+			}
+		}
+	}
+
+	platform, ok := combination[DimensionPlatform]
+	if !ok {
+		return reasons
+	}
+	constraint := m.PlatformConstraints[platform]
+	if dist := combination[DimensionDist]; len(constraint.Dists) > 0 && !containsString(constraint.Dists, dist) {
+		reasons = append(reasons, fmt.Sprintf("platform %s is only built for dist %s", platform, strings.Join(constraint.Dists, ", ")))
+	}
+	if flavor := combination[DimensionFlavor]; containsString(constraint.ExcludeFlavors, flavor) {
+		reasons = append(reasons, fmt.Sprintf("flavor %s is not available on platform %s", flavor, platform))
+	}
+	return reasons
+}
+
+// Expand returns the combinations of a matrix which are not excluded, in the order of the
+// dimensions and of their values in the config
+func (m *MatrixConfig) Expand(matrix string) []map[string]string {
+	dimensions := matrixDimensions[matrix]
+	combinations := []map[string]string{{}}
+	for _, dimension := range dimensions {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range m.values(dimension) {
+				c := map[string]string{dimension: value}
+				for k, v := range combination {
+					c[k] = v
+				}
+				next = append(next, c)
+			}
+		}
+		combinations = next
+	}
+
+	included := []map[string]string{}
+	for _, combination := range combinations {
+		if len(m.Explain(matrix, combination)) > 0 {
+			continue
+		}
+		if platform, ok := combination[DimensionPlatform]; ok {
+			combination["platform_suffix"] = m.PlatformConstraints[platform].Suffix
+		}
+		included = append(included, combination)
+	}
+	return included
+}
+
+func (r MatrixRule) matches(combination map[string]string) bool {
+	for dimension, value := range r {
+		if combination[dimension] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (r MatrixRule) String() string {
+	var parts []string
+	for dimension, value := range r {
+		parts = append(parts, dimension+"="+value)
+	}
+	sort.Strings(parts)
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+type matrixOptions struct {
+	configFile string
+	matrix     string
+	format     string
+}
+
+func newMatrixCommand() *cli.Command {
+	opts := matrixOptions{}
+
+	configFlag := &cli.StringFlag{
+		Name:        "config",
+		Aliases:     []string{"c"},
+		Usage:       "Build matrix config",
+		Value:       DefaultMatrixConfig,
+		Destination: &opts.configFile,
+		EnvVars:     []string{"MATRIX_CONFIG"},
+	}
+	matrixFlag := &cli.StringFlag{
+		Name:        "matrix",
+		Aliases:     []string{"m"},
+		Usage:       fmt.Sprintf("Matrix, one of %s", strings.Join(matrixNames(), ", ")),
+		Required:    true,
+		Destination: &opts.matrix,
+	}
+
+	// Create the 'matrix validate' subcommand
+	validate := cli.Command{}
+	validate.Name = "validate"
+	validate.Usage = "Validate the build matrix config"
+	validate.UsageText = "[-c | --config]"
+	validate.Action = func(c *cli.Context) error {
+		return ValidateMatrix(c, &opts)
+	}
+	validate.Flags = []cli.Flag{configFlag}
+
+	// Create the 'matrix expand' subcommand
+	expand := cli.Command{}
+	expand.Name = "expand"
+	expand.Usage = "Print the combinations of a matrix as JSON for GitHub Actions or GitLab"
+	expand.UsageText = "--matrix [-c | --config] [--format]"
+	expand.Action = func(c *cli.Context) error {
+		return ExpandMatrix(c, &opts)
+	}
+	expand.Flags = []cli.Flag{
+		configFlag,
+		matrixFlag,
+		&cli.StringFlag{
+			Name:        "format",
+			Aliases:     []string{"f"},
+			Usage:       "Output format, github for a strategy.matrix with include, gitlab for parallel:matrix",
+			Value:       "github",
+			Destination: &opts.format,
+		},
+	}
+
+	// Create the 'matrix explain' subcommand
+	explain := cli.Command{}
+	explain.Name = "explain"
+	explain.Usage = "Explain why a combination is excluded from a matrix, exit with 1 if it is"
+	explain.UsageText = "--matrix [-c | --config] DIMENSION=VALUE..."
+	explain.Action = func(c *cli.Context) error {
+		return ExplainMatrix(c, &opts)
+	}
+	explain.Flags = []cli.Flag{configFlag, matrixFlag}
+
+	matrix := cli.Command{}
+	matrix.Name = "matrix"
+	matrix.Usage = "Expand the CI build matrices from their config"
+	matrix.Subcommands = []*cli.Command{&validate, &expand, &explain}
+	return &matrix
+}
+
+// ValidateMatrix validates the config and prints the size of every matrix
+func ValidateMatrix(c *cli.Context, opts *matrixOptions) error {
+	config, err := LoadMatrixConfig(opts.configFile)
+	if err != nil {
+		return err
+	}
+	for _, matrix := range matrixNames() {
+		fmt.Printf("%s: %d combinations\n", matrix, len(config.Expand(matrix)))
+	}
+	return nil
+}
+
+// ExpandMatrix prints the combinations of a matrix on a single line, as GitHub outputs expect
+func ExpandMatrix(c *cli.Context, opts *matrixOptions) error {
+	config, err := loadMatrix(opts)
+	if err != nil {
+		return err
+	}
+	combinations := config.Expand(opts.matrix)
+
+	var output interface{}
+	switch opts.format {
+	case "github":
+		output = map[string]interface{}{"include": combinations}
+	case "gitlab":
+		variables := []map[string]string{}
+		for _, combination := range combinations {
+			v := map[string]string{}
+			for dimension, value := range combination {
+				v[strings.ToUpper(dimension)] = value
+			}
+			variables = append(variables, v)
+		}
+		output = map[string]interface{}{"parallel": map[string]interface{}{"matrix": variables}}
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.format)
+	}
+
+	// encoding/json sorts the map keys, so the output only depends on the config
+	data, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	log.Debugf("expanded %s matrix to %d combinations", opts.matrix, len(combinations))
+	return nil
+}
+
+// ExplainMatrix prints whether a combination is included in a matrix and the reasons it is not
+func ExplainMatrix(c *cli.Context, opts *matrixOptions) error {
+	config, err := loadMatrix(opts)
+	if err != nil {
+		return err
+	}
+
+	combination := map[string]string{}
+	for _, arg := range c.Args().Slice() {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid combination %q, expected DIMENSION=VALUE", arg)
+		}
+		if !containsString(matrixDimensions[opts.matrix], parts[0]) {
+			return fmt.Errorf("%s is not a dimension of the %s matrix", parts[0], opts.matrix)
+		}
+		if !containsString(config.values(parts[0]), parts[1]) {
+			return fmt.Errorf("unknown %s %q, expected one of %s", parts[0], parts[1], strings.Join(config.values(parts[0]), ", "))
+		}
+		combination[parts[0]] = parts[1]
+	}
+	for _, dimension := range matrixDimensions[opts.matrix] {
+		if _, ok := combination[dimension]; !ok {
+			return fmt.Errorf("missing %s, the %s matrix has dimensions %s", dimension, opts.matrix, strings.Join(matrixDimensions[opts.matrix], ", "))
+		}
+	}
+
+	reasons := config.Explain(opts.matrix, combination)
+	if len(reasons) == 0 {
+		fmt.Printf("included in the %s matrix\n", opts.matrix)
+		return nil
+	}
+	for _, reason := range reasons {
+		fmt.Println(reason)
+	}
+	return cli.Exit("", 1)
+}
+
+// LoadMatrixConfig reads and validates the build matrix config, rejecting unknown keys
+func LoadMatrixConfig(configFile string) (*MatrixConfig, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read matrix config: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	config := &MatrixConfig{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", configFile, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func loadMatrix(opts *matrixOptions) (*MatrixConfig, error) {
+	if _, ok := matrixDimensions[opts.matrix]; !ok {
+		return nil, fmt.Errorf("unknown matrix %s, expected one of %s", opts.matrix, strings.Join(matrixNames(), ", "))
+	}
+	return LoadMatrixConfig(opts.configFile)
+}
+
+func matrixNames() []string {
+	var names []string
+	for name := range matrixDimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func testMatrixConfig() *MatrixConfig {
+	return &MatrixConfig{
+		DriverBranches: []string{"580", "595"},
+		KernelFlavors:  []string{"generic", "azure-fde"},
+		Dists:          []string{"ubuntu22.04", "ubuntu24.04"},
+		LTSKernels:     []string{"5.15", "6.8"},
+		Platforms:      []string{"amd64", "arm64"},
+		ExcludeBuildPairs: []MatrixRule{
+			{DimensionDriverBranch: "595", DimensionDist: "ubuntu22.04"},
+		},
+		ExcludePrecompiledBuild: []MatrixRule{
+			{DimensionDist: "ubuntu24.04", DimensionLTSKernel: "5.15"},
+			{DimensionDist: "ubuntu22.04", DimensionFlavor: "azure-fde"},
+		},
+		ExcludePrecompiledE2E: []MatrixRule{
+			{DimensionDist: "ubuntu22.04", DimensionLTSKernel: "6.8"},
+		},
+		PlatformConstraints: map[string]PlatformConstraint{
+			"arm64": {Dists: []string{"ubuntu24.04"}, ExcludeFlavors: []string{"azure-fde"}, Suffix: "-arm64"},
+		},
+	}
+}
+
+func TestMatrixConfigExpand(t *testing.T) {
+	config := testMatrixConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var kernel []string
+	for _, c := range config.Expand("kernel") {
+		kernel = append(kernel, strings.Join([]string{c[DimensionDist], c[DimensionLTSKernel], c[DimensionPlatform], c[DimensionFlavor], c[DimensionDriverBranch], c["platform_suffix"]}, "/"))
+	}
+	// the kernel matrix applies the precompiled build exclusions and the platform constraints
+	expected := []string{
+		"ubuntu22.04/5.15/amd64/generic/580/",
+		"ubuntu22.04/6.8/amd64/generic/580/",
+		"ubuntu24.04/6.8/amd64/generic/580/",
+		"ubuntu24.04/6.8/amd64/generic/595/",
+		"ubuntu24.04/6.8/amd64/azure-fde/580/",
+		"ubuntu24.04/6.8/amd64/azure-fde/595/",
+		"ubuntu24.04/6.8/arm64/generic/580/-arm64",
+		"ubuntu24.04/6.8/arm64/generic/595/-arm64",
+	}
+	if strings.Join(kernel, " ") != strings.Join(expected, " ") {
+		t.Errorf("unexpected kernel matrix:\n  %s", strings.Join(kernel, "\n  "))
+	}
+
+	if e2e := config.Expand("precompiled-e2e"); len(e2e) != 3 {
+		t.Errorf("unexpected e2e matrix %v", e2e)
+	}
+	reasons := config.Explain("kernel", map[string]string{DimensionDist: "ubuntu22.04", DimensionLTSKernel: "5.15", DimensionPlatform: "arm64", DimensionFlavor: "azure-fde", DimensionDriverBranch: "580"})
+	if len(reasons) != 3 {
+		t.Errorf("unexpected reasons %v", reasons)
+	}
+}
+
+func TestMatrixConfigValidate(t *testing.T) {
+	config := testMatrixConfig()
+	config.ExcludePrecompiledBuild = append(config.ExcludePrecompiledBuild, MatrixRule{DimensionFlavor: "oracle"}, MatrixRule{})
+	config.ExcludePrecompiledE2E = append(config.ExcludePrecompiledE2E, MatrixRule{DimensionFlavor: "generic"})
+	config.PlatformConstraints["s390x"] = PlatformConstraint{}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	expected := []string{
+		`exclude_precompiled_build_matrix[2]: unknown flavor "oracle"`,
+		"exclude_precompiled_build_matrix[3] is empty and would exclude everything",
+		"exclude_precompiled_e2e_matrix[1]: flavor is not a dimension of the precompiled-e2e matrix",
+		`platform_constraints: unknown platform "s390x"`,
+	}
+	// the precompiled build rules are shared by the kernel matrix and reported once
+	if lines := strings.Split(err.Error(), "\n  ")[1:]; strings.Join(lines, "|") != strings.Join(expected, "|") {
+		t.Errorf("unexpected errors:\n  %s", strings.Join(lines, "\n  "))
+	}
+}
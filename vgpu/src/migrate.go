@@ -0,0 +1,153 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+type migrateCheckOptions struct {
+	guestVersion      string
+	destHostVersion   string
+	destHostBranch    string
+	gpu               string
+	subsystemDeviceID string
+}
+
+func newMigrateCheckCommand() *cli.Command {
+	opts := migrateCheckOptions{}
+
+	// Create the 'migrate-check' subcommand
+	migrateCheck := cli.Command{}
+	migrateCheck.Name = "migrate-check"
+	migrateCheck.Usage = "Check if a guest driver stays compatible after live-migration to a host running another vGPU manager"
+	migrateCheck.UsageText = "--guest-version --dest-host-version --dest-host-branch [--gpu] [--ssid] [-c | --catalog-file]"
+	migrateCheck.Action = func(c *cli.Context) error {
+		return MigrateCheck(c, &opts)
+	}
+	migrateCheck.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "guest-version",
+			Usage:       "Guest driver version running in the VM",
+			Required:    true,
+			Destination: &opts.guestVersion,
+		},
+		&cli.StringFlag{
+			Name:        "dest-host-version",
+			Usage:       "vGPU manager version of the destination host",
+			Required:    true,
+			Destination: &opts.destHostVersion,
+		},
+		&cli.StringFlag{
+			Name:        "dest-host-branch",
+			Usage:       "vGPU manager branch of the destination host",
+			Required:    true,
+			Destination: &opts.destHostBranch,
+		},
+		&cli.StringFlag{
+			Name:        "gpu",
+			Usage:       "Evaluate GPU allow / deny rules for this vGPU PCI device id, e.g. 0x2236",
+			Destination: &opts.gpu,
+		},
+		&cli.StringFlag{
+			Name:        "ssid",
+			Usage:       "Evaluate GPU allow / deny rules for this vGPU PCI subsystem device id",
+			Destination: &opts.subsystemDeviceID,
+		},
+		&cli.StringFlag{
+			Name:        "catalog-file",
+			Aliases:     []string{"c"},
+			Usage:       "vGPU driver catalog file",
+			Value:       DefaultCatalogFile,
+			Destination: &catalogFile,
+			EnvVars:     []string{"VGPU_DRIVER_CATALOG_FILE"},
+		},
+	}
+	return &migrateCheck
+}
+
+// MigrateCheck evaluates the catalog rules of the destination host for the guest driver running in the VM
+func MigrateCheck(c *cli.Context, opts *migrateCheckOptions) error {
+	log.Infof("Starting 'migrate-check' with %v", c.App.Name)
+
+	driverCatalog, err := LoadCatalog()
+	if err != nil {
+		return fmt.Errorf("unable to load catalog file: %v", err)
+	}
+
+	var pciDeviceInfo *PCIDeviceInfo
+	if opts.gpu != "" || opts.subsystemDeviceID != "" {
+		pciDeviceInfo = &PCIDeviceInfo{
+			name:        opts.gpu,
+			vendor:      NvidiaVendorID,
+			deviceID:    strings.ToLower(opts.gpu),
+			subsystemID: strings.ToLower(opts.subsystemDeviceID),
+		}
+	}
+
+	reasons, err := CheckMigration(driverCatalog, opts.guestVersion, strings.ToUpper(opts.destHostVersion), strings.ToUpper(opts.destHostBranch), pciDeviceInfo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("GUEST_DRIVER_VERSION=%s\n", opts.guestVersion)
+	fmt.Printf("DEST_HOST_DRIVER_VERSION=%s\n", strings.ToUpper(opts.destHostVersion))
+	fmt.Printf("DEST_HOST_DRIVER_BRANCH=%s\n", strings.ToUpper(opts.destHostBranch))
+	if len(reasons) > 0 {
+		fmt.Println("MIGRATION_COMPATIBLE=false")
+		for _, reason := range reasons {
+			fmt.Printf("# %s\n", reason)
+		}
+		log.Infof("Guest driver %s is not compatible with host version %s branch %s: %s", opts.guestVersion, opts.destHostVersion, opts.destHostBranch, strings.Join(reasons, "; "))
+		return cli.Exit("", 1)
+	}
+	fmt.Println("MIGRATION_COMPATIBLE=true")
+
+	log.Infof("Completed 'migrate-check' with %v", c.App.Name)
+	return nil
+}
+
+// CheckMigration returns why the guest driver is not compatible with the destination host, or nothing if it is
+func CheckMigration(driverCatalog *VGPUDriverCatalog, guestVersion string, hostVersion string, hostBranch string, pciDeviceInfo *PCIDeviceInfo) ([]string, error) {
+	eval, err := EvaluateCatalog(driverCatalog, hostVersion, hostBranch, pciDeviceInfo)
+	if err != nil {
+		// the destination branch is unknown or rejected for this GPU / CPU: nothing is compatible
+		return []string{err.Error()}, nil
+	}
+
+	found := false
+	var reasons []string
+	for _, verdict := range eval.Guests {
+		if verdict.Driver.Version != guestVersion {
+			continue
+		}
+		if verdict.Compatible() {
+			// any matching descriptor accepting the guest driver is enough
+			return nil, nil
+		}
+		found = true
+		reasons = append(reasons, verdict.Reasons...)
+	}
+	if !found {
+		return []string{fmt.Sprintf("guest driver %s is not described in catalog file", guestVersion)}, nil
+	}
+	if eval.HostDriverInfo.Version == "" {
+		reasons = append(reasons, fmt.Sprintf("note: host driver %s branch %s is not described in catalog file", hostVersion, hostBranch))
+	}
+	return reasons, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckMigration(t *testing.T) {
+	catalog := &VGPUDriverCatalog{
+		Branch: []BranchDescriptor{
+			{Name: "R550", Type: "host"},
+			{Name: "R570", Type: "host"},
+			{Name: "r550", Type: "guest"},
+		},
+		Driver: []DriverDescriptor{
+			{Version: "550.54.16", Branch: "R550", Type: "host"},
+			{Version: "570.124.03", Branch: "R570", Type: "host"},
+			guestDriver("550.90.07", "r550", "2024-06-04"),
+			guestDriver("550.54.15", "r550", "2024-02-22"),
+		},
+	}
+	catalog.Branch[2].Deny.Branch = []string{"R570"}
+	catalog.Driver[2].Allow.GPU = []GPUDescriptor{{DevID: "0x20b0", SSID: "0x134f"}}
+	catalog.Driver[3].Deny.GPU = []GPUDescriptor{{DevID: "0x20B0", SSID: "0x1533"}}
+
+	testCases := []struct {
+		description string
+		guest       string
+		hostVersion string
+		hostBranch  string
+		device      *PCIDeviceInfo
+		expected    []string
+	}{
+		{
+			description: "no device",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+		},
+		{
+			description: "allowed device ID and subsystem ID",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{deviceID: "0x20b0", subsystemID: "0x134f"},
+		},
+		{
+			description: "other device ID and subsystem ID not allowed",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{deviceID: "0x2330", subsystemID: "0x16c1"},
+			expected:    []string{"GPU 0x2330 is not in the allowed GPU list"},
+		},
+		{
+			description: "allowed device ID without subsystem ID",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{deviceID: "0x20b0"},
+		},
+		{
+			description: "allowed subsystem ID without device ID",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{subsystemID: "0x134f"},
+		},
+		{
+			description: "other device ID without subsystem ID not allowed",
+			guest:       "550.90.07",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{deviceID: "0x2330"},
+			expected:    []string{"GPU 0x2330 is not in the allowed GPU list"},
+		},
+		{
+			description: "denied device ID without subsystem ID",
+			guest:       "550.54.15",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{deviceID: "0x20b0"},
+			expected:    []string{"GPU 0x20b0 is in the denied GPU list"},
+		},
+		{
+			description: "denied subsystem ID without device ID",
+			guest:       "550.54.15",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			device:      &PCIDeviceInfo{subsystemID: "0x1533"},
+			expected:    []string{"GPU with subsystem ID 0x1533 is in the denied GPU list"},
+		},
+		{
+			description: "guest branch denies the destination branch",
+			guest:       "550.90.07",
+			hostVersion: "570.124.03",
+			hostBranch:  "R570",
+			device:      &PCIDeviceInfo{deviceID: "0x20b0"},
+			expected:    []string{"guest branch r550 denies host branch R570"},
+		},
+		{
+			description: "unknown guest driver",
+			guest:       "535.183.01",
+			hostVersion: "550.54.16",
+			hostBranch:  "R550",
+			expected:    []string{"guest driver 535.183.01 is not described in catalog file"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			reasons, err := CheckMigration(catalog, tc.guest, tc.hostVersion, tc.hostBranch, tc.device)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(reasons, "\n") != strings.Join(tc.expected, "\n") {
+				t.Errorf("expected reasons %q, got %q", tc.expected, reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultModuleParamsDirectory indicates default location of the user provided module parameter files
+	DefaultModuleParamsDirectory = "/drivers"
+	// DefaultModprobeConfigDirectory indicates default location of the generated modprobe config files
+	DefaultModprobeConfigDirectory = "/etc/modprobe.d"
+)
+
+// NvidiaModules lists the kernel modules accepting parameters, in load order
+var NvidiaModules = []string{"nvidia", "nvidia-uvm", "nvidia-modeset", "nvidia-peermem"}
+
+// builtinModuleParams are applied before the user provided parameters
+var builtinModuleParams = map[string][]ModuleParam{
+	// Starting from R580, we need to enable the CDMM (Coherent Driver Memory Management) module parameter.
+	// This prevents the GPU memory for coherent systems (GH200, GB200 etc) from being exposed as a NUMA node
+	// and thereby preventing over-reporting of a Kubernetes node's memory.
+	"nvidia": {{Name: "NVreg_CoherentGPUMemoryMode", Value: "driver", HasValue: true, Source: "built-in"}},
+}
+
+// ModuleParam is a single kernel module parameter
+type ModuleParam struct {
+	Name     string
+	Value    string
+	HasValue bool
+	// Source is where the parameter comes from, used in error messages
+	Source string
+}
+
+func (p ModuleParam) String() string {
+	if !p.HasValue {
+		return p.Name
+	}
+	return p.Name + "=" + p.Value
+}
+
+// key returns the parameter name as the kernel sees it, which treats '-' and '_' alike
+func (p ModuleParam) key() string {
+	return strings.ReplaceAll(p.Name, "-", "_")
+}
+
+type modParamsOptions struct {
+	inputDirectory  string
+	outputDirectory string
+	modinfo         cli.StringSlice
+	noDefaults      bool
+	dryRun          bool
+}
+
+func newModParamsCommand() *cli.Command {
+	opts := modParamsOptions{}
+
+	// Create the 'modparams render' subcommand
+	render := cli.Command{}
+	render.Name = "render"
+	render.Usage = "Merge built-in and user module parameters and write modprobe.d config files"
+	render.UsageText = "[-i | --input-directory] [-o | --output-directory] [--modinfo module=file] [--no-defaults] [--dry-run]"
+	render.Action = func(c *cli.Context) error {
+		return RenderModuleParams(c, &opts)
+	}
+	render.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "input-directory",
+			Aliases:     []string{"i"},
+			Usage:       "Directory containing the <module>.conf parameter files",
+			Value:       DefaultModuleParamsDirectory,
+			Destination: &opts.inputDirectory,
+		},
+		&cli.StringFlag{
+			Name:        "output-directory",
+			Aliases:     []string{"o"},
+			Usage:       "Directory the modprobe config files are written to",
+			Value:       DefaultModprobeConfigDirectory,
+			Destination: &opts.outputDirectory,
+			EnvVars:     []string{"MODPROBE_CONFIG_DIR"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "modinfo",
+			Usage:       "File with the 'modinfo -p' output of a module used to validate parameter names, as module=file",
+			Destination: &opts.modinfo,
+		},
+		&cli.BoolFlag{
+			Name:        "no-defaults",
+			Usage:       "Do not add the built-in module parameters",
+			Destination: &opts.noDefaults,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Print the modprobe config without writing it",
+			Destination: &opts.dryRun,
+		},
+	}
+
+	modParams := cli.Command{}
+	modParams.Name = "modparams"
+	modParams.Usage = "Manage NVIDIA kernel module parameters"
+	modParams.Subcommands = []*cli.Command{&render}
+	return &modParams
+}
+
+// RenderModuleParams writes an options line for every module with parameters into the modprobe config directory
+func RenderModuleParams(c *cli.Context, opts *modParamsOptions) error {
+	log.Infof("Starting 'modparams render' with %v", c.App.Name)
+
+	known := map[string]map[string]bool{}
+	for _, spec := range opts.modinfo.Value() {
+		module, file, ok := strings.Cut(spec, "=")
+		if !ok {
+			return fmt.Errorf("invalid --modinfo %q, expected module=file", spec)
+		}
+		names, err := loadModinfoParams(file)
+		if err != nil {
+			return err
+		}
+		known[module] = names
+	}
+
+	for _, module := range NvidiaModules {
+		var defaults []ModuleParam
+		if !opts.noDefaults {
+			defaults = builtinModuleParams[module]
+		}
+		user, err := loadModuleParamsFile(filepath.Join(opts.inputDirectory, module+".conf"))
+		if err != nil {
+			return err
+		}
+		if names, ok := known[module]; ok {
+			// built-in parameters only apply to the driver versions knowing them
+			defaults = supportedModuleParams(defaults, names)
+			if err := validateModuleParams(user, names); err != nil {
+				return fmt.Errorf("invalid parameters for module %s: %v", module, err)
+			}
+		}
+
+		params, err := MergeModuleParams(defaults, user)
+		if err != nil {
+			return fmt.Errorf("invalid parameters for module %s: %v", module, err)
+		}
+		confFile := filepath.Join(opts.outputDirectory, module+".conf")
+		if len(params) == 0 {
+			// a config left by a previous configuration would still be applied by modprobe
+			if !fileExists(confFile) {
+				continue
+			}
+			if opts.dryRun {
+				fmt.Printf("%s: removed\n", confFile)
+				continue
+			}
+			fmt.Printf("Removing stale %s module parameters in %s\n", module, confFile)
+			if err := os.Remove(confFile); err != nil {
+				return fmt.Errorf("unable to remove %s: %v", confFile, err)
+			}
+			continue
+		}
+
+		if len(user) > 0 {
+			fmt.Printf("Module parameters provided for %s: %s\n", module, joinModuleParams(user))
+		}
+		line := fmt.Sprintf("options %s %s\n", module, joinModuleParams(params))
+		if opts.dryRun {
+			fmt.Printf("%s: %s", confFile, line)
+			continue
+		}
+		fmt.Printf("Configuring %s module parameters in %s\n", module, confFile)
+		if err := writeFileAtomic(confFile, []byte(line), 0644); err != nil {
+			return err
+		}
+		log.Infof("wrote %s: %s", confFile, strings.TrimSpace(line))
+	}
+
+	log.Infof("Completed 'modparams render' with %v", c.App.Name)
+	return nil
+}
+
+// loadModuleParamsFile parses a parameter file, one or more whitespace separated
+// parameters per line with '#' comments. A missing file has no parameters.
+func loadModuleParamsFile(filename string) ([]ModuleParam, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read module parameters from %s: %v", filename, err)
+	}
+	defer f.Close()
+
+	var params []ModuleParam
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields, err := splitModuleParams(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+		}
+		for _, field := range fields {
+			param, err := ParseModuleParam(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+			}
+			param.Source = fmt.Sprintf("%s:%d", filename, lineNumber)
+			params = append(params, param)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read module parameters from %s: %v", filename, err)
+	}
+	return params, nil
+}
+
+// splitModuleParams splits a line on whitespace, keeping double quoted values together
+func splitModuleParams(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
+
+// ParseModuleParam parses a name or name=value parameter
+func ParseModuleParam(field string) (ModuleParam, error) {
+	name, value, hasValue := strings.Cut(field, "=")
+	if name == "" {
+		return ModuleParam{}, fmt.Errorf("parameter %q has no name", field)
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return ModuleParam{}, fmt.Errorf("parameter %q has an invalid name", field)
+		}
+	}
+	return ModuleParam{Name: name, Value: value, HasValue: hasValue}, nil
+}
+
+// MergeModuleParams deduplicates parameters, user values overriding the defaults.
+// Conflicting values within the user parameters are an error.
+func MergeModuleParams(defaults []ModuleParam, user []ModuleParam) ([]ModuleParam, error) {
+	var merged []ModuleParam
+	index := map[string]int{}
+	for _, param := range defaults {
+		if i, ok := index[param.key()]; ok {
+			merged[i] = param
+			continue
+		}
+		index[param.key()] = len(merged)
+		merged = append(merged, param)
+	}
+
+	fromUser := map[string]ModuleParam{}
+	for _, param := range user {
+		if previous, ok := fromUser[param.key()]; ok {
+			if previous.String() != param.String() {
+				return nil, fmt.Errorf("conflicting values %s (%s) and %s (%s)", previous, previous.Source, param, param.Source)
+			}
+			log.Warnf("Ignoring duplicate module parameter %s (%s)", param, param.Source)
+			continue
+		}
+		fromUser[param.key()] = param
+
+		if i, ok := index[param.key()]; ok {
+			if merged[i].String() != param.String() {
+				log.Infof("Module parameter %s overrides %s default %s", param, merged[i].Source, merged[i])
+			}
+			merged[i] = param
+			continue
+		}
+		index[param.key()] = len(merged)
+		merged = append(merged, param)
+	}
+	return merged, nil
+}
+
+// loadModinfoParams returns the parameter names listed in 'modinfo -p' output, e.g.
+//
+//	NVreg_EnableGpuFirmware:int
+func loadModinfoParams(filename string) (map[string]bool, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read modinfo output %s: %v", filename, err)
+	}
+	names := map[string]bool{}
+	for _, line := range strings.Split(string(data), "\n") {
+		name, _, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || name == "" {
+			continue
+		}
+		names[strings.ReplaceAll(name, "-", "_")] = true
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no parameters found in modinfo output %s", filename)
+	}
+	return names, nil
+}
+
+// supportedModuleParams drops the parameters the module does not know
+func supportedModuleParams(params []ModuleParam, known map[string]bool) []ModuleParam {
+	var supported []ModuleParam
+	for _, param := range params {
+		if !known[param.key()] {
+			log.Infof("Skipping %s parameter %s unknown to the module", param.Source, param)
+			continue
+		}
+		supported = append(supported, param)
+	}
+	return supported
+}
+
+func validateModuleParams(params []ModuleParam, known map[string]bool) error {
+	var unknown []string
+	for _, param := range params {
+		if !known[param.key()] {
+			unknown = append(unknown, fmt.Sprintf("%s (%s)", param.Name, param.Source))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func joinModuleParams(params []ModuleParam) string {
+	fields := make([]string, 0, len(params))
+	for _, param := range params {
+		fields = append(fields, param.String())
+	}
+	return strings.Join(fields, " ")
+}
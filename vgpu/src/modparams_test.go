@@ -0,0 +1,107 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func testContext() *cli.Context {
+	return cli.NewContext(&cli.App{Name: "vgpu-util"}, nil, nil)
+}
+
+func TestRenderModuleParams(t *testing.T) {
+	testCases := []struct {
+		description string
+		user        string
+		modinfo     string
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "built-in parameters",
+			expected:    "options nvidia NVreg_CoherentGPUMemoryMode=driver\n",
+		},
+		{
+			description: "user parameters after the built-in ones",
+			user:        "NVreg_EnableGpuFirmware=0 # no GSP\nNVreg_OpenRmEnableUnsupportedGpus=1\n",
+			expected:    "options nvidia NVreg_CoherentGPUMemoryMode=driver NVreg_EnableGpuFirmware=0 NVreg_OpenRmEnableUnsupportedGpus=1\n",
+		},
+		{
+			description: "built-in parameters unknown to a pre-R580 driver are skipped",
+			user:        "NVreg_EnableGpuFirmware=0\n",
+			modinfo:     "NVreg_EnableGpuFirmware:int\nNVreg_OpenRmEnableUnsupportedGpus:int\n",
+			expected:    "options nvidia NVreg_EnableGpuFirmware=0\n",
+		},
+		{
+			description: "user parameters unknown to the driver",
+			user:        "NVreg_EnableGpuFirmwar=0\n",
+			modinfo:     "NVreg_EnableGpuFirmware:int\n",
+			expectError: true,
+		},
+		{
+			description: "stale config removed",
+			modinfo:     "NVreg_EnableGpuFirmware:int\n",
+		},
+		{
+			description: "conflicting user parameters",
+			user:        "NVreg_EnableGpuFirmware=0\nNVreg_EnableGpuFirmware=1\n",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := &modParamsOptions{inputDirectory: filepath.Join(dir, "drivers"), outputDirectory: filepath.Join(dir, "modprobe.d")}
+			os.MkdirAll(opts.inputDirectory, 0755)
+			os.MkdirAll(opts.outputDirectory, 0755)
+			confFile := filepath.Join(opts.outputDirectory, "nvidia.conf")
+			os.WriteFile(confFile, []byte("options nvidia NVreg_RestrictProfilingToAdminUsers=0\n"), 0644)
+			if tc.user != "" {
+				os.WriteFile(filepath.Join(opts.inputDirectory, "nvidia.conf"), []byte(tc.user), 0644)
+			}
+			if tc.modinfo != "" {
+				modinfo := filepath.Join(dir, "nvidia.modinfo")
+				os.WriteFile(modinfo, []byte(tc.modinfo), 0644)
+				opts.modinfo.Set("nvidia=" + modinfo)
+			}
+
+			err := RenderModuleParams(testContext(), opts)
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			data, err := os.ReadFile(confFile)
+			if tc.expected == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("expected %s to be removed, got %q", confFile, data)
+				}
+				return
+			}
+			if string(data) != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,515 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// ModulesBundleManifestVersion is the version of the module bundle manifest
+	ModulesBundleManifestVersion = 1
+	// ModulesBundleManifest is the name of the manifest in the bundle directory
+	ModulesBundleManifest = "modules.manifest.json"
+	// NvidiaPeermemModule is the module giving RDMA devices access to the GPU memory
+	NvidiaPeermemModule = "nvidia-peermem"
+)
+
+// defaultBundleModules are the modules bundled when none is requested
+var defaultBundleModules = []string{"nvidia", "nvidia-uvm", "nvidia-modeset"}
+
+// moduleSuffixes are the extensions of the kernel module files, compressed or not
+var moduleSuffixes = []string{".ko", ".ko.xz", ".ko.zst", ".ko.gz"}
+
+// ModuleIndex is the dependency information depmod generated for a kernel
+type ModuleIndex struct {
+	// Paths maps a module name to its path relative to the kernel modules directory
+	Paths map[string]string
+	// Depends maps a module name to the names of the modules it depends on
+	Depends map[string][]string
+	// SoftPre and SoftPost map a module name to its soft dependencies
+	SoftPre  map[string][]string
+	SoftPost map[string][]string
+	// Builtin lists the modules built into the kernel
+	Builtin map[string]bool
+}
+
+// ModulesBundleFile is a file of the bundle, relative to the kernel modules directory
+type ModulesBundleFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	// Module is the module name, empty for the depmod files
+	Module string `json:"module,omitempty"`
+}
+
+// ModulesBundle is the manifest of a bundle directory, listing its files with the modules in load order
+type ModulesBundle struct {
+	Version       int                 `json:"version"`
+	KernelRelease string              `json:"kernelRelease"`
+	Requested     []string            `json:"requested"`
+	Files         []ModulesBundleFile `json:"files"`
+	// Builtin lists the dependencies built into the kernel, nothing to load for those
+	Builtin   []string  `json:"builtin,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type modulesOptions struct {
+	bundleDirectory string
+	modulesRoot     string
+	kernelRelease   string
+	modules         cli.StringSlice
+	peermem         bool
+	dryRun          bool
+}
+
+func newModulesCommand() *cli.Command {
+	opts := modulesOptions{}
+
+	bundleFlag := &cli.StringFlag{
+		Name:        "bundle",
+		Aliases:     []string{"b"},
+		Usage:       "Bundle directory",
+		Required:    true,
+		Destination: &opts.bundleDirectory,
+	}
+	modulesRootFlag := &cli.StringFlag{
+		Name:        "modules-root",
+		Usage:       "Directory of the kernels modules",
+		Value:       DefaultModulesRoot,
+		Destination: &opts.modulesRoot,
+	}
+	kernelFlag := &cli.StringFlag{
+		Name:        "kernel",
+		Usage:       "Kernel release, the running kernel if not set",
+		Destination: &opts.kernelRelease,
+		EnvVars:     []string{"KERNEL_VERSION"},
+	}
+
+	// Create the 'modules bundle' subcommand
+	bundle := cli.Command{}
+	bundle.Name = "bundle"
+	bundle.Usage = "Copy the modules and their dependencies into a bundle directory and write its manifest"
+	bundle.UsageText = "--bundle [--modules-root] [--kernel] [--module] [--peermem]"
+	bundle.Action = func(c *cli.Context) error {
+		return BundleModules(c, &opts)
+	}
+	bundle.Flags = []cli.Flag{
+		bundleFlag,
+		modulesRootFlag,
+		kernelFlag,
+		&cli.StringSliceFlag{
+			Name:        "module",
+			Usage:       "Module to bundle, nvidia, nvidia-uvm and nvidia-modeset if not set",
+			Destination: &opts.modules,
+		},
+		&cli.BoolFlag{
+			Name:        "peermem",
+			Usage:       "Bundle nvidia-peermem too",
+			Destination: &opts.peermem,
+			EnvVars:     []string{"GPU_DIRECT_RDMA_ENABLED"},
+		},
+	}
+
+	// Create the 'modules install' subcommand
+	install := cli.Command{}
+	install.Name = "install"
+	install.Usage = "Verify the bundle checksums and place its files in the kernel modules directory"
+	install.UsageText = "--bundle [--modules-root] [--kernel] [--dry-run]"
+	install.Action = func(c *cli.Context) error {
+		return InstallModules(c, &opts)
+	}
+	install.Flags = []cli.Flag{
+		bundleFlag,
+		modulesRootFlag,
+		kernelFlag,
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Verify the bundle and report the files to place without placing them",
+			Destination: &opts.dryRun,
+		},
+	}
+
+	modules := cli.Command{}
+	modules.Name = "modules"
+	modules.Usage = "Export and install the driver modules with their dependencies"
+	modules.Subcommands = []*cli.Command{&bundle, &install}
+	return &modules
+}
+
+// BundleModules copies the dependency closure of the requested modules into the bundle directory
+func BundleModules(c *cli.Context, opts *modulesOptions) error {
+	log.Infof("Starting 'modules bundle' with %v", c.App.Name)
+
+	kernelRelease, err := kernelReleaseOrRunning(opts.kernelRelease)
+	if err != nil {
+		return err
+	}
+	kernelDirectory := filepath.Join(opts.modulesRoot, kernelRelease)
+	index, err := LoadModuleIndex(kernelDirectory)
+	if err != nil {
+		return err
+	}
+
+	requested := opts.modules.Value()
+	if len(requested) == 0 {
+		requested = defaultBundleModules
+	}
+	if opts.peermem && !containsString(requested, NvidiaPeermemModule) {
+		requested = append(requested, NvidiaPeermemModule)
+	}
+	closure, builtin, err := index.Closure(requested)
+	if err != nil {
+		return err
+	}
+
+	manifest := ModulesBundle{
+		Version:       ModulesBundleManifestVersion,
+		KernelRelease: kernelRelease,
+		Requested:     requested,
+		Builtin:       builtin,
+		CreatedAt:     time.Now().UTC(),
+	}
+	for _, module := range closure {
+		file, err := copyBundleFile(kernelDirectory, opts.bundleDirectory, index.Paths[module])
+		if err != nil {
+			return err
+		}
+		file.Module = module
+		manifest.Files = append(manifest.Files, *file)
+	}
+
+	// Carry the depmod files, so modprobe resolves the bundled modules once installed
+	depmodFiles, err := filepath.Glob(filepath.Join(kernelDirectory, "modules.*"))
+	if err != nil {
+		return err
+	}
+	for _, depmodFile := range depmodFiles {
+		if filepath.Base(depmodFile) == ModulesBundleManifest {
+			continue
+		}
+		file, err := copyBundleFile(kernelDirectory, opts.bundleDirectory, filepath.Base(depmodFile))
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *file)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(opts.bundleDirectory, ModulesBundleManifest), append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Bundled modules %s for kernel %s\n", strings.Join(closure, " "), kernelRelease)
+
+	log.Infof("Completed 'modules bundle' with %v", c.App.Name)
+	return nil
+}
+
+// InstallModules verifies the bundle and places its files in the kernel modules directory
+func InstallModules(c *cli.Context, opts *modulesOptions) error {
+	log.Infof("Starting 'modules install' with %v", c.App.Name)
+
+	kernelRelease, err := kernelReleaseOrRunning(opts.kernelRelease)
+	if err != nil {
+		return err
+	}
+	manifest, err := LoadModulesBundle(opts.bundleDirectory)
+	if err != nil {
+		return err
+	}
+	if manifest.KernelRelease != kernelRelease {
+		return fmt.Errorf("bundle %s is built for kernel %s, not %s", opts.bundleDirectory, manifest.KernelRelease, kernelRelease)
+	}
+
+	// Verify every file before placing any, so a corrupt bundle leaves the kernel modules untouched
+	contents := make([][]byte, len(manifest.Files))
+	for i, file := range manifest.Files {
+		if err := validateBundlePath(file.Path); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(filepath.Join(opts.bundleDirectory, file.Path))
+		if err != nil {
+			return fmt.Errorf("unable to read bundle file: %v", err)
+		}
+		if checksum := sha256.Sum256(data); hex.EncodeToString(checksum[:]) != file.SHA256 {
+			return fmt.Errorf("checksum mismatch for bundle file %s", file.Path)
+		}
+		contents[i] = data
+	}
+
+	kernelDirectory := filepath.Join(opts.modulesRoot, kernelRelease)
+	placed := 0
+	for i, file := range manifest.Files {
+		destination := filepath.Join(kernelDirectory, file.Path)
+		if checksum, err := sha256File(destination); err == nil && checksum == file.SHA256 {
+			log.Debugf("%s is up to date", destination)
+			continue
+		}
+		if opts.dryRun {
+			fmt.Printf("Would place %s\n", destination)
+			continue
+		}
+		if err := writeFileAtomic(destination, contents[i], 0644); err != nil {
+			return err
+		}
+		placed++
+	}
+	if !opts.dryRun {
+		fmt.Printf("Installed bundle for kernel %s, placed %d of %d files\n", kernelRelease, placed, len(manifest.Files))
+	}
+
+	log.Infof("Completed 'modules install' with %v", c.App.Name)
+	return nil
+}
+
+// LoadModulesBundle reads the manifest of a bundle directory
+func LoadModulesBundle(bundleDirectory string) (*ModulesBundle, error) {
+	data, err := os.ReadFile(filepath.Join(bundleDirectory, ModulesBundleManifest))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read bundle manifest: %v", err)
+	}
+	manifest := &ModulesBundle{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %v", err)
+	}
+	if manifest.Version != ModulesBundleManifestVersion {
+		return nil, fmt.Errorf("unsupported bundle manifest version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// LoadModuleIndex reads modules.dep, modules.softdep and modules.builtin of a kernel modules directory.
+// modules.dep is required, the other two are optional.
+func LoadModuleIndex(kernelDirectory string) (*ModuleIndex, error) {
+	index := &ModuleIndex{
+		Paths:    map[string]string{},
+		Depends:  map[string][]string{},
+		SoftPre:  map[string][]string{},
+		SoftPost: map[string][]string{},
+		Builtin:  map[string]bool{},
+	}
+
+	// kernel/drivers/video/nvidia.ko.xz: kernel/drivers/gpu/drm/drm_kms_helper.ko.xz kernel/drivers/gpu/drm/drm.ko.xz
+	err := readModuleIndexFile(filepath.Join(kernelDirectory, "modules.dep"), func(line string) error {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid modules.dep line %q", line)
+		}
+		module := moduleNameFromPath(parts[0])
+		index.Paths[module] = strings.TrimSpace(parts[0])
+		for _, dep := range strings.Fields(parts[1]) {
+			depName := moduleNameFromPath(dep)
+			index.Depends[module] = append(index.Depends[module], depName)
+			if _, ok := index.Paths[depName]; !ok {
+				index.Paths[depName] = dep
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// softdep nvidia pre: ecdh_generic post: nvidia_uvm
+	err = readModuleIndexFile(filepath.Join(kernelDirectory, "modules.softdep"), func(line string) error {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "softdep" {
+			return nil
+		}
+		module := normalizeModuleName(fields[1])
+		target := index.SoftPre
+		for _, field := range fields[2:] {
+			switch field {
+			case "pre:":
+				target = index.SoftPre
+			case "post:":
+				target = index.SoftPost
+			default:
+				target[module] = append(target[module], normalizeModuleName(field))
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// kernel/drivers/i2c/i2c-core.ko
+	err = readModuleIndexFile(filepath.Join(kernelDirectory, "modules.builtin"), func(line string) error {
+		index.Builtin[moduleNameFromPath(line)] = true
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Closure returns the modules to load for the requested ones, dependencies and soft pre-dependencies
+// first, and the dependencies built into the kernel. A missing requested module or hard dependency
+// is an error, a missing soft dependency is only reported.
+func (index *ModuleIndex) Closure(requested []string) ([]string, []string, error) {
+	var order []string
+	builtin := map[string]bool{}
+	state := map[string]int{}
+	const visiting, visited = 1, 2
+	// stack holds the modules being visited, and hardEdges whether each was reached through modules.dep
+	var stack []string
+	var hardEdges []bool
+
+	var visit func(module string, hard bool) error
+	visit = func(module string, hard bool) error {
+		switch state[module] {
+		case visiting:
+			// softdeps may loop back, modules.dep may not
+			if !hard {
+				return nil
+			}
+			for i := len(stack) - 1; i >= 0 && stack[i] != module; i-- {
+				if !hardEdges[i] {
+					return nil
+				}
+			}
+			return fmt.Errorf("dependency cycle through module %s", module)
+		case visited:
+			return nil
+		}
+		if index.Builtin[module] {
+			builtin[module] = true
+			state[module] = visited
+			return nil
+		}
+		if _, ok := index.Paths[module]; !ok {
+			if hard {
+				return fmt.Errorf("module %s not found in modules.dep", module)
+			}
+			log.Warnf("ignoring soft dependency %s, not found in modules.dep", module)
+			state[module] = visited
+			return nil
+		}
+
+		state[module] = visiting
+		stack = append(stack, module)
+		hardEdges = append(hardEdges, hard)
+		for _, dep := range index.SoftPre[module] {
+			if err := visit(dep, false); err != nil {
+				return err
+			}
+		}
+		for _, dep := range index.Depends[module] {
+			if err := visit(dep, true); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		hardEdges = hardEdges[:len(hardEdges)-1]
+		state[module] = visited
+		order = append(order, module)
+		for _, dep := range index.SoftPost[module] {
+			if err := visit(dep, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, module := range requested {
+		if err := visit(normalizeModuleName(module), true); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var builtinModules []string
+	for module := range builtin {
+		builtinModules = append(builtinModules, module)
+	}
+	sort.Strings(builtinModules)
+	return order, builtinModules, nil
+}
+
+// copyBundleFile copies a file of the kernel modules directory into the bundle, keeping its relative path
+func copyBundleFile(kernelDirectory, bundleDirectory, relPath string) (*ModulesBundleFile, error) {
+	if err := validateBundlePath(relPath); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(kernelDirectory, relPath))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %v", relPath, err)
+	}
+	if err := writeFileAtomic(filepath.Join(bundleDirectory, relPath), data, 0644); err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(data)
+	log.Debugf("bundled %s", relPath)
+	return &ModulesBundleFile{Path: relPath, SHA256: hex.EncodeToString(checksum[:])}, nil
+}
+
+// validateBundlePath rejects the paths escaping the kernel modules directory
+func validateBundlePath(relPath string) error {
+	if filepath.IsAbs(relPath) || relPath != filepath.Clean(relPath) || strings.HasPrefix(relPath, "..") {
+		return fmt.Errorf("invalid module path %q", relPath)
+	}
+	return nil
+}
+
+func readModuleIndexFile(filename string, parse func(line string) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := parse(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// moduleNameFromPath returns the module name of a module file, e.g. nvidia_uvm for kernel/nvidia-uvm.ko.xz
+func moduleNameFromPath(path string) string {
+	name := filepath.Base(strings.TrimSpace(path))
+	for _, suffix := range moduleSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	return normalizeModuleName(name)
+}
+
+// normalizeModuleName replaces dashes with underscores, as the kernel does
+func normalizeModuleName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testKernelRelease = "5.14.0-427.13.1.el9_4.x86_64"
+
+const testModulesDep = `kernel/drivers/video/nvidia.ko.xz: kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz
+kernel/drivers/video/nvidia-uvm.ko.xz: kernel/drivers/video/nvidia.ko.xz kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz
+kernel/drivers/video/nvidia-modeset.ko.xz: kernel/drivers/video/nvidia.ko.xz kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz
+kernel/drivers/video/nvidia-drm.ko.zst: kernel/drivers/video/nvidia-modeset.ko.xz kernel/drivers/gpu/drm/drm_kms_helper.ko.gz kernel/drivers/gpu/drm/drm.ko kernel/drivers/video/nvidia.ko.xz
+kernel/drivers/video/nvidia-peermem.ko.xz: kernel/drivers/video/nvidia.ko.xz kernel/drivers/infiniband/core/ib_core.ko.xz
+kernel/drivers/gpu/drm/drm_kms_helper.ko.gz: kernel/drivers/gpu/drm/drm.ko
+kernel/drivers/gpu/drm/drm.ko:
+kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz:
+kernel/drivers/char/ipmi/ipmi_devintf.ko.xz: kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz
+kernel/drivers/infiniband/core/ib_core.ko.xz:
+kernel/drivers/misc/loop-a.ko: kernel/drivers/misc/loop-b.ko
+kernel/drivers/misc/loop-b.ko: kernel/drivers/misc/loop-a.ko
+`
+
+const testModulesSoftdep = `# Soft dependencies extracted from modules themselves.
+softdep nvidia pre: ecdh_generic post: nvidia-uvm
+softdep nvidia_uvm pre: nvidia
+softdep nvidia_modeset pre: nvidia-drm
+softdep ib_core pre: ib_uverbs post: rdma_ucm
+`
+
+const testModulesBuiltin = `kernel/crypto/ecdh_generic.ko
+kernel/drivers/i2c/i2c-core.ko
+`
+
+// writeModulesTree writes a synthetic kernel modules directory, each module file holding its path
+func writeModulesTree(t *testing.T, modulesRoot string) string {
+	kernelDirectory := filepath.Join(modulesRoot, testKernelRelease)
+	for _, line := range strings.Split(strings.TrimSpace(testModulesDep), "\n") {
+		path := strings.SplitN(line, ":", 2)[0]
+		if err := os.MkdirAll(filepath.Join(kernelDirectory, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(kernelDirectory, path), []byte(path), 0644)
+	}
+	os.WriteFile(filepath.Join(kernelDirectory, "modules.dep"), []byte(testModulesDep), 0644)
+	os.WriteFile(filepath.Join(kernelDirectory, "modules.softdep"), []byte(testModulesSoftdep), 0644)
+	os.WriteFile(filepath.Join(kernelDirectory, "modules.builtin"), []byte(testModulesBuiltin), 0644)
+	return kernelDirectory
+}
+
+func TestLoadModuleIndex(t *testing.T) {
+	index, err := LoadModuleIndex(writeModulesTree(t, t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if index.Paths["nvidia_drm"] != "kernel/drivers/video/nvidia-drm.ko.zst" || index.Paths["drm_kms_helper"] != "kernel/drivers/gpu/drm/drm_kms_helper.ko.gz" {
+		t.Errorf("unexpected paths %v", index.Paths)
+	}
+	if !reflect.DeepEqual(index.Depends["nvidia_uvm"], []string{"nvidia", "ipmi_msghandler"}) {
+		t.Errorf("unexpected nvidia_uvm dependencies %v", index.Depends["nvidia_uvm"])
+	}
+	if !reflect.DeepEqual(index.SoftPre["nvidia"], []string{"ecdh_generic"}) || !reflect.DeepEqual(index.SoftPost["nvidia"], []string{"nvidia_uvm"}) {
+		t.Errorf("unexpected nvidia soft dependencies %v %v", index.SoftPre["nvidia"], index.SoftPost["nvidia"])
+	}
+	if !index.Builtin["i2c_core"] || !index.Builtin["ecdh_generic"] {
+		t.Errorf("unexpected builtin modules %v", index.Builtin)
+	}
+
+	// modules.softdep and modules.builtin are optional, modules.dep is not
+	kernelDirectory := writeModulesTree(t, t.TempDir())
+	os.Remove(filepath.Join(kernelDirectory, "modules.softdep"))
+	os.Remove(filepath.Join(kernelDirectory, "modules.builtin"))
+	if _, err := LoadModuleIndex(kernelDirectory); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	os.Remove(filepath.Join(kernelDirectory, "modules.dep"))
+	if _, err := LoadModuleIndex(kernelDirectory); err == nil {
+		t.Errorf("expected an error without modules.dep")
+	}
+}
+
+func TestModuleIndexClosure(t *testing.T) {
+	index, err := LoadModuleIndex(writeModulesTree(t, t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		description string
+		requested   []string
+		order       []string
+		builtin     []string
+		expectError bool
+	}{
+		{
+			"soft post dependency after the module",
+			[]string{"nvidia"},
+			[]string{"ipmi_msghandler", "nvidia", "nvidia_uvm"},
+			[]string{"ecdh_generic"},
+			false,
+		},
+		{
+			// nvidia_modeset softly requires nvidia_drm, which requires nvidia_modeset
+			"soft cycle",
+			[]string{"i2c_core", "ipmi_devintf", "nvidia", "nvidia-uvm", "nvidia-modeset"},
+			[]string{"ipmi_msghandler", "ipmi_devintf", "nvidia", "nvidia_uvm", "drm", "drm_kms_helper", "nvidia_drm", "nvidia_modeset"},
+			[]string{"ecdh_generic", "i2c_core"},
+			false,
+		},
+		{
+			"missing soft dependencies",
+			[]string{"nvidia-peermem"},
+			[]string{"ipmi_msghandler", "nvidia", "nvidia_uvm", "ib_core", "nvidia_peermem"},
+			[]string{"ecdh_generic"},
+			false,
+		},
+		{"builtin requested module", []string{"i2c-core"}, nil, []string{"i2c_core"}, false},
+		{"missing requested module", []string{"nvidia", "nvidia-fs"}, nil, nil, true},
+		{"hard cycle", []string{"loop-a"}, nil, nil, true},
+	}
+	for _, tc := range testCases {
+		order, builtin, err := index.Closure(tc.requested)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(order, tc.order) || !reflect.DeepEqual(builtin, tc.builtin) {
+			t.Errorf("%s: expected %v %v, got %v %v", tc.description, tc.order, tc.builtin, order, builtin)
+		}
+	}
+}
+
+func TestBundleAndInstallModules(t *testing.T) {
+	dir := t.TempDir()
+	writeModulesTree(t, filepath.Join(dir, "dtk"))
+	bundleDirectory := filepath.Join(dir, "bundle")
+
+	opts := &modulesOptions{bundleDirectory: bundleDirectory, modulesRoot: filepath.Join(dir, "dtk"), kernelRelease: testKernelRelease}
+	if err := BundleModules(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manifest, err := LoadModulesBundle(bundleDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var modules []string
+	for _, file := range manifest.Files {
+		if file.Module != "" {
+			modules = append(modules, file.Module)
+		}
+	}
+	if expected := []string{"ipmi_msghandler", "nvidia", "nvidia_uvm", "drm", "drm_kms_helper", "nvidia_drm", "nvidia_modeset"}; !reflect.DeepEqual(modules, expected) {
+		t.Errorf("expected modules %v, got %v", expected, modules)
+	}
+	if len(manifest.Files) != len(modules)+3 {
+		t.Errorf("expected the depmod files to be bundled, got %+v", manifest.Files)
+	}
+
+	opts.modulesRoot = filepath.Join(dir, "host")
+	if err := InstallModules(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "host", testKernelRelease, "kernel/drivers/video/nvidia-drm.ko.zst"))
+	if err != nil || string(data) != "kernel/drivers/video/nvidia-drm.ko.zst" {
+		t.Errorf("module not installed: %q (%v)", data, err)
+	}
+
+	// a corrupt bundle is rejected before any file is placed
+	os.RemoveAll(filepath.Join(dir, "host"))
+	os.WriteFile(filepath.Join(bundleDirectory, "kernel/drivers/video/nvidia.ko.xz"), []byte("corrupt"), 0644)
+	if err := InstallModules(testContext(), opts); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "host")); !os.IsNotExist(err) {
+		t.Errorf("expected no file placed from a corrupt bundle")
+	}
+
+	// a bundle for another kernel is rejected
+	opts.kernelRelease = "5.14.0-503.11.1.el9_5.x86_64"
+	if err := InstallModules(testContext(), opts); err == nil {
+		t.Errorf("expected an error for another kernel")
+	}
+}
+
+func TestBundleModulesMissingHardDependency(t *testing.T) {
+	dir := t.TempDir()
+	kernelDirectory := writeModulesTree(t, dir)
+	os.Remove(filepath.Join(kernelDirectory, "kernel/drivers/char/ipmi/ipmi_msghandler.ko.xz"))
+
+	opts := &modulesOptions{bundleDirectory: filepath.Join(dir, "bundle"), modulesRoot: dir, kernelRelease: testKernelRelease}
+	if err := BundleModules(testContext(), opts); err == nil || !strings.Contains(err.Error(), "ipmi_msghandler") {
+		t.Errorf("expected an error for the missing dependency, got %v", err)
+	}
+	if _, err := LoadModulesBundle(opts.bundleDirectory); err == nil {
+		t.Errorf("expected no manifest written")
+	}
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// PciConfigLegacySize indicates size of the legacy PCI configuration space
+	PciConfigLegacySize = 256
+	// PciConfigExtendedSize indicates size of the PCIe extended configuration space
+	PciConfigExtendedSize = 4096
+	// PciExtCapabilityStart indicates offset of the first PCIe extended capability
+	PciExtCapabilityStart = 0x100
+	// PciExtCapabilityVendorSpecificID indicates PCIe vendor specific extended capability id
+	PciExtCapabilityVendorSpecificID = 0x000b
+	// PciExtCapabilityDesignatedVendorSpecificID indicates PCIe designated vendor specific extended capability id
+	PciExtCapabilityDesignatedVendorSpecificID = 0x0023
+	// PciVPDTagIdentifier indicates the VPD large resource tag of the identifier string
+	PciVPDTagIdentifier = 0x82
+	// PciVPDTagReadOnly indicates the VPD large resource tag of the read-only fields
+	PciVPDTagReadOnly = 0x90
+	// PciVPDTagReadWrite indicates the VPD large resource tag of the read-write fields
+	PciVPDTagReadWrite = 0x91
+	// PciVPDTagEnd indicates the VPD small resource end tag
+	PciVPDTagEnd = 0x78
+)
+
+// PCIDevice is a PCI device as listed in sysfs
+type PCIDevice struct {
+	Address         string
+	Path            string
+	Vendor          string
+	Device          string
+	SubsystemVendor string
+	SubsystemDevice string
+	Class           string
+	Driver          string
+}
+
+// ScanPCIDevices returns the devices under devicesRoot accepted by filter, sorted by address.
+// A nil filter accepts every device.
+func ScanPCIDevices(devicesRoot string, filter func(*PCIDevice) bool) ([]*PCIDevice, error) {
+	entries, err := os.ReadDir(devicesRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []*PCIDevice
+	for _, entry := range entries {
+		devicePath := filepath.Join(devicesRoot, entry.Name())
+		device := &PCIDevice{
+			Address:         entry.Name(),
+			Path:            devicePath,
+			Vendor:          readSysfsString(filepath.Join(devicePath, "vendor")),
+			Device:          readSysfsString(filepath.Join(devicePath, "device")),
+			SubsystemVendor: readSysfsString(filepath.Join(devicePath, "subsystem_vendor")),
+			SubsystemDevice: readSysfsString(filepath.Join(devicePath, "subsystem_device")),
+			Class:           readSysfsString(filepath.Join(devicePath, "class")),
+		}
+		if device.Vendor == "" {
+			return nil, fmt.Errorf("failed to read device vendor name for %s", entry.Name())
+		}
+		if driver, err := os.Readlink(filepath.Join(devicePath, "driver")); err == nil {
+			device.Driver = filepath.Base(driver)
+		}
+		if filter != nil && !filter(device) {
+			continue
+		}
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Address < devices[j].Address })
+	return devices, nil
+}
+
+// PCIVPDField is a keyword of the read-only or read-write VPD section
+type PCIVPDField struct {
+	Keyword  string
+	Value    string
+	ReadOnly bool
+}
+
+// PCIVPD is the vital product data of a PCI device
+type PCIVPD struct {
+	Identifier string
+	Fields     []PCIVPDField
+}
+
+// Field returns the value of the first field with the keyword
+func (v *PCIVPD) Field(keyword string) (string, bool) {
+	for _, field := range v.Fields {
+		if field.Keyword == keyword {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+// Contains returns true if the identifier or any field value contains s
+func (v *PCIVPD) Contains(s string) bool {
+	if strings.Contains(v.Identifier, s) {
+		return true
+	}
+	for _, field := range v.Fields {
+		if strings.Contains(field.Value, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePCIVPD parses the resource list of a VPD blob up to the end tag. The checksum
+// (RV) and reserved space (RW) keywords are not reported.
+func ParsePCIVPD(data []byte) (*PCIVPD, error) {
+	vpd := &PCIVPD{}
+	offset := 0
+	for offset < len(data) {
+		tag := data[offset]
+		if tag == PciVPDTagEnd {
+			return vpd, nil
+		}
+		if tag&0x80 == 0 {
+			// small resources other than the end tag carry no data we use
+			offset += 1 + int(tag&0x07)
+			continue
+		}
+		if offset+3 > len(data) {
+			return nil, fmt.Errorf("truncated VPD resource header at offset 0x%x", offset)
+		}
+		length := int(binary.LittleEndian.Uint16(data[offset+1:]))
+		start := offset + 3
+		if start+length > len(data) {
+			return nil, fmt.Errorf("VPD resource 0x%02x at offset 0x%x exceeds the data (%d bytes)", tag, offset, length)
+		}
+		resource := data[start : start+length]
+		switch tag {
+		case PciVPDTagIdentifier:
+			vpd.Identifier = strings.TrimRight(string(resource), "\x00 ")
+		case PciVPDTagReadOnly, PciVPDTagReadWrite:
+			fields, err := parsePCIVPDFields(resource, tag == PciVPDTagReadOnly)
+			if err != nil {
+				return nil, fmt.Errorf("invalid VPD resource 0x%02x at offset 0x%x: %v", tag, offset, err)
+			}
+			vpd.Fields = append(vpd.Fields, fields...)
+		}
+		offset = start + length
+	}
+	return nil, fmt.Errorf("VPD end tag not found")
+}
+
+// parsePCIVPDFields parses the keywords of a VPD-R or VPD-W resource, each made of a
+// two character keyword, a length byte and the data
+func parsePCIVPDFields(data []byte, readOnly bool) ([]PCIVPDField, error) {
+	var fields []PCIVPDField
+	offset := 0
+	for offset+3 <= len(data) {
+		keyword := string(data[offset : offset+2])
+		length := int(data[offset+2])
+		start := offset + 3
+		if start+length > len(data) {
+			return nil, fmt.Errorf("keyword %q exceeds the resource", keyword)
+		}
+		if keyword != "RV" && keyword != "RW" {
+			value := strings.TrimRight(string(data[start:start+length]), "\x00 ")
+			fields = append(fields, PCIVPDField{Keyword: keyword, Value: value, ReadOnly: readOnly})
+		}
+		offset = start + length
+	}
+	return fields, nil
+}
+
+// PCIConfigReader reads the configuration space of a PCI device.
+// It allows config space blobs to be injected instead of reading sysfs.
+type PCIConfigReader interface {
+	ReadConfig(device string) ([]byte, error)
+}
+
+// sysfsPCIConfigReader reads the configuration space from sysfs. Unprivileged
+// readers only get the first 64 bytes from the kernel.
+type sysfsPCIConfigReader struct {
+	devicesRoot string
+}
+
+// NewSysfsPCIConfigReader returns a PCIConfigReader reading <devicesRoot>/<device>/config
+func NewSysfsPCIConfigReader(devicesRoot string) PCIConfigReader {
+	return &sysfsPCIConfigReader{devicesRoot: devicesRoot}
+}
+
+func (r *sysfsPCIConfigReader) ReadConfig(device string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(r.devicesRoot, device, "config"))
+}
+
+// PCIConfigTruncatedError is returned when a capability lies beyond the part of
+// the configuration space that could be read, typically when running unprivileged
+type PCIConfigTruncatedError struct {
+	Device string
+	Size   int
+	Offset int
+}
+
+func (e *PCIConfigTruncatedError) Error() string {
+	return fmt.Sprintf("Entire PCI configuration is not read for device %s (%d bytes read, offset 0x%x needed). Please run with privileged mode to read complete PCI configuration data", e.Device, e.Size, e.Offset)
+}
+
+// PCICapability is a capability found in the legacy or extended capability list
+type PCICapability struct {
+	ID       uint16
+	Offset   int
+	Extended bool
+	// Version is the capability version of an extended capability
+	Version uint8
+	// Data holds the capability, header included
+	Data []byte
+}
+
+// PCIConfigSpace gives access to the capabilities of a PCI configuration space
+type PCIConfigSpace struct {
+	device string
+	data   []byte
+}
+
+// NewPCIConfigSpace wraps the configuration space read for a device
+func NewPCIConfigSpace(device string, data []byte) *PCIConfigSpace {
+	return &PCIConfigSpace{device: device, data: data}
+}
+
+// HasExtended returns true if the PCIe extended configuration space was read
+func (c *PCIConfigSpace) HasExtended() bool {
+	return len(c.data) >= PciConfigExtendedSize
+}
+
+func (c *PCIConfigSpace) truncated(offset int) error {
+	return &PCIConfigTruncatedError{Device: c.device, Size: len(c.data), Offset: offset}
+}
+
+// Capabilities walks the legacy capability list
+func (c *PCIConfigSpace) Capabilities() ([]PCICapability, error) {
+	if len(c.data) <= PciStatusByte {
+		return nil, c.truncated(PciStatusByte)
+	}
+	if c.data[PciStatusByte]&PciStatusCapabilityList == 0 {
+		return nil, nil
+	}
+	if len(c.data) <= PciCapabilityList {
+		return nil, c.truncated(PciCapabilityList)
+	}
+
+	var capabilities []PCICapability
+	var visited [PciConfigLegacySize]bool
+	pos := int(c.data[PciCapabilityList]) &^ 0x3
+	for pos != 0 {
+		if pos+PciCapabilityLength >= len(c.data) {
+			return capabilities, c.truncated(pos + PciCapabilityLength)
+		}
+		if visited[pos] {
+			// chain looped
+			break
+		}
+		visited[pos] = true
+
+		id := c.data[pos+PciCapabilityListID]
+		if id == 0xff {
+			// chain broken
+			break
+		}
+		next := int(c.data[pos+PciCapabilityListNext]) &^ 0x3
+		end := pos + 2
+		if id == PciCapabilityVendorSpecificID {
+			// vendor specific capabilities carry their length
+			end = pos + int(c.data[pos+PciCapabilityLength])
+		}
+		if end > len(c.data) {
+			return capabilities, c.truncated(end)
+		}
+		capabilities = append(capabilities, PCICapability{ID: uint16(id), Offset: pos, Data: c.data[pos:end]})
+		pos = next
+	}
+	return capabilities, nil
+}
+
+// ExtendedCapabilities walks the PCIe extended capability list. Nothing is returned
+// for conventional PCI devices or if the extended space could not be read.
+func (c *PCIConfigSpace) ExtendedCapabilities() ([]PCICapability, error) {
+	if !c.HasExtended() {
+		return nil, c.truncated(PciExtCapabilityStart)
+	}
+
+	var capabilities []PCICapability
+	visited := map[int]bool{}
+	pos := PciExtCapabilityStart
+	for pos != 0 {
+		if pos < PciExtCapabilityStart || pos+4 > len(c.data) || visited[pos] {
+			// chain broken or looped
+			break
+		}
+		visited[pos] = true
+
+		header := binary.LittleEndian.Uint32(c.data[pos:])
+		if header == 0 || header == 0xffffffff {
+			// no extended capabilities
+			break
+		}
+		id := uint16(header & 0xffff)
+		version := uint8((header >> 16) & 0xf)
+		next := int(header>>20) &^ 0x3
+
+		end := pos + 4
+		if (id == PciExtCapabilityVendorSpecificID || id == PciExtCapabilityDesignatedVendorSpecificID) && pos+8 <= len(c.data) {
+			// the vendor specific header holds the length of the whole capability
+			end = pos + int(binary.LittleEndian.Uint32(c.data[pos+4:])>>20)
+		}
+		if end > len(c.data) || end < pos+4 {
+			end = pos + 4
+		}
+		capabilities = append(capabilities, PCICapability{ID: id, Offset: pos, Extended: true, Version: version, Data: c.data[pos:end]})
+		pos = next
+	}
+	return capabilities, nil
+}
+
+// FindCapability returns the first legacy capability with the given id, or nil
+func (c *PCIConfigSpace) FindCapability(id uint16) (*PCICapability, error) {
+	capabilities, err := c.Capabilities()
+	for i := range capabilities {
+		if capabilities[i].ID == id {
+			return &capabilities[i], nil
+		}
+	}
+	return nil, err
+}
+
+// FindExtendedCapabilities returns all extended capabilities with the given id
+func (c *PCIConfigSpace) FindExtendedCapabilities(id uint16) ([]PCICapability, error) {
+	capabilities, err := c.ExtendedCapabilities()
+	var found []PCICapability
+	for _, capability := range capabilities {
+		if capability.ID == id {
+			found = append(found, capability)
+		}
+	}
+	return found, err
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// legacyConfig returns a configuration space with a power management capability at 0x40,
+// a vendor specific capability of 12 bytes at 0x50 and an MSI capability at 0x60
+func legacyConfig(size int) []byte {
+	data := make([]byte, size)
+	data[PciStatusByte] = PciStatusCapabilityList
+	data[PciCapabilityList] = 0x40
+	copy(data[0x40:], []byte{0x01, 0x50})
+	copy(data[0x50:], []byte{PciCapabilityVendorSpecificID, 0x60, 0x0c, 0xaa})
+	copy(data[0x60:], []byte{0x05, 0x00})
+	return data
+}
+
+// extendedCapabilityHeader encodes the id, version and next pointer of an extended capability
+func extendedCapabilityHeader(id uint16, version uint8, next int) uint32 {
+	return uint32(id) | uint32(version)<<16 | uint32(next)<<20
+}
+
+func TestFindCapability(t *testing.T) {
+	testCases := []struct {
+		description string
+		data        []byte
+		id          uint16
+		offset      int
+		length      int
+		truncated   bool
+	}{
+		{
+			description: "vendor specific capability with its length",
+			data:        legacyConfig(PciConfigLegacySize),
+			id:          PciCapabilityVendorSpecificID,
+			offset:      0x50,
+			length:      0x0c,
+		},
+		{
+			description: "last capability of the chain",
+			data:        legacyConfig(PciConfigLegacySize),
+			id:          0x05,
+			offset:      0x60,
+			length:      2,
+		},
+		{
+			description: "missing capability",
+			data:        legacyConfig(PciConfigLegacySize),
+			id:          0x10,
+		},
+		{
+			description: "no capability list",
+			data: func() []byte {
+				data := legacyConfig(PciConfigLegacySize)
+				data[PciStatusByte] = 0
+				return data
+			}(),
+			id: PciCapabilityVendorSpecificID,
+		},
+		{
+			description: "looped chain",
+			data: func() []byte {
+				data := legacyConfig(PciConfigLegacySize)
+				data[0x61] = 0x40
+				return data
+			}(),
+			id: 0x10,
+		},
+		{
+			description: "broken chain",
+			data: func() []byte {
+				data := legacyConfig(PciConfigLegacySize)
+				data[0x50] = 0xff
+				return data
+			}(),
+			id: 0x05,
+		},
+		{
+			description: "unprivileged read of the first 64 bytes",
+			data:        legacyConfig(PciConfigLegacySize)[:64],
+			id:          PciCapabilityVendorSpecificID,
+			truncated:   true,
+		},
+		{
+			description: "capability beyond the data read",
+			data:        legacyConfig(PciConfigLegacySize)[:0x58],
+			id:          PciCapabilityVendorSpecificID,
+			truncated:   true,
+		},
+		{
+			description: "capability found before the truncation",
+			data:        legacyConfig(PciConfigLegacySize)[:0x5c],
+			id:          PciCapabilityVendorSpecificID,
+			offset:      0x50,
+			length:      0x0c,
+			truncated:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			capability, err := NewPCIConfigSpace("0000:3b:00.0", tc.data).FindCapability(tc.id)
+			var truncated *PCIConfigTruncatedError
+			if tc.truncated && tc.offset == 0 {
+				if !errors.As(err, &truncated) {
+					t.Fatalf("expected a truncation error, got %v", err)
+				}
+				return
+			}
+			if err != nil && !(tc.truncated && errors.As(err, &truncated)) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.offset == 0 {
+				if capability != nil {
+					t.Fatalf("expected no capability, got one at 0x%x", capability.Offset)
+				}
+				return
+			}
+			if capability == nil {
+				t.Fatalf("capability 0x%x not found", tc.id)
+			}
+			if capability.Offset != tc.offset || len(capability.Data) != tc.length {
+				t.Errorf("expected capability at 0x%x of %d bytes, got 0x%x of %d bytes", tc.offset, tc.length, capability.Offset, len(capability.Data))
+			}
+		})
+	}
+}
+
+func TestFindExtendedCapabilities(t *testing.T) {
+	data := make([]byte, PciConfigExtendedSize)
+	// AER, then two vendor specific capabilities of 0x18 and 0x10 bytes, then a DVSEC
+	binary.LittleEndian.PutUint32(data[0x100:], extendedCapabilityHeader(0x0001, 2, 0x140))
+	binary.LittleEndian.PutUint32(data[0x140:], extendedCapabilityHeader(PciExtCapabilityVendorSpecificID, 1, 0x180))
+	binary.LittleEndian.PutUint32(data[0x144:], 0x018<<20|0x0001)
+	binary.LittleEndian.PutUint32(data[0x180:], extendedCapabilityHeader(PciExtCapabilityVendorSpecificID, 1, 0x1c0))
+	binary.LittleEndian.PutUint32(data[0x184:], 0x010<<20|0x0002)
+	binary.LittleEndian.PutUint32(data[0x1c0:], extendedCapabilityHeader(PciExtCapabilityDesignatedVendorSpecificID, 1, 0))
+	binary.LittleEndian.PutUint32(data[0x1c4:], 0x00c<<20|0x10de)
+
+	config := NewPCIConfigSpace("0000:3b:00.0", data)
+	found, err := config.FindExtendedCapabilities(PciExtCapabilityVendorSpecificID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 vendor specific capabilities, got %d", len(found))
+	}
+	for i, expected := range []struct{ offset, length int }{{0x140, 0x18}, {0x180, 0x10}} {
+		if found[i].Offset != expected.offset || len(found[i].Data) != expected.length || found[i].Version != 1 {
+			t.Errorf("capability %d: expected 0x%x of %d bytes, got 0x%x of %d bytes version %d",
+				i, expected.offset, expected.length, found[i].Offset, len(found[i].Data), found[i].Version)
+		}
+	}
+
+	all, err := config.ExtendedCapabilities()
+	if err != nil || len(all) != 4 {
+		t.Errorf("expected 4 extended capabilities, got %d (%v)", len(all), err)
+	}
+
+	// a loop back to the first capability ends the walk
+	binary.LittleEndian.PutUint32(data[0x1c0:], extendedCapabilityHeader(PciExtCapabilityDesignatedVendorSpecificID, 1, 0x100))
+	if all, err := config.ExtendedCapabilities(); err != nil || len(all) != 4 {
+		t.Errorf("expected 4 extended capabilities in a looped chain, got %d (%v)", len(all), err)
+	}
+
+	// a vendor specific length beyond the data only keeps the header
+	binary.LittleEndian.PutUint32(data[0x184:], 0xfff<<20|0x0002)
+	found, _ = config.FindExtendedCapabilities(PciExtCapabilityVendorSpecificID)
+	if len(found) != 2 || len(found[1].Data) != 4 {
+		t.Errorf("expected the header only for an oversized capability, got %v", found)
+	}
+
+	var truncated *PCIConfigTruncatedError
+	if _, err := NewPCIConfigSpace("0000:3b:00.0", data[:PciConfigLegacySize]).ExtendedCapabilities(); !errors.As(err, &truncated) {
+		t.Errorf("expected a truncation error for the legacy space only, got %v", err)
+	}
+}
+
+// vpdResource encodes a large VPD resource
+func vpdResource(tag byte, data []byte) []byte {
+	resource := []byte{tag, byte(len(data)), byte(len(data) >> 8)}
+	return append(resource, data...)
+}
+
+// vpdField encodes a VPD keyword
+func vpdField(keyword string, value string) []byte {
+	return append([]byte{keyword[0], keyword[1], byte(len(value))}, value...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var data []byte
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	return data
+}
+
+func TestParsePCIVPD(t *testing.T) {
+	readOnly := concat(vpdField("PN", "900-9X81Q-00CN-ST0"), vpdField("SN", "MT2412X00001"), vpdField("V0", "SW_MNG\x00\x00"), vpdField("RV", "\x5a"))
+	readWrite := concat(vpdField("V1", "spare "), vpdField("RW", "\x00\x00\x00\x00"))
+	valid := concat(
+		vpdResource(PciVPDTagIdentifier, []byte("NVIDIA ConnectX-8 SuperNIC ")),
+		vpdResource(PciVPDTagReadOnly, readOnly),
+		vpdResource(PciVPDTagReadWrite, readWrite),
+		[]byte{PciVPDTagEnd},
+		// the unused VPD space following the end tag
+		make([]byte, 16),
+	)
+
+	vpd, err := ParsePCIVPD(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vpd.Identifier != "NVIDIA ConnectX-8 SuperNIC" {
+		t.Errorf("unexpected identifier %q", vpd.Identifier)
+	}
+	expected := []PCIVPDField{
+		{Keyword: "PN", Value: "900-9X81Q-00CN-ST0", ReadOnly: true},
+		{Keyword: "SN", Value: "MT2412X00001", ReadOnly: true},
+		{Keyword: "V0", Value: "SW_MNG", ReadOnly: true},
+		{Keyword: "V1", Value: "spare"},
+	}
+	if len(vpd.Fields) != len(expected) {
+		t.Fatalf("expected fields %v, got %v", expected, vpd.Fields)
+	}
+	for i := range expected {
+		if vpd.Fields[i] != expected[i] {
+			t.Errorf("field %d: expected %v, got %v", i, expected[i], vpd.Fields[i])
+		}
+	}
+	if value, ok := vpd.Field("SN"); !ok || value != "MT2412X00001" {
+		t.Errorf("unexpected SN %q", value)
+	}
+	if _, ok := vpd.Field("RV"); ok {
+		t.Errorf("the checksum keyword is reported")
+	}
+	if !vpd.Contains(NVLink5ManagementVPDMarker) || !vpd.Contains("ConnectX-8") || vpd.Contains("BlueField") {
+		t.Errorf("unexpected Contains results")
+	}
+
+	// a small resource before the identifier is skipped
+	if vpd, err := ParsePCIVPD(concat([]byte{0x22, 0x01, 0x02}, valid)); err != nil || vpd.Identifier == "" {
+		t.Errorf("small resource not skipped: %v", err)
+	}
+
+	for _, tc := range []struct {
+		description string
+		data        []byte
+	}{
+		{"empty", nil},
+		{"no end tag", valid[:len(valid)-17]},
+		{"truncated resource header", []byte{PciVPDTagIdentifier, 0x10}},
+		{"resource beyond the data", valid[:10]},
+		{"keyword beyond the resource", concat(vpdResource(PciVPDTagReadOnly, concat(vpdField("PN", "x"), []byte{'S', 'N', 0x20, 'M'})), []byte{PciVPDTagEnd})},
+	} {
+		if _, err := ParsePCIVPD(tc.data); err == nil {
+			t.Errorf("%s: expected an error", tc.description)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// PrecompiledManifestVersion is the version of the precompiled package manifest
+	PrecompiledManifestVersion = 1
+	// PrecompiledManifestSuffix is appended to the package name to name its manifest
+	PrecompiledManifestSuffix = ".manifest.json"
+	// PrecompiledPackagePrefix is the prefix of the packages built by mkprecompiled
+	PrecompiledPackagePrefix = "nvidia-modules-"
+	// DefaultModulesRoot indicates default location of the installed kernels modules
+	DefaultModulesRoot = "/lib/modules"
+)
+
+// PrecompiledManifest records what a precompiled package was built for
+type PrecompiledManifest struct {
+	Version       int    `json:"version"`
+	Package       string `json:"package"`
+	SHA256        string `json:"sha256"`
+	KernelRelease string `json:"kernelRelease"`
+	DriverVersion string `json:"driverVersion"`
+	KernelType    string `json:"kernelType"`
+	Signed        bool   `json:"signed"`
+	// SigningKey is the sha256 of the public key certificate the modules were signed with
+	SigningKey string    `json:"signingKey,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type precompiledOptions struct {
+	directory     string
+	packageFile   string
+	kernelRelease string
+	driverVersion string
+	kernelType    string
+	signingKey    string
+	modulesRoot   string
+	keepKernels   cli.StringSlice
+	signed        bool
+	dryRun        bool
+	force         bool
+}
+
+func newPrecompiledCommand() *cli.Command {
+	opts := precompiledOptions{}
+
+	directoryFlag := &cli.StringFlag{
+		Name:        "directory",
+		Aliases:     []string{"d"},
+		Usage:       "Directory of the precompiled packages, /usr/src/nvidia-<driver-version>/<kernel-type>/precompiled if not set",
+		Destination: &opts.directory,
+	}
+	kernelFlag := &cli.StringFlag{
+		Name:        "kernel",
+		Usage:       "Kernel release, the running kernel if not set",
+		Destination: &opts.kernelRelease,
+		EnvVars:     []string{"KERNEL_VERSION"},
+	}
+	driverVersionFlag := &cli.StringFlag{
+		Name:        "driver-version",
+		Destination: &opts.driverVersion,
+		EnvVars:     []string{"DRIVER_VERSION"},
+	}
+	kernelTypeFlag := &cli.StringFlag{
+		Name:        "type",
+		Usage:       "Kernel module type, kernel or kernel-open",
+		Destination: &opts.kernelType,
+		EnvVars:     []string{"KERNEL_TYPE"},
+	}
+
+	// Create the 'precompiled index' subcommand
+	index := cli.Command{}
+	index.Name = "index"
+	index.Usage = "Write the manifest of a package built by mkprecompiled"
+	index.UsageText = "--package [--kernel] [--driver-version] [--type] [--signing-key]"
+	index.Action = func(c *cli.Context) error {
+		return IndexPrecompiled(c, &opts)
+	}
+	index.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "package",
+			Usage:       "Precompiled package",
+			Required:    true,
+			Destination: &opts.packageFile,
+		},
+		kernelFlag,
+		driverVersionFlag,
+		kernelTypeFlag,
+		&cli.StringFlag{
+			Name:        "signing-key",
+			Usage:       "Public key certificate the modules were signed with, if signed",
+			Destination: &opts.signingKey,
+		},
+	}
+
+	// Create the 'precompiled find' subcommand
+	find := cli.Command{}
+	find.Name = "find"
+	find.Usage = "Print the package built for the kernel, driver version and module type, exit with 1 if none"
+	find.UsageText = "[-d | --directory] [--kernel] [--driver-version] [--type] [--signed]"
+	find.Action = func(c *cli.Context) error {
+		return FindPrecompiled(c, &opts)
+	}
+	find.Flags = []cli.Flag{
+		directoryFlag,
+		kernelFlag,
+		driverVersionFlag,
+		kernelTypeFlag,
+		&cli.BoolFlag{
+			Name:        "signed",
+			Usage:       "Only consider packages with signed modules",
+			Destination: &opts.signed,
+		},
+	}
+
+	// Create the 'precompiled gc' subcommand
+	gc := cli.Command{}
+	gc.Name = "gc"
+	gc.Usage = "Remove the packages built for kernels no longer installed"
+	gc.UsageText = "[-d | --directory] [--modules-root] [--keep-kernel] [--dry-run] [--force]"
+	gc.Action = func(c *cli.Context) error {
+		return CollectPrecompiled(c, &opts)
+	}
+	gc.Flags = []cli.Flag{
+		directoryFlag,
+		driverVersionFlag,
+		kernelTypeFlag,
+		&cli.StringFlag{
+			Name:        "modules-root",
+			Usage:       "Directory listing the installed kernels",
+			Value:       DefaultModulesRoot,
+			Destination: &opts.modulesRoot,
+		},
+		&cli.StringSliceFlag{
+			Name:        "keep-kernel",
+			Usage:       "Kernel release to keep packages for, in addition to the installed kernels",
+			Destination: &opts.keepKernels,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Report the packages to remove without removing them",
+			Destination: &opts.dryRun,
+		},
+		&cli.BoolFlag{
+			Name:        "force",
+			Usage:       "Remove the packages even if no installed kernel is found, or if every package is to be removed",
+			Destination: &opts.force,
+		},
+	}
+
+	precompiled := cli.Command{}
+	precompiled.Name = "precompiled"
+	precompiled.Usage = "Manage the precompiled driver packages"
+	precompiled.Subcommands = []*cli.Command{&index, &find, &gc}
+	return &precompiled
+}
+
+// IndexPrecompiled writes the manifest beside a package
+func IndexPrecompiled(c *cli.Context, opts *precompiledOptions) error {
+	log.Infof("Starting 'precompiled index' with %v", c.App.Name)
+
+	kernelRelease, err := kernelReleaseOrRunning(opts.kernelRelease)
+	if err != nil {
+		return err
+	}
+	checksum, err := sha256File(opts.packageFile)
+	if err != nil {
+		return fmt.Errorf("unable to read package %s: %v", opts.packageFile, err)
+	}
+	manifest := PrecompiledManifest{
+		Version:       PrecompiledManifestVersion,
+		Package:       filepath.Base(opts.packageFile),
+		SHA256:        checksum,
+		KernelRelease: kernelRelease,
+		DriverVersion: opts.driverVersion,
+		KernelType:    opts.kernelType,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if opts.signingKey != "" {
+		if manifest.SigningKey, err = sha256File(opts.signingKey); err != nil {
+			return fmt.Errorf("unable to read signing key certificate %s: %v", opts.signingKey, err)
+		}
+		manifest.Signed = true
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifestFile := opts.packageFile + PrecompiledManifestSuffix
+	if err := writeFileAtomic(manifestFile, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Indexed NVIDIA driver package %s for kernel %s\n", manifest.Package, manifest.KernelRelease)
+
+	log.Infof("Completed 'precompiled index' with %v", c.App.Name)
+	return nil
+}
+
+// FindPrecompiled prints the path of the most recent package matching the kernel, driver version and module type
+func FindPrecompiled(c *cli.Context, opts *precompiledOptions) error {
+	log.Infof("Starting 'precompiled find' with %v", c.App.Name)
+
+	kernelRelease, err := kernelReleaseOrRunning(opts.kernelRelease)
+	if err != nil {
+		return err
+	}
+	directory, err := precompiledDirectory(opts)
+	if err != nil {
+		return err
+	}
+	manifests, unmanaged, err := LoadPrecompiledManifests(directory)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range unmanaged {
+		log.Warnf("ignoring package %s without manifest", pkg)
+	}
+
+	var candidates []*PrecompiledManifest
+	for _, manifest := range manifests {
+		switch {
+		case manifest.KernelRelease != kernelRelease:
+		case opts.driverVersion != "" && manifest.DriverVersion != opts.driverVersion:
+		case opts.kernelType != "" && manifest.KernelType != opts.kernelType:
+		case opts.signed && !manifest.Signed:
+		default:
+			candidates = append(candidates, manifest)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].CreatedAt.After(candidates[j].CreatedAt) })
+	for _, manifest := range candidates {
+		packageFile := filepath.Join(directory, manifest.Package)
+		checksum, err := sha256File(packageFile)
+		if err != nil || checksum != manifest.SHA256 {
+			log.Warnf("ignoring package %s not matching its manifest", packageFile)
+			continue
+		}
+		fmt.Println(packageFile)
+		log.Infof("Completed 'precompiled find' with %v, found %s", c.App.Name, manifest.Package)
+		return nil
+	}
+
+	log.Infof("no package found in %s for kernel %s", directory, kernelRelease)
+	return cli.Exit("", 1)
+}
+
+// CollectPrecompiled removes the packages built for kernels not installed anymore. Packages
+// without manifest are only removed if no installed kernel has the upstream version in their name.
+func CollectPrecompiled(c *cli.Context, opts *precompiledOptions) error {
+	log.Infof("Starting 'precompiled gc' with %v", c.App.Name)
+
+	directory, err := precompiledDirectory(opts)
+	if err != nil {
+		return err
+	}
+	// a missing or empty modules root most likely means the host /lib/modules is not
+	// mounted, removing the packages of every kernel would then be wrong
+	installed := map[string]bool{}
+	entries, err := os.ReadDir(opts.modulesRoot)
+	if err != nil {
+		return fmt.Errorf("unable to list the installed kernels: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			installed[entry.Name()] = true
+		}
+	}
+	if len(installed) == 0 && !opts.force {
+		return fmt.Errorf("no installed kernel found in %s, use --force to remove the packages anyway", opts.modulesRoot)
+	}
+	for _, kernel := range opts.keepKernels.Value() {
+		installed[kernel] = true
+	}
+	installedUpstream := map[string]bool{}
+	for kernel := range installed {
+		installedUpstream[strings.SplitN(kernel, "-", 2)[0]] = true
+	}
+
+	manifests, unmanaged, err := LoadPrecompiledManifests(directory)
+	if err != nil {
+		return err
+	}
+	var remove []string
+	for _, manifest := range manifests {
+		if !installed[manifest.KernelRelease] {
+			remove = append(remove, manifest.Package, manifest.Package+PrecompiledManifestSuffix)
+		}
+	}
+	for _, pkg := range unmanaged {
+		upstream := strings.SplitN(strings.TrimPrefix(pkg, PrecompiledPackagePrefix), "-", 2)[0]
+		if !installedUpstream[upstream] {
+			remove = append(remove, pkg)
+		}
+	}
+
+	if len(remove) > 0 && len(remove) == 2*len(manifests)+len(unmanaged) && !opts.force {
+		return fmt.Errorf("every package in %s is to be removed, use --force to remove them anyway", directory)
+	}
+
+	for _, name := range remove {
+		fmt.Printf("Removing %s\n", filepath.Join(directory, name))
+		if opts.dryRun {
+			continue
+		}
+		if err := os.Remove(filepath.Join(directory, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	log.Infof("Completed 'precompiled gc' with %v, removed %d files", c.App.Name, len(remove))
+	return nil
+}
+
+// LoadPrecompiledManifests returns the manifests found in the directory, and the packages without manifest
+func LoadPrecompiledManifests(directory string) ([]*PrecompiledManifest, []string, error) {
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var manifests []*PrecompiledManifest
+	indexed := map[string]bool{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), PrecompiledManifestSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		manifest := &PrecompiledManifest{}
+		if err := json.Unmarshal(data, manifest); err != nil || manifest.Version != PrecompiledManifestVersion {
+			log.Warnf("ignoring invalid manifest %s", entry.Name())
+			continue
+		}
+		// the package is removed by 'precompiled gc', it must not name a file out of the directory
+		if !validPackageName(manifest.Package) {
+			log.Warnf("ignoring manifest %s with invalid package name %q", entry.Name(), manifest.Package)
+			continue
+		}
+		manifests = append(manifests, manifest)
+		indexed[manifest.Package] = true
+	}
+
+	var unmanaged []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, PrecompiledPackagePrefix) && !strings.HasSuffix(name, PrecompiledManifestSuffix) && !indexed[name] {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+	return manifests, unmanaged, nil
+}
+
+// validPackageName returns true if the package names a file of the precompiled directory
+func validPackageName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsRune(name, os.PathSeparator)
+}
+
+func precompiledDirectory(opts *precompiledOptions) (string, error) {
+	if opts.directory != "" {
+		return opts.directory, nil
+	}
+	if opts.driverVersion == "" || opts.kernelType == "" {
+		return "", fmt.Errorf("--directory or both --driver-version and --type are required")
+	}
+	return filepath.Join("/usr/src", "nvidia-"+opts.driverVersion, opts.kernelType, "precompiled"), nil
+}
+
+func kernelReleaseOrRunning(release string) (string, error) {
+	if release != "" {
+		return release, nil
+	}
+	return runningKernelRelease()
+}
+
+func sha256File(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
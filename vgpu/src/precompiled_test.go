@@ -0,0 +1,117 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectPrecompiled(t *testing.T) {
+	writePackages := func(directory string) {
+		os.MkdirAll(directory, 0755)
+		for _, kernel := range []string{"5.15.0-105-generic", "5.15.0-107-generic"} {
+			pkg := PrecompiledPackagePrefix + kernel + ".tar.gz"
+			os.WriteFile(filepath.Join(directory, pkg), nil, 0644)
+			data, _ := json.Marshal(&PrecompiledManifest{Version: PrecompiledManifestVersion, Package: pkg, KernelRelease: kernel})
+			os.WriteFile(filepath.Join(directory, pkg+PrecompiledManifestSuffix), data, 0644)
+		}
+	}
+	remaining := func(directory string) int {
+		entries, _ := os.ReadDir(directory)
+		return len(entries)
+	}
+
+	testCases := []struct {
+		description string
+		kernels     []string
+		missingRoot bool
+		force       bool
+		expectError bool
+		remaining   int
+	}{
+		{"one kernel removed", []string{"5.15.0-107-generic"}, false, false, false, 2},
+		{"missing modules root", nil, true, false, true, 4},
+		{"missing modules root with force", nil, true, true, true, 4},
+		{"empty modules root", nil, false, false, true, 4},
+		{"empty modules root with force", nil, false, true, false, 0},
+		{"every package removed", []string{"6.8.0-45-generic"}, false, false, true, 4},
+		{"every package removed with force", []string{"6.8.0-45-generic"}, false, true, false, 0},
+	}
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		directory := filepath.Join(dir, "precompiled")
+		writePackages(directory)
+		modulesRoot := filepath.Join(dir, "modules")
+		if !tc.missingRoot {
+			os.MkdirAll(modulesRoot, 0755)
+		}
+		for _, kernel := range tc.kernels {
+			os.MkdirAll(filepath.Join(modulesRoot, kernel), 0755)
+		}
+
+		opts := &precompiledOptions{directory: directory, modulesRoot: modulesRoot, force: tc.force}
+		err := CollectPrecompiled(testContext(), opts)
+		if tc.expectError && err == nil {
+			t.Errorf("%s: expected an error", tc.description)
+		}
+		if !tc.expectError && err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+		}
+		if n := remaining(directory); n != tc.remaining {
+			t.Errorf("%s: expected %d files left, got %d", tc.description, tc.remaining, n)
+		}
+	}
+}
+
+func TestCollectPrecompiledWithInvalidPackageName(t *testing.T) {
+	dir := t.TempDir()
+	directory := filepath.Join(dir, "precompiled")
+	modulesRoot := filepath.Join(dir, "modules")
+	os.MkdirAll(filepath.Join(modulesRoot, "5.15.0-107-generic"), 0755)
+	os.MkdirAll(directory, 0755)
+	outside := filepath.Join(dir, "outside")
+	os.WriteFile(outside, nil, 0644)
+
+	for i, name := range []string{"../outside", outside, "..", ""} {
+		data, _ := json.Marshal(&PrecompiledManifest{Version: PrecompiledManifestVersion, Package: name, KernelRelease: "5.15.0-105-generic"})
+		os.WriteFile(filepath.Join(directory, fmt.Sprintf("invalid-%d%s", i, PrecompiledManifestSuffix)), data, 0644)
+	}
+	pkg := PrecompiledPackagePrefix + "5.15.0-107-generic.tar.gz"
+	os.WriteFile(filepath.Join(directory, pkg), nil, 0644)
+	data, _ := json.Marshal(&PrecompiledManifest{Version: PrecompiledManifestVersion, Package: pkg, KernelRelease: "5.15.0-107-generic"})
+	os.WriteFile(filepath.Join(directory, pkg+PrecompiledManifestSuffix), data, 0644)
+
+	manifests, _, err := LoadPrecompiledManifests(directory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(manifests) != 1 || manifests[0].Package != pkg {
+		t.Errorf("expected only the manifest of %s, got %+v", pkg, manifests)
+	}
+
+	opts := &precompiledOptions{directory: directory, modulesRoot: modulesRoot, force: true}
+	if err := CollectPrecompiled(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("expected %s out of the precompiled directory to be kept: %v", outside, err)
+	}
+	if _, err := os.Stat(filepath.Join(directory, pkg)); err != nil {
+		t.Errorf("expected %s to be kept: %v", pkg, err)
+	}
+}
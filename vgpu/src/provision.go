@@ -0,0 +1,645 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultSysfsRoot indicates default mount point of sysfs
+	DefaultSysfsRoot = "/sys"
+	// DefaultProvisionConfigFile indicates default location of the vGPU provisioning config
+	DefaultProvisionConfigFile = "/etc/nvidia/vgpu-config.yaml"
+	// PciClassDisplayController is the PCI base class of display controllers (VGA and 3D)
+	PciClassDisplayController = "0x03"
+)
+
+// VGPUProvisionConfig defines the contents of a vGPU provisioning config file
+type VGPUProvisionConfig struct {
+	Version string                `yaml:"version"`
+	Devices []VGPUDeviceProvision `yaml:"devices"`
+}
+
+// VGPUDeviceProvision maps a GPU selector to the desired vGPU layout on the selected GPUs
+type VGPUDeviceProvision struct {
+	Selector GPUSelector       `yaml:"selector"`
+	VGPUs    []VGPUTypeRequest `yaml:"vgpus"`
+}
+
+// GPUSelector selects physical GPUs; all non-empty fields must match
+type GPUSelector struct {
+	DevID string `yaml:"devid,omitempty"`
+	BDF   string `yaml:"bdf,omitempty"`
+	Index *int   `yaml:"index,omitempty"`
+}
+
+// VGPUTypeRequest is the desired number of vGPU instances of a given type
+type VGPUTypeRequest struct {
+	Type  string `yaml:"type"`
+	Count int    `yaml:"count"`
+}
+
+// physicalGPU represents an NVIDIA physical function found in sysfs
+type physicalGPU struct {
+	index    int
+	bdf      string
+	deviceID string
+	parents  []*vgpuParent
+}
+
+// vgpuParent is a PCI function able to host vGPU instances, either through
+// the mdev framework or the NVIDIA vendor specific VFIO framework
+type vgpuParent struct {
+	bdf       string
+	path      string
+	vendorFwk bool
+	// virtual functions host a single vGPU instance
+	singleVGPU bool
+	types      []vgpuType
+	instances  []vgpuInstance
+}
+
+// vgpuType is a vGPU type supported by a parent device
+type vgpuType struct {
+	id        string
+	name      string
+	available int
+}
+
+// vgpuInstance is an existing vGPU instance
+type vgpuInstance struct {
+	id     string
+	typeID string
+	parent string
+}
+
+// provisionAction is a single step required to converge to the desired layout
+type provisionAction struct {
+	create bool
+	parent *vgpuParent
+	typeID string
+	name   string
+	id     string
+}
+
+func (a provisionAction) String() string {
+	if a.create {
+		return fmt.Sprintf("create %s (%s) on %s", a.name, a.typeID, a.parent.bdf)
+	}
+	return fmt.Sprintf("remove %s (%s) on %s", a.id, a.typeID, a.parent.bdf)
+}
+
+type provisionOptions struct {
+	configFile string
+	sysfsRoot  string
+	dryRun     bool
+}
+
+func newProvisionCommand() *cli.Command {
+	opts := provisionOptions{}
+
+	sysfsFlag := &cli.StringFlag{
+		Name:        "sysfs-root",
+		Usage:       "Mount point of sysfs",
+		Value:       DefaultSysfsRoot,
+		Destination: &opts.sysfsRoot,
+		EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+	}
+
+	// Create the 'provision' subcommand
+	provision := cli.Command{}
+	provision.Name = "provision"
+	provision.Usage = "Create or remove vGPU instances to converge to a declared vGPU layout"
+	provision.UsageText = "[-f | --config] [--dry-run] [--sysfs-root]"
+	provision.Action = func(c *cli.Context) error {
+		return Provision(c, &opts)
+	}
+	provision.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Aliases:     []string{"f"},
+			Usage:       "vGPU provisioning config file",
+			Value:       DefaultProvisionConfigFile,
+			Destination: &opts.configFile,
+			EnvVars:     []string{"VGPU_PROVISION_CONFIG_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Print the actions required without applying them",
+			Destination: &opts.dryRun,
+		},
+		sysfsFlag,
+	}
+
+	// Create the 'provision status' subcommand
+	status := cli.Command{}
+	status.Name = "status"
+	status.Usage = "Report the vGPU instances currently present on each GPU"
+	status.Action = func(c *cli.Context) error {
+		return ProvisionStatus(c, &opts)
+	}
+	status.Flags = []cli.Flag{sysfsFlag}
+
+	provision.Subcommands = []*cli.Command{&status}
+	return &provision
+}
+
+// Provision creates and removes vGPU instances so the host matches the provisioning config
+func Provision(c *cli.Context, opts *provisionOptions) error {
+	log.Infof("Starting 'provision' with %v", c.App.Name)
+
+	config, err := LoadProvisionConfig(opts.configFile)
+	if err != nil {
+		return fmt.Errorf("unable to load provisioning config: %v", err)
+	}
+
+	gpus, err := GetPhysicalGPUs(opts.sysfsRoot)
+	if err != nil {
+		return fmt.Errorf("unable to discover GPUs on host: %v", err)
+	}
+
+	actions, err := PlanProvision(config, gpus)
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("vGPU layout is up to date")
+	}
+	for _, action := range actions {
+		if opts.dryRun {
+			fmt.Printf("would %s\n", action)
+			continue
+		}
+		if err := applyProvisionAction(action); err != nil {
+			return fmt.Errorf("unable to %s: %v", action, err)
+		}
+		log.Infof("%s", action)
+		fmt.Println(action)
+	}
+
+	if !opts.dryRun {
+		// re-read the state so the report reflects what the kernel actually did
+		gpus, err = GetPhysicalGPUs(opts.sysfsRoot)
+		if err != nil {
+			return fmt.Errorf("unable to discover GPUs on host: %v", err)
+		}
+		printProvisionStatus(gpus)
+	}
+
+	log.Infof("Completed 'provision' with %v", c.App.Name)
+	return nil
+}
+
+// ProvisionStatus prints the current vGPU layout of the host
+func ProvisionStatus(c *cli.Context, opts *provisionOptions) error {
+	gpus, err := GetPhysicalGPUs(opts.sysfsRoot)
+	if err != nil {
+		return fmt.Errorf("unable to discover GPUs on host: %v", err)
+	}
+	printProvisionStatus(gpus)
+	return nil
+}
+
+func printProvisionStatus(gpus []*physicalGPU) {
+	for _, gpu := range gpus {
+		counts := map[string]int{}
+		for _, parent := range gpu.parents {
+			for _, instance := range parent.instances {
+				counts[parent.typeName(instance.typeID)]++
+			}
+		}
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var layout []string
+		for _, name := range names {
+			layout = append(layout, fmt.Sprintf("%s=%d", name, counts[name]))
+		}
+		if len(layout) == 0 {
+			layout = append(layout, "none")
+		}
+		fmt.Printf("GPU %d %s %s: %s\n", gpu.index, gpu.bdf, gpu.deviceID, strings.Join(layout, ","))
+	}
+}
+
+// LoadProvisionConfig loads and validates the vGPU provisioning config file
+func LoadProvisionConfig(configFile string) (*VGPUProvisionConfig, error) {
+	log.Infof("Loading provisioning config file: %v", configFile)
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read provisioning config file %s: %v", configFile, err)
+	}
+
+	var config VGPUProvisionConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("Error un-marshalling provisioning config file: %v", err)
+	}
+
+	for i, device := range config.Devices {
+		if device.Selector.DevID == "" && device.Selector.BDF == "" && device.Selector.Index == nil {
+			return nil, fmt.Errorf("device entry %d has an empty selector", i)
+		}
+		for _, vgpu := range device.VGPUs {
+			if vgpu.Type == "" {
+				return nil, fmt.Errorf("device entry %d has a vGPU request without a type", i)
+			}
+			if vgpu.Count < 0 {
+				return nil, fmt.Errorf("device entry %d requests a negative count of %s", i, vgpu.Type)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// GetPhysicalGPUs returns all NVIDIA physical functions along with their vGPU parents and instances
+func GetPhysicalGPUs(sysfsRoot string) ([]*physicalGPU, error) {
+	devicesRoot := filepath.Join(sysfsRoot, "bus", "pci", "devices")
+	devices, err := os.ReadDir(devicesRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var gpus []*physicalGPU
+	for _, device := range devices {
+		devicePath := filepath.Join(devicesRoot, device.Name())
+		if readSysfsString(filepath.Join(devicePath, "vendor")) != NvidiaVendorID {
+			continue
+		}
+		if !strings.HasPrefix(readSysfsString(filepath.Join(devicePath, "class")), PciClassDisplayController) {
+			continue
+		}
+		// virtual functions are discovered through their physical function
+		if _, err := os.Stat(filepath.Join(devicePath, "physfn")); err == nil {
+			continue
+		}
+
+		gpu := &physicalGPU{
+			bdf:      device.Name(),
+			deviceID: readSysfsString(filepath.Join(devicePath, "device")),
+		}
+
+		// SR-IOV capable GPUs host vGPUs on their virtual functions
+		parentPaths, _ := filepath.Glob(filepath.Join(devicePath, "virtfn*"))
+		sort.Slice(parentPaths, func(i, j int) bool {
+			return virtfnIndex(parentPaths[i]) < virtfnIndex(parentPaths[j])
+		})
+		sriov := len(parentPaths) > 0
+		if !sriov {
+			parentPaths = []string{devicePath}
+		}
+		for _, parentPath := range parentPaths {
+			parent, err := readVGPUParent(parentPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read vGPU state of %s: %v", parentPath, err)
+			}
+			if parent != nil {
+				parent.singleVGPU = sriov
+				gpu.parents = append(gpu.parents, parent)
+			}
+		}
+		gpus = append(gpus, gpu)
+	}
+
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].bdf < gpus[j].bdf })
+	for i, gpu := range gpus {
+		gpu.index = i
+	}
+	return gpus, nil
+}
+
+func virtfnIndex(p string) int {
+	i, _ := strconv.Atoi(strings.TrimPrefix(filepath.Base(p), "virtfn"))
+	return i
+}
+
+// readVGPUParent reads supported types and existing instances of a vGPU parent device.
+// It returns nil if the device can't host vGPUs.
+func readVGPUParent(devicePath string) (*vgpuParent, error) {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return nil, err
+	}
+	parent := &vgpuParent{bdf: filepath.Base(resolved), path: resolved}
+
+	// NVIDIA vendor specific VFIO framework
+	vendorPath := filepath.Join(resolved, "nvidia")
+	if _, err := os.Stat(filepath.Join(vendorPath, "creatable_vgpu_types")); err == nil {
+		parent.vendorFwk = true
+		parent.types = parseCreatableVGPUTypes(readSysfsString(filepath.Join(vendorPath, "creatable_vgpu_types")))
+		// all supported types are only listed while no vGPU is created
+		if supported := readSysfsString(filepath.Join(vendorPath, "supported_vgpu_types")); supported != "" {
+			for _, t := range parseCreatableVGPUTypes(supported) {
+				if parent.findType(t.id) == nil {
+					parent.types = append(parent.types, vgpuType{id: t.id, name: t.name})
+				}
+			}
+		}
+		current := readSysfsString(filepath.Join(vendorPath, "current_vgpu_type"))
+		if current != "" && current != "0" {
+			parent.instances = append(parent.instances, vgpuInstance{id: parent.bdf, typeID: current, parent: parent.bdf})
+		}
+		return parent, nil
+	}
+
+	// mdev framework
+	typesPath := filepath.Join(resolved, "mdev_supported_types")
+	typeDirs, err := os.ReadDir(typesPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, typeDir := range typeDirs {
+		typePath := filepath.Join(typesPath, typeDir.Name())
+		available, _ := strconv.Atoi(readSysfsString(filepath.Join(typePath, "available_instances")))
+		parent.types = append(parent.types, vgpuType{
+			id:        typeDir.Name(),
+			name:      readSysfsString(filepath.Join(typePath, "name")),
+			available: available,
+		})
+		instances, _ := os.ReadDir(filepath.Join(typePath, "devices"))
+		for _, instance := range instances {
+			parent.instances = append(parent.instances, vgpuInstance{id: instance.Name(), typeID: typeDir.Name(), parent: parent.bdf})
+		}
+	}
+	return parent, nil
+}
+
+// parseCreatableVGPUTypes parses the vendor specific type list, e.g.
+//
+//	ID    : vGPU Name
+//	1155  : NVIDIA L4-1B
+func parseCreatableVGPUTypes(content string) []vgpuType {
+	var types []vgpuType
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		id := strings.TrimSpace(fields[0])
+		if _, err := strconv.Atoi(id); err != nil {
+			continue
+		}
+		types = append(types, vgpuType{id: id, name: strings.TrimSpace(fields[1]), available: 1})
+	}
+	return types
+}
+
+func (p *vgpuParent) findType(typeID string) *vgpuType {
+	for i := range p.types {
+		if p.types[i].id == typeID {
+			return &p.types[i]
+		}
+	}
+	return nil
+}
+
+// resolveType returns the type id matching a type requested by id or name
+func (p *vgpuParent) resolveType(requested string) string {
+	for _, t := range p.types {
+		if vgpuTypeMatches(t, requested) {
+			return t.id
+		}
+	}
+	return ""
+}
+
+func (p *vgpuParent) typeName(typeID string) string {
+	if t := p.findType(typeID); t != nil && t.name != "" {
+		return shortVGPUTypeName(t.name)
+	}
+	return typeID
+}
+
+func vgpuTypeMatches(t vgpuType, requested string) bool {
+	if strings.EqualFold(t.id, requested) {
+		return true
+	}
+	return t.name != "" && (strings.EqualFold(t.name, requested) || strings.EqualFold(shortVGPUTypeName(t.name), requested))
+}
+
+// shortVGPUTypeName strips the vendor prefix from a type name, e.g. "NVIDIA A10-4Q" becomes "A10-4Q"
+func shortVGPUTypeName(name string) string {
+	for _, prefix := range []string{"NVIDIA ", "GRID "} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+func (s GPUSelector) matches(gpu *physicalGPU) bool {
+	if s.DevID != "" && normalizeDeviceID(s.DevID) != normalizeDeviceID(gpu.deviceID) {
+		return false
+	}
+	if s.BDF != "" && !strings.EqualFold(normalizeBDF(s.BDF), gpu.bdf) {
+		return false
+	}
+	if s.Index != nil && *s.Index != gpu.index {
+		return false
+	}
+	return true
+}
+
+// normalizeDeviceID returns a PCI device ID in the sysfs format, e.g. 2236 and 0X2236 become 0x2236
+func normalizeDeviceID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if !strings.HasPrefix(id, "0x") {
+		id = "0x" + id
+	}
+	return id
+}
+
+// normalizeBDF adds the default PCI domain to a bus:device.function address
+func normalizeBDF(bdf string) string {
+	if strings.Count(bdf, ":") == 1 {
+		return "0000:" + bdf
+	}
+	return bdf
+}
+
+// PlanProvision computes the actions required to converge the GPUs to the desired layout.
+// GPUs not matched by any selector are left untouched.
+func PlanProvision(config *VGPUProvisionConfig, gpus []*physicalGPU) ([]provisionAction, error) {
+	var actions []provisionAction
+	for _, gpu := range gpus {
+		var desired []VGPUTypeRequest
+		matched := false
+		for _, device := range config.Devices {
+			if device.Selector.matches(gpu) {
+				// the first matching entry wins, so more specific selectors should be listed first
+				desired = device.VGPUs
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			log.Debugf("GPU %s is not selected by the provisioning config, skipping", gpu.bdf)
+			continue
+		}
+		gpuActions, err := planGPU(gpu, desired)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan vGPU layout for GPU %s: %v", gpu.bdf, err)
+		}
+		actions = append(actions, gpuActions...)
+	}
+	return actions, nil
+}
+
+func planGPU(gpu *physicalGPU, desired []VGPUTypeRequest) ([]provisionAction, error) {
+	if len(gpu.parents) == 0 && len(desired) > 0 {
+		return nil, fmt.Errorf("GPU does not expose any vGPU capable device, are VFs enabled?")
+	}
+
+	// resolve the requested types against the types supported by the parents
+	wanted := map[string]int{}
+	names := map[string]string{}
+	for _, request := range desired {
+		typeID := ""
+		for _, parent := range gpu.parents {
+			if typeID = parent.resolveType(request.Type); typeID != "" {
+				names[typeID] = parent.typeName(typeID)
+				break
+			}
+		}
+		if typeID == "" {
+			return nil, fmt.Errorf("vGPU type %s is not supported", request.Type)
+		}
+		wanted[typeID] += request.Count
+	}
+
+	var actions []provisionAction
+	// remove surplus instances first to free capacity for the new ones
+	remaining := map[string]int{}
+	for typeID, count := range wanted {
+		remaining[typeID] = count
+	}
+	busy := map[*vgpuParent]bool{}
+	for _, parent := range gpu.parents {
+		for _, instance := range parent.instances {
+			if remaining[instance.typeID] > 0 {
+				remaining[instance.typeID]--
+				busy[parent] = true
+				continue
+			}
+			actions = append(actions, provisionAction{parent: parent, typeID: instance.typeID, id: instance.id})
+		}
+	}
+
+	typeIDs := make([]string, 0, len(remaining))
+	for typeID := range remaining {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Strings(typeIDs)
+
+	for _, typeID := range typeIDs {
+		for i := 0; i < remaining[typeID]; i++ {
+			parent := findParentFor(gpu.parents, typeID, busy, actions)
+			if parent == nil {
+				return nil, fmt.Errorf("not enough capacity to create %d more %s instances", remaining[typeID]-i, names[typeID])
+			}
+			actions = append(actions, provisionAction{create: true, parent: parent, typeID: typeID, name: names[typeID]})
+			busy[parent] = true
+		}
+	}
+	return actions, nil
+}
+
+// findParentFor returns a parent with capacity left for typeID after the planned actions
+func findParentFor(parents []*vgpuParent, typeID string, busy map[*vgpuParent]bool, planned []provisionAction) *vgpuParent {
+	for _, parent := range parents {
+		t := parent.findType(typeID)
+		if t == nil {
+			continue
+		}
+		if parent.singleVGPU {
+			if !busy[parent] {
+				return parent
+			}
+			continue
+		}
+		available := t.available
+		for _, action := range planned {
+			if action.parent != parent {
+				continue
+			}
+			if action.create && action.typeID == typeID {
+				available--
+			}
+			if !action.create && action.typeID == typeID {
+				available++
+			}
+		}
+		if available > 0 {
+			return parent
+		}
+	}
+	return nil
+}
+
+func applyProvisionAction(action provisionAction) error {
+	parent := action.parent
+	if parent.vendorFwk {
+		value := action.typeID
+		if !action.create {
+			value = "0"
+		}
+		return os.WriteFile(filepath.Join(parent.path, "nvidia", "current_vgpu_type"), []byte(value), 0644)
+	}
+
+	if action.create {
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(parent.path, "mdev_supported_types", action.typeID, "create"), []byte(id), 0200)
+	}
+	return os.WriteFile(filepath.Join(parent.path, "mdev_supported_types", action.typeID, "devices", action.id, "remove"), []byte("1"), 0200)
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// readSysfsString returns the trimmed content of a sysfs attribute, or an empty string if it can't be read
+func readSysfsString(p string) string {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testSRIOVGPU returns a GPU hosting a single vGPU on each of its virtual functions, with
+// instances of the given types on the first ones
+func testSRIOVGPU(bdf string, vfs int, instances ...string) *physicalGPU {
+	gpu := &physicalGPU{bdf: bdf, deviceID: "0x2236"}
+	for i := 0; i < vfs; i++ {
+		parent := &vgpuParent{
+			bdf:        bdf[:len(bdf)-1] + string(rune('1'+i)),
+			vendorFwk:  true,
+			singleVGPU: true,
+			types:      []vgpuType{{id: "1155", name: "NVIDIA A10-4Q", available: 1}, {id: "1156", name: "NVIDIA A10-8Q", available: 1}},
+		}
+		if i < len(instances) {
+			parent.instances = []vgpuInstance{{id: parent.bdf, typeID: instances[i], parent: parent.bdf}}
+		}
+		gpu.parents = append(gpu.parents, parent)
+	}
+	return gpu
+}
+
+// testMdevGPU returns a GPU hosting its vGPUs through the mdev framework on its physical function
+func testMdevGPU(bdf string, available int, instances ...string) *physicalGPU {
+	parent := &vgpuParent{bdf: bdf, types: []vgpuType{{id: "nvidia-588", name: "GRID T4-4Q", available: available}}}
+	for _, id := range instances {
+		parent.instances = append(parent.instances, vgpuInstance{id: id, typeID: "nvidia-588", parent: bdf})
+	}
+	return &physicalGPU{bdf: bdf, deviceID: "0x1eb8", parents: []*vgpuParent{parent}}
+}
+
+func TestPlanProvision(t *testing.T) {
+	index := 1
+	testCases := []struct {
+		description string
+		devices     []VGPUDeviceProvision
+		gpus        []*physicalGPU
+		expected    []string
+		expectError string
+	}{
+		{
+			description: "placement on free virtual functions",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "2236"}, VGPUs: []VGPUTypeRequest{{Type: "A10-4Q", Count: 2}, {Type: "1156", Count: 1}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 4, "1155")},
+			expected:    []string{"create A10-4Q (1155) on 0000:41:00.2", "create A10-8Q (1156) on 0000:41:00.3"},
+		},
+		{
+			description: "placement on mdev capacity",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "0X1EB8"}, VGPUs: []VGPUTypeRequest{{Type: "grid t4-4q", Count: 2}}}},
+			gpus:        []*physicalGPU{testMdevGPU("0000:3b:00.0", 3)},
+			expected:    []string{"create T4-4Q (nvidia-588) on 0000:3b:00.0", "create T4-4Q (nvidia-588) on 0000:3b:00.0"},
+		},
+		{
+			description: "surplus instances removed first",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{BDF: "41:00.0"}, VGPUs: []VGPUTypeRequest{{Type: "A10-8Q", Count: 2}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 2, "1155", "1156")},
+			expected:    []string{"remove 0000:41:00.1 (1155) on 0000:41:00.1", "create A10-8Q (1156) on 0000:41:00.1"},
+		},
+		{
+			description: "already satisfied layout",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "0x2236"}, VGPUs: []VGPUTypeRequest{{Type: "A10-4Q", Count: 1}, {Type: "A10-8Q", Count: 1}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 2, "1156", "1155")},
+		},
+		{
+			description: "first matching selector wins and unselected GPUs are left untouched",
+			devices: []VGPUDeviceProvision{
+				{Selector: GPUSelector{Index: &index}, VGPUs: nil},
+				{Selector: GPUSelector{DevID: "1eb8"}, VGPUs: []VGPUTypeRequest{{Type: "T4-4Q", Count: 1}}},
+			},
+			gpus:     []*physicalGPU{testMdevGPU("0000:3b:00.0", 1), testMdevGPU("0000:5e:00.0", 0, "c0ffee"), testSRIOVGPU("0000:41:00.0", 1, "1155")},
+			expected: []string{"create T4-4Q (nvidia-588) on 0000:3b:00.0", "remove c0ffee (nvidia-588) on 0000:5e:00.0"},
+		},
+		{
+			description: "impossible layout",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "0x2236"}, VGPUs: []VGPUTypeRequest{{Type: "A10-4Q", Count: 3}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 2)},
+			expectError: "not enough capacity to create 1 more A10-4Q instances",
+		},
+		{
+			description: "unsupported type",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "0x2236"}, VGPUs: []VGPUTypeRequest{{Type: "A10-24Q", Count: 1}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 2)},
+			expectError: "vGPU type A10-24Q is not supported",
+		},
+		{
+			description: "virtual functions not enabled",
+			devices:     []VGPUDeviceProvision{{Selector: GPUSelector{DevID: "0x2236"}, VGPUs: []VGPUTypeRequest{{Type: "A10-4Q", Count: 1}}}},
+			gpus:        []*physicalGPU{testSRIOVGPU("0000:41:00.0", 0)},
+			expectError: "are VFs enabled?",
+		},
+	}
+	for _, tc := range testCases {
+		for i, gpu := range tc.gpus {
+			gpu.index = i
+		}
+		actions, err := PlanProvision(&VGPUProvisionConfig{Version: "v1", Devices: tc.devices}, tc.gpus)
+		if tc.expectError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+				t.Errorf("%s: expected error %q, got %v", tc.description, tc.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+			continue
+		}
+		var planned []string
+		for _, action := range actions {
+			planned = append(planned, action.String())
+		}
+		if strings.Join(planned, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("%s: unexpected actions:\n  %s", tc.description, strings.Join(planned, "\n  "))
+		}
+	}
+}
+
+func TestNormalizeDeviceID(t *testing.T) {
+	for _, id := range []string{"0x2236", "2236", "0X2236", " 0x2236\n"} {
+		if normalized := normalizeDeviceID(id); normalized != "0x2236" {
+			t.Errorf("%q: expected 0x2236, got %q", id, normalized)
+		}
+	}
+}
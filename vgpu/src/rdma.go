@@ -0,0 +1,277 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// MellanoxVendorID represents Mellanox PCI vendor ID
+	MellanoxVendorID = "0x15b3"
+	// DefaultMOFEDReadyFile indicates default location of the readiness flag of the MOFED driver container
+	DefaultMOFEDReadyFile = "/run/mellanox/drivers/.driver-ready"
+	// DefaultDriverReadyFile indicates default location of the readiness flag of the driver container, created by the validator
+	DefaultDriverReadyFile = "/run/nvidia/validations/.driver-ctr-ready"
+	// rdmaWaitInterval is the interval between two checks while waiting for nvidia-peermem to be loadable
+	rdmaWaitInterval = 2 * time.Second
+)
+
+// connectXModels maps the PCI device id of Mellanox adapters to their model
+var connectXModels = map[string]string{
+	"0x1003": "ConnectX-3",
+	"0x1004": "ConnectX-3 Virtual Function",
+	"0x1007": "ConnectX-3 Pro",
+	"0x1013": "ConnectX-4",
+	"0x1014": "ConnectX-4 Virtual Function",
+	"0x1015": "ConnectX-4 Lx",
+	"0x1016": "ConnectX-4 Lx Virtual Function",
+	"0x1017": "ConnectX-5",
+	"0x1018": "ConnectX-5 Virtual Function",
+	"0x1019": "ConnectX-5 Ex",
+	"0x101a": "ConnectX-5 Ex Virtual Function",
+	"0x101b": "ConnectX-6",
+	"0x101c": "ConnectX-6 Virtual Function",
+	"0x101d": "ConnectX-6 Dx",
+	"0x101e": "ConnectX Family Virtual Function",
+	"0x101f": "ConnectX-6 Lx",
+	"0x1021": "ConnectX-7",
+	"0x1023": "ConnectX-8",
+	"0xa2d6": "BlueField-2",
+	"0xa2dc": "BlueField-3",
+}
+
+// rdmaModules lists the modules reported by 'rdma check'
+var rdmaModules = []string{"mlx5_core", "ib_core", "nvidia", "nvidia_peermem"}
+
+// RDMADevice is a Mellanox network adapter
+type RDMADevice struct {
+	Address  string `json:"address"`
+	DeviceID string `json:"deviceID"`
+	Model    string `json:"model"`
+	Driver   string `json:"driver,omitempty"`
+}
+
+// RDMAStatus reports the GPUDirect RDMA prerequisites of the node
+type RDMAStatus struct {
+	Devices         []RDMADevice    `json:"devices"`
+	Modules         map[string]bool `json:"modules"`
+	PeermemLoaded   bool            `json:"peermemLoaded"`
+	PeermemLoadable bool            `json:"peermemLoadable"`
+	// Reasons lists why nvidia-peermem cannot be loaded yet
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+type rdmaOptions struct {
+	sysfsRoot        string
+	useHostMOFED     bool
+	mofedReadyFile   string
+	driverStatusFile string
+	driverReadyFile  string
+	rdmaEnabled      bool
+	output           string
+	wait             time.Duration
+}
+
+func newRDMACommand() *cli.Command {
+	opts := rdmaOptions{}
+
+	// Create the 'rdma check' subcommand
+	check := cli.Command{}
+	check.Name = "check"
+	check.Usage = "Report Mellanox adapters and RDMA modules, and whether nvidia-peermem can be loaded"
+	check.UsageText = "[--sysfs-root] [--use-host-mofed] [--mofed-ready-file] [--driver-status-file] [--driver-ready-file] [--rdma-enabled] [-o | --output] [--wait]"
+	check.Action = func(c *cli.Context) error {
+		return CheckRDMA(c, &opts)
+	}
+	check.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+		&cli.BoolFlag{
+			Name:        "use-host-mofed",
+			Usage:       "MOFED drivers are installed on the host, wait for mlx5_core instead of the MOFED driver container",
+			Destination: &opts.useHostMOFED,
+			EnvVars:     []string{"USE_HOST_MOFED"},
+		},
+		&cli.StringFlag{
+			Name:        "mofed-ready-file",
+			Usage:       "Readiness flag created by the MOFED driver container",
+			Value:       DefaultMOFEDReadyFile,
+			Destination: &opts.mofedReadyFile,
+		},
+		&cli.StringFlag{
+			Name:        "driver-status-file",
+			Usage:       "Driver status document of the driver container, not checked if empty",
+			Value:       DefaultDriverStatusFile,
+			Destination: &opts.driverStatusFile,
+			EnvVars:     []string{"DRIVER_STATUS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "driver-ready-file",
+			Usage:       "Readiness flag of the driver container, checked when there is no driver status document, e.g. with precompiled drivers",
+			Value:       DefaultDriverReadyFile,
+			Destination: &opts.driverReadyFile,
+		},
+		&cli.BoolFlag{
+			Name:        "rdma-enabled",
+			Usage:       "GPUDirect RDMA is requested, exit with 1 if nvidia-peermem cannot be loaded",
+			Destination: &opts.rdmaEnabled,
+			EnvVars:     []string{"GPU_DIRECT_RDMA_ENABLED"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env or json",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+		&cli.DurationFlag{
+			Name:        "wait",
+			Usage:       "Time to wait for nvidia-peermem to become loadable",
+			Destination: &opts.wait,
+		},
+	}
+
+	rdma := cli.Command{}
+	rdma.Name = "rdma"
+	rdma.Usage = "Inspect the GPUDirect RDMA prerequisites"
+	rdma.Subcommands = []*cli.Command{&check}
+	return &rdma
+}
+
+// CheckRDMA prints the RDMA status. When GPUDirect RDMA is requested, it exits with a non-zero
+// code if nvidia-peermem cannot be loaded.
+func CheckRDMA(c *cli.Context, opts *rdmaOptions) error {
+	log.Infof("Starting 'rdma check' with %v", c.App.Name)
+
+	status, err := GetRDMAStatus(opts)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(opts.wait)
+	for !status.PeermemLoadable && len(status.Devices) > 0 && time.Now().Before(deadline) {
+		log.Infof("waiting for nvidia-peermem to be loadable: %s", strings.Join(status.Reasons, "; "))
+		time.Sleep(rdmaWaitInterval)
+		if status, err = GetRDMAStatus(opts); err != nil {
+			return err
+		}
+	}
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		var addresses []string
+		for _, device := range status.Devices {
+			addresses = append(addresses, device.Address)
+		}
+		fmt.Printf("MELLANOX_DEVICES=%s\n", strings.Join(addresses, ","))
+		fmt.Printf("MLX5_CORE_LOADED=%t\n", status.Modules["mlx5_core"])
+		fmt.Printf("IB_CORE_LOADED=%t\n", status.Modules["ib_core"])
+		fmt.Printf("NVIDIA_PEERMEM_LOADED=%t\n", status.PeermemLoaded)
+		fmt.Printf("NVIDIA_PEERMEM_LOADABLE=%t\n", status.PeermemLoadable)
+		for _, device := range status.Devices {
+			fmt.Printf("# %s %s (%s)\n", device.Address, device.Model, orDash(device.Driver))
+		}
+		for _, reason := range status.Reasons {
+			fmt.Printf("# %s\n", reason)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+
+	log.Infof("Completed 'rdma check' with %v, nvidia-peermem loadable: %t", c.App.Name, status.PeermemLoadable)
+	if opts.rdmaEnabled && !status.PeermemLoadable {
+		return cli.Exit("", 1)
+	}
+	return nil
+}
+
+// GetRDMAStatus scans the Mellanox adapters and evaluates the nvidia-peermem prerequisites
+func GetRDMAStatus(opts *rdmaOptions) (*RDMAStatus, error) {
+	devices, err := ScanPCIDevices(filepath.Join(opts.sysfsRoot, "bus", "pci", "devices"), func(d *PCIDevice) bool {
+		return d.Vendor == MellanoxVendorID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan PCI devices: %v", err)
+	}
+
+	status := &RDMAStatus{Devices: []RDMADevice{}, Modules: map[string]bool{}}
+	for _, device := range devices {
+		model, ok := connectXModels[device.Device]
+		if !ok {
+			model = "unknown"
+		}
+		status.Devices = append(status.Devices, RDMADevice{Address: device.Address, DeviceID: device.Device, Model: model, Driver: device.Driver})
+	}
+	missing := missingModules(opts.sysfsRoot, rdmaModules)
+	for _, module := range rdmaModules {
+		status.Modules[module] = true
+	}
+	for _, module := range missing {
+		status.Modules[module] = false
+	}
+	status.PeermemLoaded = status.Modules["nvidia_peermem"]
+
+	if len(status.Devices) == 0 {
+		status.Reasons = append(status.Reasons, "no Mellanox devices found")
+	}
+	for _, module := range []string{"mlx5_core", "ib_core", "nvidia"} {
+		if !status.Modules[module] {
+			status.Reasons = append(status.Reasons, fmt.Sprintf("module %s is not loaded", module))
+		}
+	}
+	if !opts.useHostMOFED && !fileExists(opts.mofedReadyFile) {
+		status.Reasons = append(status.Reasons, fmt.Sprintf("MOFED driver container is not ready, %s not found", opts.mofedReadyFile))
+	}
+	if opts.driverStatusFile != "" {
+		driver, err := LoadDriverStatus(opts.driverStatusFile)
+		switch {
+		case err != nil:
+			status.Reasons = append(status.Reasons, fmt.Sprintf("driver container status unknown: %v", err))
+		case driver == nil && opts.driverReadyFile != "":
+			if !fileExists(opts.driverReadyFile) {
+				status.Reasons = append(status.Reasons, fmt.Sprintf("driver container is not ready, %s and %s not found", opts.driverStatusFile, opts.driverReadyFile))
+			}
+		case driver == nil:
+			status.Reasons = append(status.Reasons, fmt.Sprintf("driver container is not ready, %s not found", opts.driverStatusFile))
+		case driver.Phase.index() < PhaseReady.index():
+			status.Reasons = append(status.Reasons, fmt.Sprintf("driver container is not ready, in phase %s", driver.Phase))
+		}
+	}
+	status.PeermemLoadable = len(status.Reasons) == 0
+	return status, nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetRDMAStatus(t *testing.T) {
+	dir := t.TempDir()
+	sysfsRoot := filepath.Join(dir, "sys")
+	deviceDir := filepath.Join(sysfsRoot, "bus", "pci", "devices", "0000:03:00.0")
+	os.MkdirAll(deviceDir, 0755)
+	os.WriteFile(filepath.Join(deviceDir, "vendor"), []byte(MellanoxVendorID+"\n"), 0644)
+	os.WriteFile(filepath.Join(deviceDir, "device"), []byte("0x1021\n"), 0644)
+	for _, module := range []string{"mlx5_core", "ib_core", "nvidia"} {
+		os.MkdirAll(filepath.Join(sysfsRoot, "module", module), 0755)
+	}
+	mofedReadyFile := filepath.Join(dir, "mellanox", ".driver-ready")
+	os.MkdirAll(filepath.Dir(mofedReadyFile), 0755)
+	os.WriteFile(mofedReadyFile, nil, 0644)
+	statusFile := filepath.Join(dir, "validations", "driver-status.json")
+	readyFile := filepath.Join(dir, "validations", ".driver-ctr-ready")
+
+	opts := &rdmaOptions{sysfsRoot: sysfsRoot, mofedReadyFile: mofedReadyFile, driverStatusFile: statusFile, driverReadyFile: readyFile}
+	for _, tc := range []struct {
+		phase     DriverPhase
+		readyFile bool
+		loadable  bool
+		reason    string
+	}{
+		{"", false, false, "not found"},
+		// drivers not recording their status fall back to the readiness flag
+		{"", true, true, ""},
+		// the status document takes precedence over the readiness flag
+		{PhaseLoad, true, false, "in phase load"},
+		{PhaseReady, true, true, ""},
+	} {
+		if tc.readyFile {
+			os.MkdirAll(filepath.Dir(readyFile), 0755)
+			os.WriteFile(readyFile, nil, 0644)
+		}
+		if tc.phase != "" {
+			if err := SaveDriverStatus(statusFile, &DriverStatus{Phase: tc.phase}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		status, err := GetRDMAStatus(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(status.Devices) != 1 || status.Devices[0].Model != "ConnectX-7" {
+			t.Errorf("unexpected devices %+v", status.Devices)
+		}
+		if status.PeermemLoadable != tc.loadable || tc.reason != "" && !strings.Contains(strings.Join(status.Reasons, ";"), tc.reason) {
+			t.Errorf("phase %q: expected loadable %t with reason %q, got %t %v", tc.phase, tc.loadable, tc.reason, status.PeermemLoadable, status.Reasons)
+		}
+	}
+}
+
+func TestCheckRDMAExitCode(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	os.MkdirAll(filepath.Join(sysfsRoot, "bus", "pci", "devices"), 0755)
+	opts := &rdmaOptions{sysfsRoot: sysfsRoot, useHostMOFED: true, output: "env"}
+
+	// nvidia-peermem not being loadable is only a failure when RDMA is requested
+	if err := CheckRDMA(testContext(), opts); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	opts.rdmaEnabled = true
+	if err := CheckRDMA(testContext(), opts); err == nil {
+		t.Errorf("expected an error when RDMA is requested")
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	// registryTimeout bounds every request to the registry
+	registryTimeout = 30 * time.Second
+)
+
+// ErrManifestNotFound is returned for a tag the repository does not have
+var ErrManifestNotFound = errors.New("manifest not found")
+
+var (
+	// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:nvidia/driver:pull"
+	authParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+	// <https://ghcr.io/v2/nvidia/driver/tags/list?last=580&n=1000>; rel="next"
+	linkNextRegex = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="?next"?`)
+)
+
+// RegistryClient reads the tags and image platforms of the repositories of a registry
+type RegistryClient interface {
+	// ListTags returns the tags of a repository, e.g. nvidia/driver
+	ListTags(ctx context.Context, repository string) ([]string, error)
+	// ManifestPlatforms returns the platforms of an image, e.g. linux/amd64, or ErrManifestNotFound
+	ManifestPlatforms(ctx context.Context, repository string, tag string) ([]string, error)
+}
+
+// ociRegistryClient implements RegistryClient with the OCI distribution API
+type ociRegistryClient struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+	// tokens caches the bearer token of every scope
+	tokens map[string]string
+}
+
+// NewOCIRegistryClient returns a client of the registry host, e.g. ghcr.io or localhost:5000.
+// Credentials are optional, anonymous tokens are requested without.
+func NewOCIRegistryClient(host string, plainHTTP bool, username string, password string) RegistryClient {
+	scheme := "https"
+	if plainHTTP {
+		scheme = "http"
+	}
+	return &ociRegistryClient{
+		baseURL:  scheme + "://" + host,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: registryTimeout},
+		tokens:   map[string]string{},
+	}
+}
+
+type ociTagList struct {
+	Tags []string `json:"tags"`
+}
+
+type ociDescriptor struct {
+	MediaType string       `json:"mediaType"`
+	Digest    string       `json:"digest"`
+	Platform  *ociPlatform `json:"platform,omitempty"`
+}
+
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p ociPlatform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// ociManifest is an image index or an image manifest, as both are served for a tag
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests,omitempty"`
+	Config    *ociDescriptor  `json:"config,omitempty"`
+}
+
+func (r *ociRegistryClient) ListTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=1000", r.baseURL, repository)
+	for next != "" {
+		resp, err := r.get(ctx, repository, next, "application/json")
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			return nil, nil
+		}
+		var list ociTagList
+		err = decodeRegistryResponse(resp, &list)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list tags of %s: %v", repository, err)
+		}
+		tags = append(tags, list.Tags...)
+
+		next = ""
+		if m := linkNextRegex.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			link, err := url.Parse(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid Link header %q: %v", resp.Header.Get("Link"), err)
+			}
+			next = resp.Request.URL.ResolveReference(link).String()
+		}
+	}
+	return tags, nil
+}
+
+func (r *ociRegistryClient) ManifestPlatforms(ctx context.Context, repository string, tag string) ([]string, error) {
+	accept := strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeDockerManifest}, ", ")
+	resp, err := r.get(ctx, repository, fmt.Sprintf("%s/v2/%s/manifests/%s", r.baseURL, repository, tag), accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrManifestNotFound
+	}
+	var manifest ociManifest
+	if err := decodeRegistryResponse(resp, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to read manifest %s:%s: %v", repository, tag, err)
+	}
+
+	if len(manifest.Manifests) > 0 {
+		var platforms []string
+		for _, descriptor := range manifest.Manifests {
+			// attestations are listed with an unknown platform
+			if descriptor.Platform != nil && descriptor.Platform.OS != "unknown" {
+				platforms = append(platforms, descriptor.Platform.String())
+			}
+		}
+		return platforms, nil
+	}
+
+	// a single platform image only names its platform in its config
+	if manifest.Config == nil {
+		return nil, fmt.Errorf("manifest %s:%s has neither manifests nor config", repository, tag)
+	}
+	resp, err = r.get(ctx, repository, fmt.Sprintf("%s/v2/%s/blobs/%s", r.baseURL, repository, manifest.Config.Digest), "")
+	if err != nil {
+		return nil, err
+	}
+	var platform ociPlatform
+	if err := decodeRegistryResponse(resp, &platform); err != nil {
+		return nil, fmt.Errorf("unable to read config of %s:%s: %v", repository, tag, err)
+	}
+	return []string{platform.String()}, nil
+}
+
+// get sends a GET request, authenticating with a token of the repository pull scope when the
+// registry asks for one
+func (r *ociRegistryClient) get(ctx context.Context, repository string, target string, accept string) (*http.Response, error) {
+	scope := "repository:" + repository + ":pull"
+	for attempt := 0; attempt < 2; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token, ok := r.tokens[scope]; ok {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if r.tokens[scope], err = r.authorize(ctx, challenge, scope); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("unauthorized to pull %s", repository)
+}
+
+// authorize returns the Authorization header answering a WWW-Authenticate challenge
+func (r *ociRegistryClient) authorize(ctx context.Context, challenge string, scope string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(challenge), "basic") {
+		if r.username == "" {
+			return "", errors.New("registry requires credentials")
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(r.username, r.password)
+		return req.Header.Get("Authorization"), nil
+	}
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range authParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("no realm in authentication challenge %q", challenge)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	if params["scope"] != "" {
+		scope = params["scope"]
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeRegistryResponse(resp, &token); err != nil {
+		return "", fmt.Errorf("unable to get a token from %s: %v", params["realm"], err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	log.Debugf("got registry token for %s", scope)
+	return "Bearer " + token.Token, nil
+}
+
+func decodeRegistryResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeRegistry serves the nvidia/driver repository of an OCI distribution API
+type fakeRegistry struct {
+	// auth is the challenge sent without credentials, bearer, basic or none
+	auth     string
+	username string
+	password string
+	tags     []string
+	// pageSize splits the tag list in pages linked by a Link header
+	pageSize int
+	// failing answers every request with an internal server error
+	failing  bool
+	requests []string
+}
+
+const (
+	fakeRegistryToken    = "secret-token"
+	fakeRegistryIndex    = `{"mediaType": "` + mediaTypeOCIIndex + `", "manifests": [{"mediaType": "` + mediaTypeOCIManifest + `", "digest": "sha256:aa", "platform": {"architecture": "amd64", "os": "linux"}}, {"mediaType": "` + mediaTypeOCIManifest + `", "digest": "sha256:bb", "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}, {"mediaType": "` + mediaTypeOCIManifest + `", "digest": "sha256:cc", "platform": {"architecture": "unknown", "os": "unknown"}}]}`
+	fakeRegistryManifest = `{"mediaType": "` + mediaTypeDockerManifest + `", "config": {"mediaType": "application/vnd.docker.container.image.v1+json", "digest": "sha256:config"}}`
+	fakeRegistryConfig   = `{"architecture": "arm64", "os": "linux", "rootfs": {}}`
+)
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.URL.Path)
+	if f.failing {
+		http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.URL.Path == "/token" {
+		if username, password, _ := r.BasicAuth(); username != f.username || password != f.password {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if scope := r.URL.Query().Get("scope"); scope != "repository:nvidia/driver:pull" || r.URL.Query().Get("service") != "fake" {
+			http.Error(w, "invalid scope "+scope, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": %q}`, fakeRegistryToken)
+		return
+	}
+
+	switch f.auth {
+	case "bearer":
+		if r.Header.Get("Authorization") != "Bearer "+fakeRegistryToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake",scope="repository:nvidia/driver:pull"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	case "basic":
+		if username, password, ok := r.BasicAuth(); !ok || username != f.username || password != f.password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="fake"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	switch {
+	case r.URL.Path == "/v2/nvidia/driver/tags/list":
+		tags := f.tags
+		if last := r.URL.Query().Get("last"); last != "" {
+			i := sort.SearchStrings(tags, last)
+			tags = tags[i+1:]
+		}
+		if f.pageSize > 0 && len(tags) > f.pageSize {
+			tags = tags[:f.pageSize]
+			// a relative link, as served by the registries
+			w.Header().Set("Link", fmt.Sprintf(`</v2/nvidia/driver/tags/list?last=%s&n=%d>; rel="next"`, tags[len(tags)-1], f.pageSize))
+		}
+		fmt.Fprintf(w, `{"name": "nvidia/driver", "tags": ["%s"]}`, strings.Join(tags, `", "`))
+	case r.URL.Path == "/v2/nvidia/driver/manifests/580-index-ubuntu24.04":
+		w.Header().Set("Content-Type", mediaTypeOCIIndex)
+		fmt.Fprint(w, fakeRegistryIndex)
+	case r.URL.Path == "/v2/nvidia/driver/manifests/580-single-ubuntu24.04":
+		w.Header().Set("Content-Type", mediaTypeDockerManifest)
+		fmt.Fprint(w, fakeRegistryManifest)
+	case r.URL.Path == "/v2/nvidia/driver/blobs/sha256:config":
+		fmt.Fprint(w, fakeRegistryConfig)
+	case r.URL.Path == "/v2/nvidia/driver/manifests/580-broken-ubuntu24.04":
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	default:
+		http.Error(w, `{"errors": [{"code": "NAME_UNKNOWN"}]}`, http.StatusNotFound)
+	}
+}
+
+func newFakeRegistryClient(t *testing.T, registry *fakeRegistry, username string, password string) RegistryClient {
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+	return NewOCIRegistryClient(strings.TrimPrefix(server.URL, "http://"), true, username, password)
+}
+
+func TestOCIRegistryClientAuth(t *testing.T) {
+	tags := []string{"580-index-ubuntu24.04", "580-single-ubuntu24.04"}
+	testCases := []struct {
+		description string
+		registry    *fakeRegistry
+		username    string
+		password    string
+		expectError bool
+	}{
+		{"anonymous", &fakeRegistry{tags: tags}, "", "", false},
+		{"anonymous bearer token", &fakeRegistry{auth: "bearer", tags: tags}, "", "", false},
+		{"bearer token with credentials", &fakeRegistry{auth: "bearer", username: "user", password: "pass", tags: tags}, "user", "pass", false},
+		{"bearer token with invalid credentials", &fakeRegistry{auth: "bearer", username: "user", password: "pass", tags: tags}, "user", "wrong", true},
+		{"basic", &fakeRegistry{auth: "basic", username: "user", password: "pass", tags: tags}, "user", "pass", false},
+		{"basic without credentials", &fakeRegistry{auth: "basic", username: "user", password: "pass", tags: tags}, "", "", true},
+		{"basic with invalid credentials", &fakeRegistry{auth: "basic", username: "user", password: "pass", tags: tags}, "user", "wrong", true},
+	}
+	for _, tc := range testCases {
+		client := newFakeRegistryClient(t, tc.registry, tc.username, tc.password)
+		list, err := client.ListTags(context.Background(), "nvidia/driver")
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+			continue
+		}
+		if strings.Join(list, " ") != strings.Join(tags, " ") {
+			t.Errorf("%s: unexpected tags %v", tc.description, list)
+		}
+
+		// the token is cached for the following requests of the repository
+		if _, err := client.ManifestPlatforms(context.Background(), "nvidia/driver", "580-index-ubuntu24.04"); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+		}
+		tokens := 0
+		for _, path := range tc.registry.requests {
+			if path == "/token" {
+				tokens++
+			}
+		}
+		if tc.registry.auth == "bearer" && tokens != 1 {
+			t.Errorf("%s: expected a single token request, got %v", tc.description, tc.registry.requests)
+		}
+	}
+}
+
+func TestOCIRegistryClientListTags(t *testing.T) {
+	var tags []string
+	for i := 0; i < 7; i++ {
+		tags = append(tags, fmt.Sprintf("580-6.8.0-%d-generic-ubuntu24.04", 10+i))
+	}
+	registry := &fakeRegistry{auth: "bearer", tags: tags, pageSize: 3}
+	client := newFakeRegistryClient(t, registry, "", "")
+
+	list, err := client.ListTags(context.Background(), "nvidia/driver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(list, " ") != strings.Join(tags, " ") {
+		t.Errorf("unexpected tags %v", list)
+	}
+	pages := 0
+	for _, path := range registry.requests {
+		if path == "/v2/nvidia/driver/tags/list" {
+			pages++
+		}
+	}
+	// 3 pages, and the first one once more after the token request
+	if pages != 4 {
+		t.Errorf("expected 4 tag list requests, got %v", registry.requests)
+	}
+
+	// an unknown repository has no tags
+	list, err = client.ListTags(context.Background(), "nvidia/unknown")
+	if err != nil || len(list) != 0 {
+		t.Errorf("unexpected tags %v, error %v", list, err)
+	}
+
+	registry.failing = true
+	if _, err := client.ListTags(context.Background(), "nvidia/driver"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected a 503 error, got %v", err)
+	}
+}
+
+func TestOCIRegistryClientManifestPlatforms(t *testing.T) {
+	registry := &fakeRegistry{auth: "bearer"}
+	client := newFakeRegistryClient(t, registry, "", "")
+
+	testCases := []struct {
+		tag       string
+		platforms []string
+		err       error
+	}{
+		// the attestation of the index is skipped
+		{"580-index-ubuntu24.04", []string{"linux/amd64", "linux/arm64/v8"}, nil},
+		// the platform of a single manifest is read from its config
+		{"580-single-ubuntu24.04", []string{"linux/arm64"}, nil},
+		{"580-missing-ubuntu24.04", nil, ErrManifestNotFound},
+	}
+	for _, tc := range testCases {
+		platforms, err := client.ManifestPlatforms(context.Background(), "nvidia/driver", tc.tag)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected error %v, got %v", tc.tag, tc.err, err)
+		}
+		if strings.Join(platforms, " ") != strings.Join(tc.platforms, " ") {
+			t.Errorf("%s: unexpected platforms %v", tc.tag, platforms)
+		}
+	}
+
+	_, err := client.ManifestPlatforms(context.Background(), "nvidia/driver", "580-broken-ubuntu24.04")
+	if err == nil || errors.Is(err, ErrManifestNotFound) || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected a 500 error, got %v", err)
+	}
+	registry.failing = true
+	if _, err := client.ManifestPlatforms(context.Background(), "nvidia/driver", "580-index-ubuntu24.04"); err == nil || errors.Is(err, ErrManifestNotFound) {
+		t.Errorf("expected a registry error, got %v", err)
+	}
+}
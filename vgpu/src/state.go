@@ -0,0 +1,372 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DriverStateVersion is the version of the driver state file
+	DriverStateVersion = 1
+	// DefaultDriverStateFile indicates default location of the driver state file
+	DefaultDriverStateFile = "/run/nvidia/nvidia-driver.state"
+)
+
+// DriverState is the configuration the NVIDIA kernel modules were loaded with
+type DriverState struct {
+	Version       int    `json:"version"`
+	Digest        string `json:"digest"`
+	DriverVersion string `json:"driverVersion"`
+	KernelType    string `json:"kernelType"`
+	KernelRelease string `json:"kernelRelease"`
+	// ModuleParams lists the parameters of every module, as rendered in the modprobe config
+	ModuleParams map[string][]string `json:"moduleParams"`
+	// Features lists the enabled features as name=value, sorted
+	Features []string `json:"features"`
+}
+
+// StateDifference is a field differing between the stored and the requested state, or the loaded driver
+type StateDifference struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (d StateDifference) String() string {
+	return fmt.Sprintf("%s: expected %q, found %q", d.Field, d.Expected, d.Actual)
+}
+
+type stateOptions struct {
+	stateFile       string
+	sysfsRoot       string
+	driverVersion   string
+	kernelType      string
+	kernelRelease   string
+	paramsDirectory string
+	features        cli.StringSlice
+	noDefaults      bool
+}
+
+func newStateCommand() *cli.Command {
+	opts := stateOptions{}
+
+	inputFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:        "driver-version",
+			Destination: &opts.driverVersion,
+			EnvVars:     []string{"DRIVER_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "kernel-type",
+			Usage:       "Kernel module type, kernel or kernel-open",
+			Destination: &opts.kernelType,
+			EnvVars:     []string{"KERNEL_TYPE"},
+		},
+		&cli.StringFlag{
+			Name:        "kernel-release",
+			Usage:       "Kernel release, the running kernel if not set",
+			Destination: &opts.kernelRelease,
+			EnvVars:     []string{"KERNEL_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "input-directory",
+			Aliases:     []string{"i"},
+			Usage:       "Directory containing the <module>.conf parameter files",
+			Value:       DefaultModuleParamsDirectory,
+			Destination: &opts.paramsDirectory,
+		},
+		&cli.BoolFlag{
+			Name:        "no-defaults",
+			Usage:       "Do not add the built-in module parameters",
+			Destination: &opts.noDefaults,
+		},
+		&cli.StringSliceFlag{
+			Name:        "feature",
+			Usage:       "Enabled feature affecting the loaded modules, as name=value, e.g. GPU_DIRECT_RDMA_ENABLED=true",
+			Destination: &opts.features,
+		},
+	}
+	stateFileFlag := &cli.StringFlag{
+		Name:        "state-file",
+		Aliases:     []string{"s"},
+		Usage:       "Driver state file",
+		Value:       DefaultDriverStateFile,
+		Destination: &opts.stateFile,
+	}
+
+	// Create the 'state digest' subcommand
+	digest := cli.Command{}
+	digest.Name = "digest"
+	digest.Usage = "Print the digest of the requested driver configuration"
+	digest.Action = func(c *cli.Context) error {
+		return PrintStateDigest(c, &opts)
+	}
+	digest.Flags = inputFlags
+
+	// Create the 'state store' subcommand
+	store := cli.Command{}
+	store.Name = "store"
+	store.Usage = "Store the requested driver configuration once the modules are loaded"
+	store.Action = func(c *cli.Context) error {
+		return StoreState(c, &opts)
+	}
+	store.Flags = append([]cli.Flag{stateFileFlag}, inputFlags...)
+
+	// Create the 'state compare' subcommand
+	compare := cli.Command{}
+	compare.Name = "compare"
+	compare.Usage = "Compare the requested driver configuration with the stored one and the loaded modules, exit with 1 on differences"
+	compare.Action = func(c *cli.Context) error {
+		return CompareState(c, &opts)
+	}
+	compare.Flags = append([]cli.Flag{stateFileFlag, &cli.StringFlag{
+		Name:        "sysfs-root",
+		Usage:       "Mount point of sysfs",
+		Value:       DefaultSysfsRoot,
+		Destination: &opts.sysfsRoot,
+	}}, inputFlags...)
+
+	state := cli.Command{}
+	state.Name = "state"
+	state.Usage = "Compute, store and compare the driver configuration the kernel modules are loaded with"
+	state.Subcommands = []*cli.Command{&digest, &store, &compare}
+	return &state
+}
+
+// PrintStateDigest prints the digest of the requested configuration
+func PrintStateDigest(c *cli.Context, opts *stateOptions) error {
+	state, err := ComputeDriverState(opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println(state.Digest)
+	return nil
+}
+
+// StoreState writes the requested configuration to the state file
+func StoreState(c *cli.Context, opts *stateOptions) error {
+	log.Infof("Starting 'state store' with %v", c.App.Name)
+
+	state, err := ComputeDriverState(opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println("Storing driver configuration digest...")
+	if err := writeFileAtomic(opts.stateFile, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Driver configuration digest stored at %s\n", opts.stateFile)
+
+	log.Infof("Completed 'state store' with %v, digest %s", c.App.Name, state.Digest)
+	return nil
+}
+
+// CompareState reports the fields differing between the requested configuration, the
+// stored one and the loaded modules. It succeeds only if the module reload can be skipped.
+func CompareState(c *cli.Context, opts *stateOptions) error {
+	log.Infof("Starting 'state compare' with %v", c.App.Name)
+
+	requested, err := ComputeDriverState(opts)
+	if err != nil {
+		return err
+	}
+
+	var differences []StateDifference
+	stored, err := LoadDriverState(opts.stateFile)
+	if err != nil {
+		differences = append(differences, StateDifference{Field: "stateFile", Expected: "stored state", Actual: err.Error()})
+	} else if stored.Digest != requested.Digest {
+		differences = append(differences, DiffDriverState(stored, requested)...)
+	}
+	differences = append(differences, DiffLoadedDriver(opts.sysfsRoot, requested)...)
+
+	if len(differences) > 0 {
+		for _, difference := range differences {
+			fmt.Println(difference)
+		}
+		log.Infof("driver state differs: %d differences", len(differences))
+		return cli.Exit("", 1)
+	}
+	fmt.Printf("Driver state matches digest %s\n", requested.Digest)
+
+	log.Infof("Completed 'state compare' with %v", c.App.Name)
+	return nil
+}
+
+// ComputeDriverState builds the requested state from the options and the module parameter files
+func ComputeDriverState(opts *stateOptions) (*DriverState, error) {
+	state := &DriverState{
+		Version:       DriverStateVersion,
+		DriverVersion: opts.driverVersion,
+		KernelType:    opts.kernelType,
+		KernelRelease: opts.kernelRelease,
+		ModuleParams:  map[string][]string{},
+		Features:      []string{},
+	}
+	if state.KernelRelease == "" {
+		release, err := runningKernelRelease()
+		if err != nil {
+			return nil, err
+		}
+		state.KernelRelease = release
+	}
+
+	for _, module := range NvidiaModules {
+		var defaults []ModuleParam
+		if !opts.noDefaults {
+			defaults = builtinModuleParams[module]
+		}
+		user, err := loadModuleParamsFile(filepath.Join(opts.paramsDirectory, module+".conf"))
+		if err != nil {
+			return nil, err
+		}
+		params, err := MergeModuleParams(defaults, user)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters for module %s: %v", module, err)
+		}
+		if len(params) == 0 {
+			continue
+		}
+		for _, param := range params {
+			state.ModuleParams[module] = append(state.ModuleParams[module], param.String())
+		}
+	}
+
+	for _, feature := range opts.features.Value() {
+		if !strings.Contains(feature, "=") {
+			return nil, fmt.Errorf("invalid --feature %q, expected name=value", feature)
+		}
+		state.Features = append(state.Features, feature)
+	}
+	sort.Strings(state.Features)
+
+	digest, err := state.computeDigest()
+	if err != nil {
+		return nil, err
+	}
+	state.Digest = digest
+	return state, nil
+}
+
+// computeDigest hashes every field but the digest itself. Maps are marshalled with sorted keys.
+func (s *DriverState) computeDigest() (string, error) {
+	unhashed := *s
+	unhashed.Digest = ""
+	data, err := json.Marshal(unhashed)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// LoadDriverState reads the state file. The plain digest written by earlier versions is rejected.
+func LoadDriverState(stateFile string) (*DriverState, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return nil, err
+	}
+	state := &DriverState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("unsupported state file format")
+	}
+	if state.Version != DriverStateVersion {
+		return nil, fmt.Errorf("unsupported state file version %d", state.Version)
+	}
+	return state, nil
+}
+
+// DiffDriverState returns the fields differing between the stored and the requested state
+func DiffDriverState(stored *DriverState, requested *DriverState) []StateDifference {
+	var differences []StateDifference
+	add := func(field, expected, actual string) {
+		if expected != actual {
+			differences = append(differences, StateDifference{Field: field, Expected: expected, Actual: actual})
+		}
+	}
+	add("driverVersion", requested.DriverVersion, stored.DriverVersion)
+	add("kernelType", requested.KernelType, stored.KernelType)
+	add("kernelRelease", requested.KernelRelease, stored.KernelRelease)
+	for _, module := range NvidiaModules {
+		add("moduleParams."+module, strings.Join(requested.ModuleParams[module], " "), strings.Join(stored.ModuleParams[module], " "))
+	}
+	add("features", strings.Join(requested.Features, ","), strings.Join(stored.Features, ","))
+	return differences
+}
+
+// DiffLoadedDriver compares the requested state with the loaded modules: the module must be
+// loaded with the requested version, and the parameters exposed in sysfs must have the requested value
+func DiffLoadedDriver(sysfsRoot string, requested *DriverState) []StateDifference {
+	var differences []StateDifference
+	moduleDir := filepath.Join(sysfsRoot, "module", NvidiaCoreModule)
+	if _, err := os.Stat(filepath.Join(moduleDir, "refcnt")); err != nil {
+		return []StateDifference{{Field: "loaded", Expected: NvidiaCoreModule, Actual: "not loaded"}}
+	}
+	// the version is not compared when none was requested
+	if version := readSysfsString(filepath.Join(moduleDir, "version")); requested.DriverVersion != "" && version != requested.DriverVersion {
+		differences = append(differences, StateDifference{Field: "loaded.version", Expected: requested.DriverVersion, Actual: version})
+	}
+
+	for _, module := range NvidiaModules {
+		paramsDir := filepath.Join(sysfsRoot, "module", moduleSysfsName(module), "parameters")
+		for _, field := range requested.ModuleParams[module] {
+			param, err := ParseModuleParam(field)
+			if err != nil || !param.HasValue {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(paramsDir, param.key()))
+			if err != nil {
+				// not exposed in sysfs, or module not loaded
+				continue
+			}
+			expected := strings.Trim(param.Value, `"`)
+			if actual := strings.TrimSpace(string(data)); actual != expected {
+				differences = append(differences, StateDifference{Field: "loaded.parameters." + moduleSysfsName(module) + "." + param.key(), Expected: expected, Actual: actual})
+			}
+		}
+	}
+	return differences
+}
+
+// runningKernelRelease returns the release of the running kernel, as 'uname -r'
+func runningKernelRelease() (string, error) {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return "", fmt.Errorf("unable to get kernel release: %v", err)
+	}
+	var release strings.Builder
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release.WriteByte(byte(c))
+	}
+	return release.String(), nil
+}
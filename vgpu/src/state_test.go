@@ -0,0 +1,51 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffLoadedDriver(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	moduleDir := filepath.Join(sysfsRoot, "module", "nvidia")
+	os.MkdirAll(filepath.Join(moduleDir, "parameters"), 0755)
+	os.WriteFile(filepath.Join(moduleDir, "refcnt"), []byte("0\n"), 0644)
+	os.WriteFile(filepath.Join(moduleDir, "version"), []byte("580.65.06\n"), 0644)
+	os.WriteFile(filepath.Join(moduleDir, "parameters", "NVreg_EnableGpuFirmware"), []byte("18\n"), 0644)
+
+	testCases := []struct {
+		description string
+		requested   DriverState
+		differences int
+	}{
+		{"same version", DriverState{DriverVersion: "580.65.06"}, 0},
+		{"other version", DriverState{DriverVersion: "570.172.08"}, 1},
+		{"no version requested", DriverState{}, 0},
+		{"same parameter", DriverState{ModuleParams: map[string][]string{"nvidia": {"NVreg_EnableGpuFirmware=18"}}}, 0},
+		{"other parameter", DriverState{ModuleParams: map[string][]string{"nvidia": {"NVreg_EnableGpuFirmware=0"}}}, 1},
+		{"parameter not exposed", DriverState{ModuleParams: map[string][]string{"nvidia": {"NVreg_OpenRmEnableUnsupportedGpus=1"}}}, 0},
+	}
+	for _, tc := range testCases {
+		if differences := DiffLoadedDriver(sysfsRoot, &tc.requested); len(differences) != tc.differences {
+			t.Errorf("%s: expected %d differences, got %v", tc.description, tc.differences, differences)
+		}
+	}
+
+	if differences := DiffLoadedDriver(t.TempDir(), &DriverState{}); len(differences) != 1 || differences[0].Field != "loaded" {
+		t.Errorf("expected the driver not to be loaded, got %v", differences)
+	}
+}
@@ -0,0 +1,514 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DriverStatusVersion is the version of the driver status document
+	DriverStatusVersion = 1
+	// DefaultDriverStatusFile indicates default location of the driver status document
+	DefaultDriverStatusFile = "/run/nvidia/validations/driver-status.json"
+	// statusWaitInterval is the interval between two reads of the status document while waiting
+	statusWaitInterval = time.Second
+)
+
+// DriverPhase is a step of the driver container lifecycle
+type DriverPhase string
+
+const (
+	// PhasePrepare is the installation of the driver prerequisites
+	PhasePrepare DriverPhase = "prepare"
+	// PhaseBuild is the build or link of the kernel modules
+	PhaseBuild DriverPhase = "build"
+	// PhaseLoad is the load of the kernel modules
+	PhaseLoad DriverPhase = "load"
+	// PhaseDaemons is the start of the NVIDIA daemons
+	PhaseDaemons DriverPhase = "daemons"
+	// PhaseReady is reached once the driver is fully usable
+	PhaseReady DriverPhase = "ready"
+)
+
+// driverPhases lists the phases in lifecycle order
+var driverPhases = []DriverPhase{PhasePrepare, PhaseBuild, PhaseLoad, PhaseDaemons, PhaseReady}
+
+func (p DriverPhase) index() int {
+	for i, phase := range driverPhases {
+		if phase == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// DriverStatus is the versioned driver status document
+type DriverStatus struct {
+	Version       int                       `json:"version"`
+	Phase         DriverPhase               `json:"phase"`
+	DriverVersion string                    `json:"driverVersion,omitempty"`
+	KernelType    string                    `json:"kernelType,omitempty"`
+	KernelRelease string                    `json:"kernelRelease,omitempty"`
+	Modules       []LoadedModule            `json:"modules"`
+	Daemons       []DaemonStatus            `json:"daemons"`
+	LastError     *DriverError              `json:"lastError,omitempty"`
+	KernelUpdate  *KernelUpdateResult       `json:"kernelUpdate,omitempty"`
+	Transitions   map[DriverPhase]time.Time `json:"transitions"`
+	UpdatedAt     time.Time                 `json:"updatedAt"`
+}
+
+// DriverError is the last error reported by the driver container
+type DriverError struct {
+	Phase   DriverPhase `json:"phase"`
+	Message string      `json:"message"`
+	Time    time.Time   `json:"time"`
+}
+
+// LoadedModule is an NVIDIA kernel module loaded in the kernel
+type LoadedModule struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	RefCnt  int    `json:"refcnt"`
+}
+
+type statusOptions struct {
+	statusFile       string
+	daemonStatusFile string
+	sysfsRoot        string
+	phase            string
+	driverVersion    string
+	kernelType       string
+	kernelRelease    string
+	errorMessage     string
+	field            string
+	timeout          time.Duration
+	failOnError      bool
+}
+
+func newStatusCommand() *cli.Command {
+	opts := statusOptions{}
+
+	statusFileFlag := &cli.StringFlag{
+		Name:        "status-file",
+		Usage:       "Driver status document",
+		Value:       DefaultDriverStatusFile,
+		Destination: &opts.statusFile,
+		EnvVars:     []string{"DRIVER_STATUS_FILE"},
+	}
+	phaseFlag := &cli.StringFlag{
+		Name:        "phase",
+		Usage:       fmt.Sprintf("Driver phase, one of %s", joinPhases()),
+		Destination: &opts.phase,
+	}
+
+	// Create the 'status set' subcommand
+	set := cli.Command{}
+	set.Name = "set"
+	set.Usage = "Record the current driver phase, versions and last error"
+	set.UsageText = "--phase [--driver-version] [--kernel-type] [--kernel-release] [--error]"
+	set.Action = func(c *cli.Context) error {
+		return SetStatus(c, &opts)
+	}
+	set.Flags = []cli.Flag{
+		statusFileFlag,
+		phaseFlag,
+		&cli.StringFlag{
+			Name:        "driver-version",
+			Destination: &opts.driverVersion,
+			EnvVars:     []string{"DRIVER_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "kernel-type",
+			Usage:       "Kernel module type, kernel or kernel-open",
+			Destination: &opts.kernelType,
+		},
+		&cli.StringFlag{
+			Name:        "kernel-release",
+			Destination: &opts.kernelRelease,
+			EnvVars:     []string{"KERNEL_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "error",
+			Usage:       "Error encountered in the phase",
+			Destination: &opts.errorMessage,
+		},
+		&cli.StringFlag{
+			Name:        "daemon-status-file",
+			Usage:       "Status file written by the daemon supervisor",
+			Value:       DefaultSupervisorStatusFile,
+			Destination: &opts.daemonStatusFile,
+		},
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+		},
+	}
+
+	// Create the 'status get' subcommand
+	get := cli.Command{}
+	get.Name = "get"
+	get.Usage = "Print the driver status document or one of its fields"
+	get.UsageText = "[--field]"
+	get.Action = func(c *cli.Context) error {
+		return GetStatus(c, &opts)
+	}
+	get.Flags = []cli.Flag{
+		statusFileFlag,
+		&cli.StringFlag{
+			Name:        "field",
+			Usage:       "Only print this top level field",
+			Destination: &opts.field,
+		},
+	}
+
+	// Create the 'status wait' subcommand
+	wait := cli.Command{}
+	wait.Name = "wait"
+	wait.Usage = "Wait until the driver reaches a phase"
+	wait.UsageText = "--phase [--timeout] [--fail-on-error]"
+	wait.Action = func(c *cli.Context) error {
+		return WaitStatus(c, &opts)
+	}
+	wait.Flags = []cli.Flag{
+		statusFileFlag,
+		phaseFlag,
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Maximum time to wait, 0 to wait forever",
+			Destination: &opts.timeout,
+		},
+		&cli.BoolFlag{
+			Name:        "fail-on-error",
+			Usage:       "Stop waiting if the driver reports an error",
+			Destination: &opts.failOnError,
+		},
+	}
+
+	// Create the 'status clear' subcommand
+	clear := cli.Command{}
+	clear.Name = "clear"
+	clear.Usage = "Remove the driver status document left by a previous run of the driver container"
+	clear.Action = func(c *cli.Context) error {
+		return ClearStatus(c, &opts)
+	}
+	clear.Flags = []cli.Flag{
+		statusFileFlag,
+	}
+
+	status := cli.Command{}
+	status.Name = "status"
+	status.Usage = "Record and query the driver readiness status"
+	status.Subcommands = []*cli.Command{&set, &get, &wait, &clear}
+	return &status
+}
+
+func joinPhases() string {
+	phases := make([]string, 0, len(driverPhases))
+	for _, phase := range driverPhases {
+		phases = append(phases, string(phase))
+	}
+	return strings.Join(phases, ", ")
+}
+
+func parsePhase(phase string) (DriverPhase, error) {
+	if DriverPhase(phase).index() < 0 {
+		return "", fmt.Errorf("invalid phase %q, must be one of %s", phase, joinPhases())
+	}
+	return DriverPhase(phase), nil
+}
+
+// SetStatus updates the driver status document
+func SetStatus(c *cli.Context, opts *statusOptions) error {
+	phase, err := parsePhase(opts.phase)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockDriverStatus(opts.statusFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	status, err := LoadDriverStatus(opts.statusFile)
+	if err != nil {
+		log.Warnf("Ignoring previous driver status: %v", err)
+	}
+	if status == nil {
+		status = &DriverStatus{}
+	}
+
+	now := time.Now().UTC()
+	if status.Phase != phase {
+		if status.Transitions == nil || phase.index() <= status.Phase.index() {
+			// restarting the lifecycle, e.g. after a container restart
+			status.Transitions = map[DriverPhase]time.Time{}
+		}
+		status.Transitions[phase] = now
+	}
+	status.Phase = phase
+	if opts.driverVersion != "" {
+		status.DriverVersion = opts.driverVersion
+	}
+	if opts.kernelType != "" {
+		status.KernelType = opts.kernelType
+	}
+	if opts.kernelRelease != "" {
+		status.KernelRelease = opts.kernelRelease
+	}
+	if opts.errorMessage != "" {
+		status.LastError = &DriverError{Phase: phase, Message: opts.errorMessage, Time: now}
+	} else if phase == PhaseReady {
+		status.LastError = nil
+	}
+
+	if status.Modules, err = ReadLoadedModules(opts.sysfsRoot); err != nil {
+		log.Warnf("unable to read loaded modules: %v", err)
+	}
+	if status.Daemons, err = loadDaemonStatuses(opts.daemonStatusFile); err != nil {
+		log.Warnf("unable to read daemon status: %v", err)
+	}
+
+	if err := SaveDriverStatus(opts.statusFile, status); err != nil {
+		return err
+	}
+	log.Infof("driver status set to phase %s", phase)
+	return nil
+}
+
+// GetStatus prints the driver status document
+func GetStatus(c *cli.Context, opts *statusOptions) error {
+	data, err := os.ReadFile(opts.statusFile)
+	if err != nil {
+		return fmt.Errorf("unable to read driver status: %v", err)
+	}
+	if opts.field == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	return printJSONField(data, opts.field, opts.statusFile)
+}
+
+// printJSONField prints a top level field of a JSON document, strings unquoted for shell consumption
+func printJSONField(data []byte, field string, source string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", source, err)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return fmt.Errorf("field %s is not set", field)
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		fmt.Println(s)
+		return nil
+	}
+	fmt.Println(string(value))
+	return nil
+}
+
+// WaitStatus waits until the driver status reaches a phase
+func WaitStatus(c *cli.Context, opts *statusOptions) error {
+	phase, err := parsePhase(opts.phase)
+	if err != nil {
+		return err
+	}
+
+	var deadline time.Time
+	if opts.timeout > 0 {
+		deadline = time.Now().Add(opts.timeout)
+	}
+	for {
+		status, err := LoadDriverStatus(opts.statusFile)
+		if err != nil {
+			log.Debugf("unable to read driver status: %v", err)
+		}
+		if status != nil {
+			if status.Phase.index() >= phase.index() {
+				fmt.Printf("driver reached phase %s\n", status.Phase)
+				return nil
+			}
+			if opts.failOnError && status.LastError != nil {
+				return cli.Exit(fmt.Sprintf("driver failed in phase %s: %s", status.LastError.Phase, status.LastError.Message), 1)
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			current := "unknown"
+			if status != nil {
+				current = string(status.Phase)
+			}
+			return cli.Exit(fmt.Sprintf("timed out after %v waiting for phase %s, current phase is %s", opts.timeout, phase, current), 1)
+		}
+		time.Sleep(statusWaitInterval)
+	}
+}
+
+// ClearStatus removes the driver status document, the status directory under /run outlives the driver container
+func ClearStatus(c *cli.Context, opts *statusOptions) error {
+	unlock, err := lockDriverStatus(opts.statusFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(opts.statusFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove driver status: %v", err)
+	}
+	log.Infof("driver status cleared")
+	return nil
+}
+
+// LoadDriverStatus reads the driver status document, nil if it does not exist yet
+func LoadDriverStatus(statusFile string) (*DriverStatus, error) {
+	data, err := os.ReadFile(statusFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var status DriverStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", statusFile, err)
+	}
+	if status.Version != DriverStatusVersion {
+		return nil, fmt.Errorf("unsupported driver status version %d in %s", status.Version, statusFile)
+	}
+	return &status, nil
+}
+
+// SaveDriverStatus atomically writes the driver status document
+func SaveDriverStatus(statusFile string, status *DriverStatus) error {
+	status.Version = DriverStatusVersion
+	status.UpdatedAt = time.Now().UTC()
+	if status.Modules == nil {
+		status.Modules = []LoadedModule{}
+	}
+	if status.Daemons == nil {
+		status.Daemons = []DaemonStatus{}
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(statusFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write driver status: %v", err)
+	}
+	return nil
+}
+
+// ReadLoadedModules returns the NVIDIA kernel modules currently loaded
+func ReadLoadedModules(sysfsRoot string) ([]LoadedModule, error) {
+	paths, err := filepath.Glob(filepath.Join(sysfsRoot, "module", "nvidia*", "refcnt"))
+	if err != nil {
+		return nil, err
+	}
+	modules := []LoadedModule{}
+	for _, path := range paths {
+		moduleDir := filepath.Dir(path)
+		module := LoadedModule{
+			Name:    filepath.Base(moduleDir),
+			Version: readSysfsString(filepath.Join(moduleDir, "version")),
+		}
+		fmt.Sscanf(readSysfsString(path), "%d", &module.RefCnt)
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules, nil
+}
+
+func loadDaemonStatuses(statusFile string) ([]DaemonStatus, error) {
+	data, err := os.ReadFile(statusFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var statuses []DaemonStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", statusFile, err)
+	}
+	return statuses, nil
+}
+
+// lockDriverStatus serializes the updates of the driver status document, which are read-modify-write
+// cycles of the whole document, through a lock file next to it
+func lockDriverStatus(statusFile string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(statusFile), 0755); err != nil {
+		return nil, err
+	}
+	lockFile := statusFile + ".lock"
+	f, err := os.OpenFile(lockFile, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock %s: %v", lockFile, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// updateDriverStatus applies update to an existing driver status document under the status lock
+func updateDriverStatus(statusFile string, update func(status *DriverStatus)) error {
+	unlock, err := lockDriverStatus(statusFile)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	status, err := LoadDriverStatus(statusFile)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		// the driver container has not started, or runs without 'vgpu-util status set'
+		log.Warnf("No driver status document %s to update", statusFile)
+		return nil
+	}
+	update(status)
+	return SaveDriverStatus(statusFile, status)
+}
+
+// updateDriverStatusDaemons refreshes the daemon health of an existing driver status document
+func updateDriverStatusDaemons(statusFile string, daemons []DaemonStatus) error {
+	return updateDriverStatus(statusFile, func(status *DriverStatus) {
+		status.Daemons = daemons
+	})
+}
+
+// updateDriverStatusKernelUpdate records the outcome of the last kernel update hook in an existing driver status document
+func updateDriverStatusKernelUpdate(statusFile string, result *KernelUpdateResult) error {
+	return updateDriverStatus(statusFile, func(status *DriverStatus) {
+		status.KernelUpdate = result
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentStatusUpdates checks the daemon and kernel hook updates never bring back a
+// phase set concurrently by 'status set'
+func TestConcurrentStatusUpdates(t *testing.T) {
+	dir := t.TempDir()
+	statusFile := filepath.Join(dir, "validations", "driver-status.json")
+	opts := &statusOptions{statusFile: statusFile, sysfsRoot: dir, daemonStatusFile: filepath.Join(dir, "daemons.json")}
+
+	opts.phase = string(PhaseLoad)
+	if err := SetStatus(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- updateDriverStatusDaemons(statusFile, []DaemonStatus{{Name: "nvidia-persistenced", State: DaemonRunning, Restarts: i}})
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			errs <- updateDriverStatusKernelUpdate(statusFile, &KernelUpdateResult{KernelRelease: fmt.Sprintf("6.8.0-%d-generic", i)})
+		}(i)
+	}
+
+	opts.phase = string(PhaseReady)
+	if err := SetStatus(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	status, err := LoadDriverStatus(statusFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Phase != PhaseReady {
+		t.Errorf("expected phase %s, got %s", PhaseReady, status.Phase)
+	}
+	if len(status.Daemons) != 1 || status.KernelUpdate == nil {
+		t.Errorf("concurrent updates lost: %+v", status)
+	}
+}
+
+func TestUpdateMissingDriverStatus(t *testing.T) {
+	statusFile := filepath.Join(t.TempDir(), "driver-status.json")
+	if err := updateDriverStatusDaemons(statusFile, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, err := LoadDriverStatus(statusFile); status != nil || err != nil {
+		t.Errorf("expected no driver status to be created, got %+v (%v)", status, err)
+	}
+}
+
+// TestClearStatus checks a restarted driver container doesn't report the ready phase of its previous run
+func TestClearStatus(t *testing.T) {
+	dir := t.TempDir()
+	statusFile := filepath.Join(dir, "validations", "driver-status.json")
+	opts := &statusOptions{statusFile: statusFile, sysfsRoot: dir, daemonStatusFile: filepath.Join(dir, "daemons.json")}
+
+	opts.phase = string(PhaseReady)
+	if err := SetStatus(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		// clearing a missing document is not an error
+		if err := ClearStatus(testContext(), opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if status, err := LoadDriverStatus(statusFile); status != nil || err != nil {
+		t.Fatalf("expected the driver status to be cleared, got %+v (%v)", status, err)
+	}
+
+	opts.phase = string(PhasePrepare)
+	if err := SetStatus(testContext(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts.phase = string(PhaseReady)
+	opts.timeout = statusWaitInterval
+	if err := WaitStatus(testContext(), opts); err == nil {
+		t.Errorf("expected the wait for phase %s to time out", PhaseReady)
+	}
+}
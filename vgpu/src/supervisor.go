@@ -0,0 +1,507 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultSupervisorStatusFile indicates default location of the per-daemon status written by the supervisor
+	DefaultSupervisorStatusFile = "/run/nvidia/daemons.json"
+	// DefaultRestartBackoff indicates default delay before restarting a daemon that exited
+	DefaultRestartBackoff = time.Second
+	// DefaultMaxRestartBackoff indicates default maximum delay before restarting a daemon
+	DefaultMaxRestartBackoff = time.Minute
+	// DefaultPIDFileTimeout indicates default time to wait for a daemon to write its pidfile
+	DefaultPIDFileTimeout = 10 * time.Second
+	// supervisorPollInterval is the interval between two liveness checks of the daemons
+	supervisorPollInterval = time.Second
+)
+
+// NvidiaDaemon describes a daemon started by the driver container
+type NvidiaDaemon struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	PIDFile     string   `yaml:"pidfile,omitempty"`
+	Command     []string `yaml:"command,omitempty"`
+	Env         []string `yaml:"env,omitempty"`
+	// Foreground daemons don't fork and are tracked as child processes instead of through their pidfile
+	Foreground bool `yaml:"foreground,omitempty"`
+}
+
+// nvidiaDaemons lists the daemons managed by the driver container, in start order.
+// Daemons without command are started by other means and only stopped.
+var nvidiaDaemons = []NvidiaDaemon{
+	{
+		Name:        "nvidia-persistenced",
+		Description: "NVIDIA persistence daemon",
+		PIDFile:     "/var/run/nvidia-persistenced/nvidia-persistenced.pid",
+		Command:     []string{"nvidia-persistenced", "--persistence-mode"},
+	},
+	{
+		Name:        "nvidia-gridd",
+		Description: "NVIDIA grid daemon",
+		PIDFile:     "/var/run/nvidia-gridd/nvidia-gridd.pid",
+		Command:     []string{"nvidia-gridd"},
+		Env:         []string{"LD_LIBRARY_PATH=/usr/lib/${DRIVER_ARCH}-linux-gnu/nvidia/gridd"},
+	},
+	{
+		Name:        "nvidia-topologyd",
+		Description: "NVIDIA topology daemon",
+		PIDFile:     "/var/run/nvidia-topologyd/nvidia-topologyd.pid",
+		Command:     []string{"nvidia-topologyd"},
+	},
+	{
+		Name:        "nv-fabricmanager",
+		Description: "NVIDIA fabric manager daemon",
+		PIDFile:     "/var/run/nvidia-fabricmanager/nv-fabricmanager.pid",
+		Command:     []string{"nv-fabricmanager", "-c", "/usr/share/nvidia/nvswitch/fabricmanager.cfg"},
+	},
+	{
+		Name:        "nvlsm",
+		Description: "NVLink Subnet Manager daemon",
+		PIDFile:     "/var/run/nvidia-fabricmanager/nvlsm.pid",
+	},
+}
+
+// DaemonState is the state of a supervised daemon
+type DaemonState string
+
+const (
+	// DaemonStarting is the state of a daemon until its pid is known
+	DaemonStarting DaemonState = "starting"
+	// DaemonRunning is the state of a running daemon
+	DaemonRunning DaemonState = "running"
+	// DaemonBackoff is the state of an exited daemon waiting to be restarted
+	DaemonBackoff DaemonState = "backoff"
+	// DaemonStopped is the state of a daemon stopped by the supervisor
+	DaemonStopped DaemonState = "stopped"
+)
+
+// DaemonStatus is the status of a supervised daemon
+type DaemonStatus struct {
+	Name      string      `json:"name"`
+	State     DaemonState `json:"state"`
+	PID       int         `json:"pid,omitempty"`
+	Restarts  int         `json:"restarts"`
+	LastError string      `json:"lastError,omitempty"`
+	StartedAt *time.Time  `json:"startedAt,omitempty"`
+	ExitedAt  *time.Time  `json:"exitedAt,omitempty"`
+}
+
+// supervisedDaemon tracks a daemon started by the supervisor
+type supervisedDaemon struct {
+	NvidiaDaemon
+	status    DaemonStatus
+	backoff   time.Duration
+	restartAt time.Time
+	// exited is closed when a foreground daemon exits
+	exited chan struct{}
+}
+
+type supervisorOptions struct {
+	daemons          cli.StringSlice
+	configFile       string
+	statusFile       string
+	driverStatusFile string
+	procRoot         string
+	backoff          time.Duration
+	maxBackoff       time.Duration
+	pidFileTimeout   time.Duration
+	stopTimeout      time.Duration
+}
+
+func newSupervisorCommand() *cli.Command {
+	opts := supervisorOptions{}
+
+	statusFileFlag := &cli.StringFlag{
+		Name:        "status-file",
+		Usage:       "File the per-daemon status is written to",
+		Value:       DefaultSupervisorStatusFile,
+		Destination: &opts.statusFile,
+		EnvVars:     []string{"SUPERVISOR_STATUS_FILE"},
+	}
+
+	// Create the 'supervise' subcommand
+	supervise := cli.Command{}
+	supervise.Name = "supervise"
+	supervise.Usage = "Start the enabled NVIDIA daemons and restart them with backoff when they exit"
+	supervise.UsageText = "[-d | --daemon]... [-f | --config] [--status-file] [--backoff] [--max-backoff]"
+	supervise.Action = func(c *cli.Context) error {
+		return Supervise(c, &opts)
+	}
+	supervise.Flags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "daemon",
+			Aliases:     []string{"d"},
+			Usage:       "Name of a daemon to supervise, in start order",
+			Destination: &opts.daemons,
+			EnvVars:     []string{"SUPERVISED_DAEMONS"},
+		},
+		&cli.StringFlag{
+			Name:        "config",
+			Aliases:     []string{"f"},
+			Usage:       "YAML file with daemon definitions overriding the built-in table",
+			Destination: &opts.configFile,
+		},
+		statusFileFlag,
+		&cli.StringFlag{
+			Name:        "driver-status-file",
+			Usage:       "Driver status document to keep the daemon health up to date in, if it exists",
+			Value:       DefaultDriverStatusFile,
+			Destination: &opts.driverStatusFile,
+			EnvVars:     []string{"DRIVER_STATUS_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:        "backoff",
+			Usage:       "Delay before the first restart of a daemon, doubled on each consecutive restart",
+			Value:       DefaultRestartBackoff,
+			Destination: &opts.backoff,
+		},
+		&cli.DurationFlag{
+			Name:        "max-backoff",
+			Usage:       "Maximum delay before restarting a daemon",
+			Value:       DefaultMaxRestartBackoff,
+			Destination: &opts.maxBackoff,
+		},
+		&cli.DurationFlag{
+			Name:        "pidfile-timeout",
+			Usage:       "Time to wait for a daemon to write its pidfile",
+			Value:       DefaultPIDFileTimeout,
+			Destination: &opts.pidFileTimeout,
+		},
+		&cli.DurationFlag{
+			Name:        "stop-timeout",
+			Usage:       "Time to wait for each daemon to exit on shutdown",
+			Value:       DefaultDaemonStopTimeout,
+			Destination: &opts.stopTimeout,
+		},
+		&cli.StringFlag{
+			Name:        "proc-root",
+			Usage:       "Mount point of procfs",
+			Value:       DefaultProcRoot,
+			Destination: &opts.procRoot,
+		},
+	}
+
+	// Create the 'supervise status' subcommand
+	status := cli.Command{}
+	status.Name = "status"
+	status.Usage = "Print the status of the supervised daemons"
+	status.Action = func(c *cli.Context) error {
+		return SupervisorStatus(c, &opts)
+	}
+	status.Flags = []cli.Flag{statusFileFlag}
+
+	supervise.Subcommands = []*cli.Command{&status}
+	return &supervise
+}
+
+// Supervise starts the enabled daemons, restarts them when they exit and stops them on SIGTERM
+func Supervise(c *cli.Context, opts *supervisorOptions) error {
+	log.Infof("Starting 'supervise' with %v", c.App.Name)
+
+	table := nvidiaDaemons
+	if opts.configFile != "" {
+		var err error
+		if table, err = loadDaemonTable(opts.configFile); err != nil {
+			return err
+		}
+	}
+
+	var daemons []*supervisedDaemon
+	for _, name := range opts.daemons.Value() {
+		daemon, err := findDaemon(table, name)
+		if err != nil {
+			return err
+		}
+		daemons = append(daemons, &supervisedDaemon{NvidiaDaemon: daemon, backoff: opts.backoff, status: DaemonStatus{Name: daemon.Name}})
+	}
+	if len(daemons) == 0 {
+		return fmt.Errorf("no daemon to supervise")
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+
+	for _, daemon := range daemons {
+		startSupervisedDaemon(daemon, opts)
+	}
+	writeSupervisorStatus(opts, daemons)
+
+	ticker := time.NewTicker(supervisorPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			fmt.Printf("Caught signal %v, stopping daemons\n", sig)
+			var errs []string
+			// stop in reverse start order
+			for i := len(daemons) - 1; i >= 0; i-- {
+				if err := stopSupervisedDaemon(daemons[i], opts); err != nil {
+					log.Errorf("%v", err)
+					errs = append(errs, err.Error())
+				}
+			}
+			writeSupervisorStatus(opts, daemons)
+			if len(errs) > 0 {
+				return fmt.Errorf("%s", strings.Join(errs, "; "))
+			}
+			log.Infof("Completed 'supervise' with %v", c.App.Name)
+			return nil
+		case <-ticker.C:
+		}
+
+		changed := false
+		for _, daemon := range daemons {
+			if checkSupervisedDaemon(daemon, opts) {
+				changed = true
+			}
+		}
+		if changed {
+			writeSupervisorStatus(opts, daemons)
+		}
+	}
+}
+
+// checkSupervisedDaemon restarts a daemon that exited once its backoff expired, and returns true if its status changed
+func checkSupervisedDaemon(daemon *supervisedDaemon, opts *supervisorOptions) bool {
+	now := time.Now()
+	switch daemon.status.State {
+	case DaemonRunning:
+		if daemonAlive(daemon, opts.procRoot) {
+			// reset the backoff once the daemon has been running for a while
+			if daemon.status.StartedAt != nil && now.Sub(*daemon.status.StartedAt) > opts.maxBackoff {
+				daemon.backoff = opts.backoff
+			}
+			return false
+		}
+		log.Warnf("%s (pid %d) exited, restarting in %v", daemon.Name, daemon.status.PID, daemon.backoff)
+		fmt.Printf("%s exited, restarting in %v\n", daemon.Description, daemon.backoff)
+		daemon.status.State = DaemonBackoff
+		daemon.status.ExitedAt = &now
+		daemon.status.LastError = fmt.Sprintf("exited at %s", now.UTC().Format(time.RFC3339))
+		daemon.restartAt = now.Add(daemon.backoff)
+		return true
+	case DaemonBackoff:
+		if now.Before(daemon.restartAt) {
+			return false
+		}
+		daemon.status.Restarts++
+		startSupervisedDaemon(daemon, opts)
+		return true
+	}
+	return false
+}
+
+// startSupervisedDaemon starts a daemon and waits for its pid. Failures put the daemon in backoff.
+func startSupervisedDaemon(daemon *supervisedDaemon, opts *supervisorOptions) {
+	daemon.status.State = DaemonStarting
+	fmt.Printf("Starting %s...\n", daemon.Description)
+
+	pid, err := launchDaemon(daemon, opts)
+	now := time.Now()
+	if err != nil {
+		log.Errorf("unable to start %s: %v", daemon.Name, err)
+		daemon.status.State = DaemonBackoff
+		daemon.status.PID = 0
+		daemon.status.LastError = err.Error()
+		daemon.status.ExitedAt = &now
+		daemon.restartAt = now.Add(daemon.backoff)
+		daemon.backoff = min(2*daemon.backoff, opts.maxBackoff)
+		return
+	}
+	log.Infof("started %s (pid %d)", daemon.Name, pid)
+	daemon.status.State = DaemonRunning
+	daemon.status.PID = pid
+	daemon.status.StartedAt = &now
+	if daemon.status.Restarts > 0 {
+		daemon.backoff = min(2*daemon.backoff, opts.maxBackoff)
+	}
+}
+
+func launchDaemon(daemon *supervisedDaemon, opts *supervisorOptions) (int, error) {
+	if len(daemon.Command) == 0 {
+		return 0, fmt.Errorf("daemon %s has no command", daemon.Name)
+	}
+	cmd := exec.Command(daemon.Command[0], daemon.Command[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	for _, env := range daemon.Env {
+		cmd.Env = append(cmd.Env, os.ExpandEnv(env))
+	}
+
+	if daemon.PIDFile != "" {
+		// a stale pidfile would be mistaken for the new daemon
+		os.Remove(daemon.PIDFile)
+	}
+
+	if daemon.Foreground {
+		if err := cmd.Start(); err != nil {
+			return 0, err
+		}
+		daemon.exited = make(chan struct{})
+		go func(exited chan struct{}) {
+			err := cmd.Wait()
+			log.Infof("%s exited: %v", daemon.Name, err)
+			close(exited)
+		}(daemon.exited)
+		return cmd.Process.Pid, nil
+	}
+
+	// the command forks the daemon and exits
+	if err := cmd.Run(); err != nil {
+		return 0, err
+	}
+	if daemon.PIDFile == "" {
+		return 0, fmt.Errorf("daemon %s has no pidfile to track it", daemon.Name)
+	}
+	deadline := time.Now().Add(opts.pidFileTimeout)
+	for {
+		pid, err := readPIDFile(daemon.PIDFile)
+		if err == nil && processRunning(opts.procRoot, pid) {
+			return pid, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("no running process found in %s after %v", daemon.PIDFile, opts.pidFileTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func daemonAlive(daemon *supervisedDaemon, procRoot string) bool {
+	if daemon.Foreground {
+		select {
+		case <-daemon.exited:
+			return false
+		default:
+			return true
+		}
+	}
+	return processRunning(procRoot, daemon.status.PID)
+}
+
+func stopSupervisedDaemon(daemon *supervisedDaemon, opts *supervisorOptions) error {
+	defer func() { daemon.status.State = DaemonStopped }()
+	if daemon.status.State != DaemonRunning || !daemonAlive(daemon, opts.procRoot) {
+		return nil
+	}
+	fmt.Printf("Stopping %s...\n", daemon.Description)
+	if err := terminateProcess(opts.procRoot, daemon.status.PID, opts.stopTimeout); err != nil {
+		return fmt.Errorf("Could not stop %s: %v", daemon.Description, err)
+	}
+	if daemon.Foreground {
+		<-daemon.exited
+	}
+	return nil
+}
+
+// terminateProcess sends SIGTERM to a process and waits for it to exit
+func terminateProcess(procRoot string, pid int, timeout time.Duration) error {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("unable to signal pid %d: %v", pid, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for processRunning(procRoot, pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pid %d still running after %v", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+func findDaemon(table []NvidiaDaemon, name string) (NvidiaDaemon, error) {
+	for _, daemon := range table {
+		if daemon.Name == name {
+			if daemon.Description == "" {
+				daemon.Description = daemon.Name
+			}
+			return daemon, nil
+		}
+	}
+	return NvidiaDaemon{}, fmt.Errorf("unknown daemon %s", name)
+}
+
+func loadDaemonTable(configFile string) ([]NvidiaDaemon, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read daemon config file %s: %v", configFile, err)
+	}
+	var table []NvidiaDaemon
+	if err := yaml.UnmarshalStrict(data, &table); err != nil {
+		return nil, fmt.Errorf("Error un-marshalling daemon config file: %v", err)
+	}
+	return table, nil
+}
+
+func writeSupervisorStatus(opts *supervisorOptions, daemons []*supervisedDaemon) {
+	statuses := make([]DaemonStatus, 0, len(daemons))
+	for _, daemon := range daemons {
+		statuses = append(statuses, daemon.status)
+	}
+	if opts.statusFile != "" {
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err == nil {
+			err = writeFileAtomic(opts.statusFile, append(data, '\n'), 0644)
+		}
+		if err != nil {
+			log.Errorf("unable to write daemon status: %v", err)
+		}
+	}
+	if opts.driverStatusFile != "" {
+		if err := updateDriverStatusDaemons(opts.driverStatusFile, statuses); err != nil {
+			log.Errorf("unable to update driver status: %v", err)
+		}
+	}
+}
+
+// SupervisorStatus prints the status of the supervised daemons
+func SupervisorStatus(c *cli.Context, opts *supervisorOptions) error {
+	data, err := os.ReadFile(opts.statusFile)
+	if err != nil {
+		return fmt.Errorf("unable to read daemon status: %v", err)
+	}
+	var statuses []DaemonStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", filepath.Base(opts.statusFile), err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tPID\tRESTARTS\tLAST ERROR")
+	for _, status := range statuses {
+		pid := "-"
+		if status.PID > 0 && status.State == DaemonRunning {
+			pid = fmt.Sprint(status.PID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", status.Name, status.State, pid, status.Restarts, orDash(status.LastError))
+	}
+	return w.Flush()
+}
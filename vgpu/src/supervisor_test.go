@@ -0,0 +1,183 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeStub writes an executable shell script standing in for a daemon
+func writeStub(t *testing.T, dir string, name string, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testSupervisorOptions() *supervisorOptions {
+	return &supervisorOptions{
+		procRoot:       "/proc",
+		backoff:        10 * time.Millisecond,
+		maxBackoff:     40 * time.Millisecond,
+		pidFileTimeout: 500 * time.Millisecond,
+		stopTimeout:    time.Second,
+	}
+}
+
+func newTestDaemon(opts *supervisorOptions, daemon NvidiaDaemon) *supervisedDaemon {
+	return &supervisedDaemon{NvidiaDaemon: daemon, backoff: opts.backoff, status: DaemonStatus{Name: daemon.Name}}
+}
+
+// waitForState runs the supervisor checks until the daemon reaches the state
+func waitForState(t *testing.T, daemon *supervisedDaemon, opts *supervisorOptions, state DaemonState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for daemon.status.State != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: expected state %s, still %s", daemon.Name, state, daemon.status.State)
+		}
+		checkSupervisedDaemon(daemon, opts)
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSupervisorRestartsForegroundDaemon(t *testing.T) {
+	opts := testSupervisorOptions()
+	stub := writeStub(t, t.TempDir(), "crashing-daemon", "sleep 0.05; exit 1")
+	daemon := newTestDaemon(opts, NvidiaDaemon{Name: "crashing-daemon", Command: []string{stub}, Foreground: true})
+
+	startSupervisedDaemon(daemon, opts)
+	if daemon.status.State != DaemonRunning || daemon.status.PID == 0 {
+		t.Fatalf("expected a running daemon, got %+v", daemon.status)
+	}
+
+	waitForState(t, daemon, opts, DaemonBackoff)
+	if daemon.status.ExitedAt == nil || daemon.status.LastError == "" {
+		t.Errorf("exit not recorded: %+v", daemon.status)
+	}
+	if wait := daemon.restartAt.Sub(*daemon.status.ExitedAt); wait != opts.backoff {
+		t.Errorf("expected a restart after %v, got %v", opts.backoff, wait)
+	}
+
+	waitForState(t, daemon, opts, DaemonRunning)
+	if daemon.status.Restarts != 1 {
+		t.Errorf("expected 1 restart, got %d", daemon.status.Restarts)
+	}
+	if daemon.backoff != 2*opts.backoff {
+		t.Errorf("expected the backoff to double to %v, got %v", 2*opts.backoff, daemon.backoff)
+	}
+
+	// a daemon running longer than the maximum backoff gets the initial backoff back
+	started := time.Now().Add(-2 * opts.maxBackoff)
+	daemon.status.StartedAt = &started
+	daemon.exited = make(chan struct{})
+	checkSupervisedDaemon(daemon, opts)
+	if daemon.backoff != opts.backoff {
+		t.Errorf("expected the backoff to be reset to %v, got %v", opts.backoff, daemon.backoff)
+	}
+}
+
+func TestSupervisorBackoffOnStartFailure(t *testing.T) {
+	opts := testSupervisorOptions()
+	daemon := newTestDaemon(opts, NvidiaDaemon{Name: "missing-daemon", Command: []string{filepath.Join(t.TempDir(), "missing")}})
+
+	for _, expected := range []time.Duration{20, 40, 40, 40} {
+		startSupervisedDaemon(daemon, opts)
+		if daemon.status.State != DaemonBackoff || daemon.status.LastError == "" {
+			t.Fatalf("expected a daemon in backoff, got %+v", daemon.status)
+		}
+		if daemon.backoff != expected*time.Millisecond {
+			t.Errorf("expected a backoff of %v, got %v", expected*time.Millisecond, daemon.backoff)
+		}
+	}
+
+	// no restart before the backoff expired
+	daemon.restartAt = time.Now().Add(time.Hour)
+	if checkSupervisedDaemon(daemon, opts) || daemon.status.Restarts != 0 {
+		t.Errorf("daemon restarted before its backoff expired")
+	}
+	daemon.restartAt = time.Now()
+	if !checkSupervisedDaemon(daemon, opts) || daemon.status.Restarts != 1 {
+		t.Errorf("daemon not restarted once its backoff expired")
+	}
+}
+
+func TestSupervisorForkingDaemon(t *testing.T) {
+	opts := testSupervisorOptions()
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "daemon.pid")
+	// fork a long running process, record its pid and exit, as the NVIDIA daemons do
+	stub := writeStub(t, dir, "forking-daemon", "sleep 30 >/dev/null 2>&1 &\necho $! > "+pidFile)
+	daemon := newTestDaemon(opts, NvidiaDaemon{Name: "forking-daemon", Command: []string{stub}, PIDFile: pidFile})
+
+	// a stale pidfile is not mistaken for the new daemon
+	os.WriteFile(pidFile, []byte("1\n"), 0644)
+	startSupervisedDaemon(daemon, opts)
+	if daemon.status.State != DaemonRunning || daemon.status.PID <= 1 {
+		t.Fatalf("expected a running daemon, got %+v", daemon.status)
+	}
+	pid := daemon.status.PID
+	defer syscall.Kill(pid, syscall.SIGKILL)
+
+	if checkSupervisedDaemon(daemon, opts) {
+		t.Errorf("status of a running daemon changed")
+	}
+	if err := stopSupervisedDaemon(daemon, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if daemon.status.State != DaemonStopped || processRunning(opts.procRoot, pid) {
+		t.Errorf("daemon not stopped: %+v", daemon.status)
+	}
+}
+
+func TestSupervisorMissingPIDFile(t *testing.T) {
+	opts := testSupervisorOptions()
+	dir := t.TempDir()
+	stub := writeStub(t, dir, "silent-daemon", "exit 0")
+	daemon := newTestDaemon(opts, NvidiaDaemon{Name: "silent-daemon", Command: []string{stub}, PIDFile: filepath.Join(dir, "silent.pid")})
+
+	startSupervisedDaemon(daemon, opts)
+	if daemon.status.State != DaemonBackoff {
+		t.Errorf("expected a daemon in backoff without a pidfile, got %+v", daemon.status)
+	}
+}
+
+func TestLoadDaemonTable(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	os.WriteFile(valid, []byte("- name: nvidia-imex\n  command: [nvidia-imex, -c, /etc/nvidia-imex/config.cfg]\n  foreground: true\n"), 0644)
+	table, err := loadDaemonTable(valid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	daemon, err := findDaemon(table, "nvidia-imex")
+	if err != nil || !daemon.Foreground || len(daemon.Command) != 3 || daemon.Description != "nvidia-imex" {
+		t.Errorf("unexpected daemon %+v (%v)", daemon, err)
+	}
+	if _, err := findDaemon(table, "nvidia-persistenced"); err == nil {
+		t.Errorf("expected an error for a daemon missing from the table")
+	}
+
+	unknown := filepath.Join(dir, "unknown.yaml")
+	os.WriteFile(unknown, []byte("- name: nvidia-imex\n  restart: always\n"), 0644)
+	if _, err := loadDaemonTable(unknown); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
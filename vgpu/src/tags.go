@@ -0,0 +1,298 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+// defaultDriverRepositories are searched for the precompiled images, an image in either is not rebuilt
+var defaultDriverRepositories = []string{"nvcr.io/nvidia/driver", "ghcr.io/nvidia/driver"}
+
+// KernelVersionEntry is the kernel version to build for the matrix combinations it matches,
+// an empty field matches any value
+type KernelVersionEntry struct {
+	DriverBranch  string `json:"driver_branch,omitempty"`
+	Flavor        string `json:"flavor"`
+	Dist          string `json:"dist"`
+	LTSKernel     string `json:"lts_kernel"`
+	Platform      string `json:"platform,omitempty"`
+	KernelVersion string `json:"kernel_version"`
+}
+
+func (e *KernelVersionEntry) matches(combination map[string]string) bool {
+	for dimension, value := range map[string]string{
+		DimensionDriverBranch: e.DriverBranch,
+		DimensionFlavor:       e.Flavor,
+		DimensionDist:         e.Dist,
+		DimensionLTSKernel:    e.LTSKernel,
+		DimensionPlatform:     e.Platform,
+	} {
+		if value != "" && combination[dimension] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// PlannedImage is a precompiled image expected for a matrix combination
+type PlannedImage struct {
+	DriverBranch  string `json:"driver_branch"`
+	Flavor        string `json:"flavor"`
+	Dist          string `json:"dist"`
+	LTSKernel     string `json:"lts_kernel"`
+	Platform      string `json:"platform"`
+	KernelVersion string `json:"kernel_version"`
+	Tag           string `json:"tag"`
+	// Repository is where the image was found, empty if it is missing
+	Repository string `json:"repository,omitempty"`
+}
+
+// TagPlan lists the expected images found in the repositories and the missing ones to build
+type TagPlan struct {
+	Present []PlannedImage `json:"present"`
+	Missing []PlannedImage `json:"missing"`
+	// Unresolved lists the combinations no kernel version was given for
+	Unresolved []map[string]string `json:"unresolved,omitempty"`
+}
+
+type tagsOptions struct {
+	configFile   string
+	kernelsFile  string
+	repositories cli.StringSlice
+	plainHTTP    bool
+	username     string
+	password     string
+	output       string
+}
+
+func newTagsCommand() *cli.Command {
+	opts := tagsOptions{}
+
+	// Create the 'tags plan' subcommand
+	plan := cli.Command{}
+	plan.Name = "plan"
+	plan.Usage = "Compute the precompiled image tags of the kernel matrix and print the ones missing from the registries"
+	plan.UsageText = "--kernels [-c | --config] [--repository] [--plain-http] [--username] [--password] [-o | --output]"
+	plan.Action = func(c *cli.Context) error {
+		return PlanTags(c, &opts)
+	}
+	plan.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Aliases:     []string{"c"},
+			Usage:       "Build matrix config",
+			Value:       DefaultMatrixConfig,
+			Destination: &opts.configFile,
+			EnvVars:     []string{"MATRIX_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "kernels",
+			Aliases:     []string{"k"},
+			Usage:       "JSON list of {flavor, dist, lts_kernel, kernel_version}, optionally restricted to a driver_branch or platform",
+			Required:    true,
+			Destination: &opts.kernelsFile,
+		},
+		&cli.StringSliceFlag{
+			Name:        "repository",
+			Usage:       fmt.Sprintf("Repository searched for the images, %s if not set", strings.Join(defaultDriverRepositories, " and ")),
+			Destination: &opts.repositories,
+		},
+		&cli.BoolFlag{
+			Name:        "plain-http",
+			Usage:       "Reach the registries over http, e.g. a local test registry",
+			Destination: &opts.plainHTTP,
+		},
+		&cli.StringFlag{
+			Name:        "username",
+			Destination: &opts.username,
+			EnvVars:     []string{"REGISTRY_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:        "password",
+			Destination: &opts.password,
+			EnvVars:     []string{"REGISTRY_PASSWORD"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, github for a strategy.matrix including the missing images, or json for the whole plan",
+			Value:       "github",
+			Destination: &opts.output,
+		},
+	}
+
+	tags := cli.Command{}
+	tags.Name = "tags"
+	tags.Usage = "Plan the precompiled driver images to build"
+	tags.Subcommands = []*cli.Command{&plan}
+	return &tags
+}
+
+// PlanTags prints the images of the kernel matrix missing from every repository
+func PlanTags(c *cli.Context, opts *tagsOptions) error {
+	log.Infof("Starting 'tags plan' with %v", c.App.Name)
+
+	config, err := LoadMatrixConfig(opts.configFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(opts.kernelsFile)
+	if err != nil {
+		return fmt.Errorf("unable to read kernel versions: %v", err)
+	}
+	var kernels []KernelVersionEntry
+	if err := json.Unmarshal(data, &kernels); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", opts.kernelsFile, err)
+	}
+
+	repositories := opts.repositories.Value()
+	if len(repositories) == 0 {
+		repositories = defaultDriverRepositories
+	}
+	clients := map[string]RegistryClient{}
+	for _, repository := range repositories {
+		host, _, err := splitRepository(repository)
+		if err != nil {
+			return err
+		}
+		if _, ok := clients[host]; !ok {
+			clients[host] = NewOCIRegistryClient(host, opts.plainHTTP, opts.username, opts.password)
+		}
+	}
+
+	plan, err := ComputeTagPlan(c.Context, config, kernels, repositories, func(repository string) RegistryClient {
+		host, _, _ := splitRepository(repository)
+		return clients[host]
+	})
+	if err != nil {
+		return err
+	}
+	for _, combination := range plan.Unresolved {
+		log.Warnf("no kernel version for %v", combination)
+	}
+
+	var output interface{}
+	switch opts.output {
+	case "github":
+		output = map[string]interface{}{"include": plan.Missing}
+		data, err = json.Marshal(output)
+	case "json":
+		data, err = json.MarshalIndent(plan, "", "  ")
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	log.Infof("Completed 'tags plan' with %v, %d images present, %d missing", c.App.Name, len(plan.Present), len(plan.Missing))
+	return nil
+}
+
+// ComputeTagPlan expands the kernel matrix into the expected images, tagged
+// <driver_branch>-<kernel_version>-<dist>, and looks each one up for its platform in the
+// repositories with a manifest request of its tag, the repositories are not listed. A registry
+// error fails the plan, rather than rebuilding every image.
+func ComputeTagPlan(ctx context.Context, config *MatrixConfig, kernels []KernelVersionEntry, repositories []string, clientFor func(repository string) RegistryClient) (*TagPlan, error) {
+	plan := &TagPlan{Present: []PlannedImage{}, Missing: []PlannedImage{}}
+
+	var expected []PlannedImage
+	seen := map[string]bool{}
+	for _, combination := range config.Expand("kernel") {
+		resolved := false
+		for _, kernel := range kernels {
+			if !kernel.matches(combination) {
+				continue
+			}
+			resolved = true
+			image := PlannedImage{
+				DriverBranch:  combination[DimensionDriverBranch],
+				Flavor:        combination[DimensionFlavor],
+				Dist:          combination[DimensionDist],
+				LTSKernel:     combination[DimensionLTSKernel],
+				Platform:      combination[DimensionPlatform],
+				KernelVersion: kernel.KernelVersion,
+			}
+			image.Tag = fmt.Sprintf("%s-%s-%s", image.DriverBranch, image.KernelVersion, image.Dist)
+			if key := image.Tag + "@" + image.Platform; !seen[key] {
+				seen[key] = true
+				expected = append(expected, image)
+			}
+		}
+		if !resolved {
+			plan.Unresolved = append(plan.Unresolved, combination)
+		}
+	}
+
+	platforms := map[string][]string{}
+	for _, image := range expected {
+		for _, repository := range repositories {
+			_, path, err := splitRepository(repository)
+			if err != nil {
+				return nil, err
+			}
+
+			// the platforms of a tag are looked up once, an image per platform shares it
+			reference := repository + ":" + image.Tag
+			if _, ok := platforms[reference]; !ok {
+				p, err := clientFor(repository).ManifestPlatforms(ctx, path, image.Tag)
+				if err != nil && !errors.Is(err, ErrManifestNotFound) {
+					return nil, fmt.Errorf("unable to inspect %s: %v", reference, err)
+				}
+				platforms[reference] = p
+			}
+			if hasPlatform(platforms[reference], image.Platform) {
+				image.Repository = repository
+				break
+			}
+		}
+
+		if image.Repository != "" {
+			plan.Present = append(plan.Present, image)
+		} else {
+			plan.Missing = append(plan.Missing, image)
+		}
+	}
+	return plan, nil
+}
+
+// hasPlatform reports whether the platforms of an image include linux/<architecture>, with
+// any variant, e.g. linux/arm64/v8
+func hasPlatform(platforms []string, architecture string) bool {
+	for _, platform := range platforms {
+		if platform == "linux/"+architecture || strings.HasPrefix(platform, "linux/"+architecture+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRepository splits nvcr.io/nvidia/driver into its registry host and repository path
+func splitRepository(repository string) (string, string, error) {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) != 2 || parts[1] == "" || !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "", "", fmt.Errorf("invalid repository %q, expected <registry>/<path>", repository)
+	}
+	return parts[0], parts[1], nil
+}
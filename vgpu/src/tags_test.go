@@ -0,0 +1,162 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// memoryRegistry is a RegistryClient of a single repository, the images are named tag@platform
+type memoryRegistry struct {
+	images []string
+	err    error
+	// lists and manifests count the ListTags and ManifestPlatforms calls
+	lists     int
+	manifests int
+}
+
+func (m *memoryRegistry) ListTags(ctx context.Context, repository string) ([]string, error) {
+	m.lists++
+	if m.err != nil {
+		return nil, m.err
+	}
+	var tags []string
+	for _, image := range m.images {
+		tags = append(tags, strings.Split(image, "@")[0])
+	}
+	return tags, nil
+}
+
+func (m *memoryRegistry) ManifestPlatforms(ctx context.Context, repository string, tag string) ([]string, error) {
+	m.manifests++
+	if m.err != nil {
+		return nil, m.err
+	}
+	var platforms []string
+	for _, image := range m.images {
+		if parts := strings.Split(image, "@"); parts[0] == tag && len(parts) == 2 {
+			platforms = append(platforms, parts[1])
+		}
+	}
+	if len(platforms) == 0 {
+		return nil, ErrManifestNotFound
+	}
+	return platforms, nil
+}
+
+func TestComputeTagPlan(t *testing.T) {
+	config := &MatrixConfig{
+		DriverBranches:          []string{"580", "595"},
+		KernelFlavors:           []string{"generic", "aws"},
+		Dists:                   []string{"ubuntu24.04"},
+		LTSKernels:              []string{"6.8"},
+		Platforms:               []string{"amd64", "arm64"},
+		ExcludePrecompiledBuild: []MatrixRule{{DimensionDriverBranch: "595", DimensionFlavor: "aws"}},
+		PlatformConstraints:     map[string]PlatformConstraint{"arm64": {ExcludeFlavors: []string{"aws"}}},
+	}
+	kernels := []KernelVersionEntry{
+		{Flavor: "generic", Dist: "ubuntu24.04", LTSKernel: "6.8", KernelVersion: "6.8.0-60-generic"},
+		// a newer kernel for a single driver branch
+		{DriverBranch: "595", Flavor: "generic", Dist: "ubuntu24.04", LTSKernel: "6.8", KernelVersion: "6.8.0-62-generic"},
+	}
+	nvcr := &memoryRegistry{images: []string{
+		"580-6.8.0-60-generic-ubuntu24.04@linux/amd64",
+		// a tag without manifest
+		"595-6.8.0-60-generic-ubuntu24.04",
+	}}
+	ghcr := &memoryRegistry{images: []string{
+		"580-6.8.0-60-generic-ubuntu24.04@linux/amd64",
+		"580-6.8.0-60-generic-ubuntu24.04@linux/arm64",
+		"595-6.8.0-62-generic-ubuntu24.04@linux/amd64",
+	}}
+	clients := map[string]RegistryClient{"nvcr.io/nvidia/driver": nvcr, "ghcr.io/nvidia/driver": ghcr}
+	clientFor := func(repository string) RegistryClient { return clients[repository] }
+
+	plan, err := ComputeTagPlan(context.Background(), config, kernels, defaultDriverRepositories, clientFor)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	summarize := func(images []PlannedImage) string {
+		var s []string
+		for _, image := range images {
+			s = append(s, image.Tag+"@"+image.Platform+" "+image.Repository)
+		}
+		return strings.Join(s, ", ")
+	}
+	// the amd64 image is found in the first repository, the arm64 image in the second one
+	expectedPresent := "580-6.8.0-60-generic-ubuntu24.04@amd64 nvcr.io/nvidia/driver, " +
+		"595-6.8.0-62-generic-ubuntu24.04@amd64 ghcr.io/nvidia/driver, " +
+		"580-6.8.0-60-generic-ubuntu24.04@arm64 ghcr.io/nvidia/driver"
+	if summarize(plan.Present) != expectedPresent {
+		t.Errorf("unexpected present images %s", summarize(plan.Present))
+	}
+	expectedMissing := "595-6.8.0-60-generic-ubuntu24.04@amd64 , " +
+		"595-6.8.0-60-generic-ubuntu24.04@arm64 , " +
+		"595-6.8.0-62-generic-ubuntu24.04@arm64 "
+	if summarize(plan.Missing) != expectedMissing {
+		t.Errorf("unexpected missing images %s", summarize(plan.Missing))
+	}
+	// the aws kernel is only expected for amd64 and the 580 branch
+	if len(plan.Unresolved) != 1 || plan.Unresolved[0][DimensionFlavor] != "aws" || plan.Unresolved[0][DimensionDriverBranch] != "580" {
+		t.Errorf("unexpected unresolved combinations %v", plan.Unresolved)
+	}
+	// the repositories are not listed, each of the 3 tags is looked up once per repository, in
+	// ghcr.io too as none has every platform in nvcr.io
+	if nvcr.lists != 0 || ghcr.lists != 0 {
+		t.Errorf("expected no tag list, got %d and %d", nvcr.lists, ghcr.lists)
+	}
+	if nvcr.manifests != 3 || ghcr.manifests != 3 {
+		t.Errorf("expected 3 manifest requests per repository, got %d and %d", nvcr.manifests, ghcr.manifests)
+	}
+
+	// a registry error fails the plan rather than rebuilding every image
+	ghcr.err = errors.New("503 Service Unavailable")
+	if _, err := ComputeTagPlan(context.Background(), config, kernels, defaultDriverRepositories, clientFor); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestComputeTagPlanWithOCIRegistry(t *testing.T) {
+	config := &MatrixConfig{
+		DriverBranches: []string{"580"},
+		KernelFlavors:  []string{"index", "single"},
+		Dists:          []string{"ubuntu24.04"},
+		LTSKernels:     []string{"6.8"},
+		Platforms:      []string{"amd64", "arm64"},
+	}
+	kernels := []KernelVersionEntry{
+		{Flavor: "index", Dist: "ubuntu24.04", LTSKernel: "6.8", KernelVersion: "index"},
+		{Flavor: "single", Dist: "ubuntu24.04", LTSKernel: "6.8", KernelVersion: "single"},
+	}
+	registry := &fakeRegistry{auth: "bearer"}
+	client := newFakeRegistryClient(t, registry, "", "")
+
+	plan, err := ComputeTagPlan(context.Background(), config, kernels, []string{"localhost/nvidia/driver"}, func(string) RegistryClient { return client })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the single manifest image is only built for arm64, the index has both platforms and
+	// lists arm64 with its variant
+	if len(plan.Present) != 3 || len(plan.Missing) != 1 || plan.Missing[0].Tag != "580-single-ubuntu24.04" || plan.Missing[0].Platform != "amd64" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	for _, path := range registry.requests {
+		if strings.HasSuffix(path, "/tags/list") {
+			t.Errorf("unexpected tag list request %s", path)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// CompilerMatchExact is reported when an available compiler is the one the kernel was built with
+	CompilerMatchExact = "exact"
+	// CompilerMatchTolerable is reported when an available compiler only differs by its minor version or build
+	CompilerMatchTolerable = "tolerable"
+	// CompilerMatchMismatch is reported when no available compiler can build modules for the kernel
+	CompilerMatchMismatch = "mismatch"
+)
+
+var (
+	// (Ubuntu 13.2.0-23ubuntu4) or (Debian 12.2.0-14), the version of the distribution package
+	debianCompilerBuildRegex = regexp.MustCompile(`\((?:Ubuntu|Debian) ([0-9][^)\s]*)\)`)
+	// the compiler part of a banner, up to the linker: (user@host) (gcc (GCC) 11.4.1, GNU ld version 2.35.2)
+	bannerCompilerRegex = regexp.MustCompile(`\) \((.+?), (?:GNU ld|[^,]*LLD)`)
+)
+
+// CompilerInfo is a compiler as named in a version text
+type CompilerInfo struct {
+	// Command is the compiler command, empty for the kernel compiler
+	Command  string `json:"command,omitempty"`
+	Text     string `json:"text"`
+	Compiler string `json:"compiler"`
+	Version  string `json:"version"`
+}
+
+// Major returns the major version of the compiler
+func (c *CompilerInfo) Major() string {
+	return strings.SplitN(c.Version, ".", 2)[0]
+}
+
+// buildText returns the version text without the compiler command, which differs between
+// e.g. x86_64-linux-gnu-gcc-13 and gcc-13 for the same compiler
+func (c *CompilerInfo) buildText() string {
+	text := strings.TrimSpace(c.Text)
+	if c.Compiler == "gcc" {
+		if i := strings.IndexByte(text, ' '); i >= 0 {
+			return text[i+1:]
+		}
+	}
+	return text
+}
+
+// ToolchainReport is the result of the comparison of the kernel compiler with the available ones
+type ToolchainReport struct {
+	KernelRelease string        `json:"kernelRelease"`
+	Kernel        *CompilerInfo `json:"kernel"`
+	// Source is where the kernel compiler was read from
+	Source    string          `json:"source"`
+	Available []*CompilerInfo `json:"available"`
+	Selected  *CompilerInfo   `json:"selected,omitempty"`
+	Match     string          `json:"match"`
+	// IgnoreMismatch tells the installer to skip its own compiler check
+	IgnoreMismatch     bool     `json:"ignoreMismatch"`
+	RecommendedPackage string   `json:"recommendedPackage"`
+	Reasons            []string `json:"reasons,omitempty"`
+}
+
+type toolchainOptions struct {
+	kernelRelease string
+	modulesRoot   string
+	bootDirectory string
+	kernelConfig  string
+	compilers     cli.StringSlice
+	output        string
+}
+
+func newToolchainCommand() *cli.Command {
+	opts := toolchainOptions{}
+
+	// Create the 'toolchain check' subcommand
+	check := cli.Command{}
+	check.Name = "check"
+	check.Usage = "Compare the compiler the kernel was built with to the available ones, exit with 1 on an intolerable mismatch"
+	check.UsageText = "[--kernel] [--modules-root] [--boot-dir] [--kernel-config] [--cc] [-o | --output]"
+	check.Action = func(c *cli.Context) error {
+		return CheckToolchain(c, &opts)
+	}
+	check.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "kernel",
+			Usage:       "Kernel release, the running kernel if not set",
+			Destination: &opts.kernelRelease,
+			EnvVars:     []string{"KERNEL_VERSION"},
+		},
+		&cli.StringFlag{
+			Name:        "modules-root",
+			Usage:       "Directory of the kernels modules",
+			Value:       DefaultModulesRoot,
+			Destination: &opts.modulesRoot,
+		},
+		&cli.StringFlag{
+			Name:        "boot-dir",
+			Usage:       "Directory of the kernel images and configs",
+			Value:       "/boot",
+			Destination: &opts.bootDirectory,
+		},
+		&cli.StringFlag{
+			Name:        "kernel-config",
+			Usage:       "Kernel config, searched in the modules and boot directories if not set",
+			Destination: &opts.kernelConfig,
+		},
+		&cli.StringSliceFlag{
+			Name:        "cc",
+			Usage:       "Compiler command to consider in addition to gcc, clang and their versioned commands",
+			Destination: &opts.compilers,
+			EnvVars:     []string{"CC"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env or json",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+	}
+
+	toolchain := cli.Command{}
+	toolchain.Name = "toolchain"
+	toolchain.Usage = "Check the compiler used to build the kernel modules"
+	toolchain.Subcommands = []*cli.Command{&check}
+	return &toolchain
+}
+
+// CheckToolchain selects the available compiler closest to the kernel compiler and reports
+// whether the modules it builds can be loaded
+func CheckToolchain(c *cli.Context, opts *toolchainOptions) error {
+	log.Infof("Starting 'toolchain check' with %v", c.App.Name)
+
+	kernelRelease, err := kernelReleaseOrRunning(opts.kernelRelease)
+	if err != nil {
+		return err
+	}
+	report := &ToolchainReport{KernelRelease: kernelRelease, Available: []*CompilerInfo{}}
+
+	config, configFile, err := readKernelConfig(opts, kernelRelease)
+	if err != nil {
+		log.Warnf("unable to read the kernel config: %v", err)
+	}
+	report.Kernel, report.Source, err = kernelCompiler(opts, kernelRelease, config, configFile)
+	if err != nil {
+		return err
+	}
+
+	for _, command := range compilerCandidates(report.Kernel, opts.compilers.Value()) {
+		if compiler := probeCompiler(command); compiler != nil {
+			report.Available = append(report.Available, compiler)
+		}
+	}
+	report.Match, report.Selected, report.Reasons = matchCompiler(report.Kernel, report.Available, config)
+	report.IgnoreMismatch = report.Match == CompilerMatchTolerable
+	report.RecommendedPackage = recommendedCompilerPackage(kernelRelease, report.Kernel)
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		fmt.Printf("KERNEL_CC_VERSION_TEXT=%s\n", shellQuote(report.Kernel.Text))
+		fmt.Printf("KERNEL_COMPILER=%s\n", report.Kernel.Compiler)
+		fmt.Printf("KERNEL_COMPILER_VERSION=%s\n", report.Kernel.Version)
+		if report.Selected != nil {
+			fmt.Printf("CC=%s\n", report.Selected.Command)
+		}
+		if report.Kernel.Compiler == "clang" {
+			fmt.Printf("LLVM=1\n")
+		}
+		fmt.Printf("CC_MATCH=%s\n", report.Match)
+		ignore := 0
+		if report.IgnoreMismatch {
+			ignore = 1
+		}
+		fmt.Printf("IGNORE_CC_MISMATCH=%d\n", ignore)
+		fmt.Printf("RECOMMENDED_COMPILER_PACKAGE=%s\n", report.RecommendedPackage)
+		for _, reason := range report.Reasons {
+			fmt.Printf("# %s\n", reason)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+
+	log.Infof("Completed 'toolchain check' with %v, compiler match %s", c.App.Name, report.Match)
+	if report.Match == CompilerMatchMismatch {
+		return cli.Exit(fmt.Sprintf("no compiler matching %s, install %s", report.Kernel.Text, report.RecommendedPackage), 1)
+	}
+	return nil
+}
+
+// kernelCompiler returns the compiler the kernel was built with. CONFIG_CC_VERSION_TEXT is what
+// kbuild compares the compiler of out-of-tree modules with, the banner is used without config.
+func kernelCompiler(opts *toolchainOptions, kernelRelease string, config map[string]string, configFile string) (*CompilerInfo, string, error) {
+	if text := config["CONFIG_CC_VERSION_TEXT"]; text != "" {
+		if compiler, version := parseCompilerVersion(text); compiler != "" {
+			return &CompilerInfo{Text: text, Compiler: compiler, Version: version}, configFile, nil
+		}
+		log.Warnf("unable to parse CONFIG_CC_VERSION_TEXT %q", text)
+	}
+
+	banner, source, err := kernelBanner(opts, kernelRelease)
+	if err != nil {
+		return nil, "", err
+	}
+	compiler, version := parseCompilerVersion(banner)
+	if compiler == "" {
+		return nil, "", fmt.Errorf("no compiler in the kernel banner %q", banner)
+	}
+	// keep the compiler part of the banner, e.g. gcc (GCC) 11.4.1 20231218 (Red Hat 11.4.1-3)
+	text := banner
+	if m := bannerCompilerRegex.FindStringSubmatch(banner); m != nil {
+		text = m[1]
+	}
+	return &CompilerInfo{Text: text, Compiler: compiler, Version: version}, source, nil
+}
+
+// kernelBanner reads the banner of the running kernel, the one saved next to the kernel modules,
+// or the one in the kernel image
+func kernelBanner(opts *toolchainOptions, kernelRelease string) (string, string, error) {
+	var candidates []string
+	if running, err := runningKernelRelease(); err == nil && running == kernelRelease {
+		candidates = append(candidates, "/proc/version")
+	}
+	candidates = append(candidates, filepath.Join(opts.modulesRoot, kernelRelease, "proc", "version"))
+	for _, candidate := range candidates {
+		if banner := readSysfsString(candidate); strings.HasPrefix(banner, vmlinuxBannerPrefix) {
+			return banner, candidate, nil
+		}
+	}
+
+	for _, image := range []string{filepath.Join(opts.modulesRoot, kernelRelease, "vmlinuz"), filepath.Join(opts.bootDirectory, "vmlinuz-"+kernelRelease)} {
+		data, err := os.ReadFile(image)
+		if err != nil {
+			continue
+		}
+		v, err := ReadKernelVersionString(data)
+		if err != nil {
+			log.Warnf("%s: %v", image, err)
+			continue
+		}
+		return v.Banner, image, nil
+	}
+	return "", "", fmt.Errorf("unable to find the compiler of kernel %s, no kernel config, banner or image", kernelRelease)
+}
+
+// readKernelConfig reads the CONFIG_ options of the kernel config
+func readKernelConfig(opts *toolchainOptions, kernelRelease string) (map[string]string, string, error) {
+	candidates := []string{opts.kernelConfig}
+	if opts.kernelConfig == "" {
+		candidates = []string{
+			filepath.Join(opts.modulesRoot, kernelRelease, "build", ".config"),
+			filepath.Join(opts.modulesRoot, kernelRelease, "config"),
+			filepath.Join(opts.bootDirectory, "config-"+kernelRelease),
+		}
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		config, err := readKernelConfigFile(candidate)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return config, candidate, nil
+	}
+	return nil, "", lastErr
+}
+
+func readKernelConfigFile(filename string) (map[string]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "CONFIG_") {
+			continue
+		}
+		value := parts[1]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		config[parts[0]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// compilerCandidates returns the commands which may run the kernel compiler, the versioned
+// command first, e.g. gcc-13 before gcc
+func compilerCandidates(kernel *CompilerInfo, extra []string) []string {
+	candidates := append([]string{}, extra...)
+	if kernel.Compiler == "clang" {
+		candidates = append(candidates, "clang-"+kernel.Major(), "clang")
+	} else {
+		candidates = append(candidates, "gcc-"+kernel.Major(), "gcc", "cc")
+	}
+	return uniqueStrings(candidates)
+}
+
+// probeCompiler runs 'command --version', nil if the command is not an available gcc or clang
+func probeCompiler(command string) *CompilerInfo {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return nil
+	}
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		log.Warnf("unable to run %s --version: %v", command, err)
+		return nil
+	}
+	text := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	compiler, version := parseCompilerVersion(text)
+	if compiler == "" {
+		log.Warnf("unknown compiler %s: %s", command, text)
+		return nil
+	}
+	return &CompilerInfo{Command: command, Text: text, Compiler: compiler, Version: version}
+}
+
+// matchCompiler selects the best available compiler. Modules built by another compiler, or another
+// major version, may use flags or an ABI the kernel does not agree with. A different minor version
+// is tolerable, unless the kernel uses GCC plugins which only load in the exact gcc they were built for.
+func matchCompiler(kernel *CompilerInfo, available []*CompilerInfo, config map[string]string) (string, *CompilerInfo, []string) {
+	var tolerable *CompilerInfo
+	for _, compiler := range available {
+		if compiler.Compiler != kernel.Compiler {
+			continue
+		}
+		if compiler.Version == kernel.Version && compiler.buildText() == kernel.buildText() {
+			return CompilerMatchExact, compiler, nil
+		}
+		if tolerable == nil && compiler.Major() == kernel.Major() {
+			tolerable = compiler
+		}
+	}
+
+	if tolerable != nil {
+		if config["CONFIG_GCC_PLUGINS"] == "y" && tolerable.Version != kernel.Version {
+			return CompilerMatchMismatch, nil, []string{
+				fmt.Sprintf("%s %s differs from the kernel gcc %s and the kernel uses GCC plugins", tolerable.Command, tolerable.Version, kernel.Version),
+			}
+		}
+		return CompilerMatchTolerable, tolerable, []string{
+			fmt.Sprintf("%s is %s, the kernel was built by %s", tolerable.Command, tolerable.Text, kernel.Text),
+		}
+	}
+
+	var reasons []string
+	for _, compiler := range available {
+		reasons = append(reasons, fmt.Sprintf("%s is %s %s, the kernel needs %s %s", compiler.Command, compiler.Compiler, compiler.Version, kernel.Compiler, kernel.Major()))
+	}
+	if len(available) == 0 {
+		reasons = append(reasons, fmt.Sprintf("no %s available", kernel.Compiler))
+	}
+	return CompilerMatchMismatch, nil, reasons
+}
+
+// recommendedCompilerPackage returns the distribution package of the kernel compiler, pinned to
+// the exact version where the package manager allows it
+func recommendedCompilerPackage(kernelRelease string, kernel *CompilerInfo) string {
+	distro := DistroUnknown
+	if k, err := ParseKernelRelease(kernelRelease); err == nil {
+		distro = k.Distro
+	}
+
+	switch distro {
+	case DistroUbuntu, DistroDebian:
+		pkg := kernel.Compiler + "-" + kernel.Major()
+		if m := debianCompilerBuildRegex.FindStringSubmatch(kernel.Text); m != nil {
+			pkg += "=" + m[1]
+		}
+		return pkg
+	default:
+		// dnf install gcc-11.4.1
+		return kernel.Compiler + "-" + kernel.Version
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what f prints on stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-output
+}
+
+func TestCheckToolchain(t *testing.T) {
+	const kernelRelease = "6.8.0-45-generic"
+	const ccVersionText = "x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0"
+
+	testCases := []struct {
+		description string
+		gccVersion  string
+		match       string
+		cc          string
+	}{
+		{"exact", ccVersionText, CompilerMatchExact, "CC=gcc-13\n"},
+		{"tolerable", "gcc-13 (Ubuntu 13.3.0-6ubuntu2~24.04) 13.3.0", CompilerMatchTolerable, "CC=gcc-13\n"},
+		{"mismatch", "gcc-12 (Ubuntu 12.3.0-1ubuntu1~22.04) 12.3.0", CompilerMatchMismatch, ""},
+	}
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		modulesRoot := filepath.Join(dir, "modules")
+		os.MkdirAll(filepath.Join(modulesRoot, kernelRelease, "build"), 0755)
+		os.WriteFile(filepath.Join(modulesRoot, kernelRelease, "build", ".config"), []byte("CONFIG_CC_VERSION_TEXT=\""+ccVersionText+"\"\nCONFIG_GCC_PLUGINS is not set\n"), 0644)
+		bin := filepath.Join(dir, "bin")
+		os.MkdirAll(bin, 0755)
+		writeStub(t, bin, "gcc-13", "echo '"+tc.gccVersion+"'")
+		t.Setenv("PATH", bin)
+
+		opts := &toolchainOptions{kernelRelease: kernelRelease, modulesRoot: modulesRoot, bootDirectory: filepath.Join(dir, "boot"), output: "env"}
+		var err error
+		output := captureStdout(t, func() { err = CheckToolchain(testContext(), opts) })
+		if (err != nil) != (tc.match == CompilerMatchMismatch) {
+			t.Errorf("%s: unexpected error %v", tc.description, err)
+		}
+		if !strings.Contains(output, "CC_MATCH="+tc.match+"\n") {
+			t.Errorf("%s: expected match %s, got:\n%s", tc.description, tc.match, output)
+		}
+		ccLine := ""
+		for _, line := range strings.SplitAfter(output, "\n") {
+			if strings.HasPrefix(line, "CC=") {
+				ccLine = line
+			}
+		}
+		if ccLine != tc.cc {
+			t.Errorf("%s: expected %q, got %q", tc.description, tc.cc, ccLine)
+		}
+		if !strings.Contains(output, "RECOMMENDED_COMPILER_PACKAGE=gcc-13=13.2.0-23ubuntu4\n") {
+			t.Errorf("%s: unexpected recommended package:\n%s", tc.description, output)
+		}
+	}
+}
+
+func TestReadKernelConfig(t *testing.T) {
+	dir := t.TempDir()
+	opts := &toolchainOptions{modulesRoot: filepath.Join(dir, "modules"), bootDirectory: filepath.Join(dir, "boot")}
+	if _, _, err := readKernelConfig(opts, "5.14.0-427.13.1.el9_4.x86_64"); err == nil {
+		t.Errorf("expected an error without kernel config")
+	}
+
+	os.MkdirAll(opts.bootDirectory, 0755)
+	configFile := filepath.Join(opts.bootDirectory, "config-5.14.0-427.13.1.el9_4.x86_64")
+	os.WriteFile(configFile, []byte("# CONFIG_GCC_PLUGINS is not set\nCONFIG_CC_VERSION_TEXT=\"gcc (GCC) 11.4.1 20231218 (Red Hat 11.4.1-3)\"\nCONFIG_GCC_VERSION=110401\n"), 0644)
+	config, source, err := readKernelConfig(opts, "5.14.0-427.13.1.el9_4.x86_64")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != configFile || config["CONFIG_CC_VERSION_TEXT"] != "gcc (GCC) 11.4.1 20231218 (Red Hat 11.4.1-3)" || config["CONFIG_GCC_VERSION"] != "110401" || len(config) != 2 {
+		t.Errorf("unexpected config %v from %s", config, source)
+	}
+}
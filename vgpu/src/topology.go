@@ -0,0 +1,302 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// PciClassBridgeOther is the PCI class of NVSwitch devices
+	PciClassBridgeOther = "0x0680"
+	// NVLink5ManagementVPDMarker marks the VPD of the NICs managing NVLink5 switch trays
+	NVLink5ManagementVPDMarker = "SW_MNG"
+	// topologyWaitInterval is the interval between two checks of the module prerequisites
+	topologyWaitInterval = time.Second
+)
+
+// TopologyDecision tells which fabric daemons the node needs
+type TopologyDecision string
+
+const (
+	// TopologyNone is a node without NVSwitch, no fabric daemon is needed
+	TopologyNone TopologyDecision = "none"
+	// TopologyFabricManager is a node with NVSwitch devices managed by the fabric manager
+	TopologyFabricManager TopologyDecision = "fm"
+	// TopologyFabricManagerNVLSM is an NVLink5+ node, also needing the NVLink subnet manager
+	TopologyFabricManagerNVLSM TopologyDecision = "fm+nvlsm"
+)
+
+// nvswitchGenerations maps the PCI device id of NVSwitch devices to their generation,
+// NVSwitch1 to NVSwitch3 connect NVLink2 to NVLink4 GPUs
+var nvswitchGenerations = map[string]string{
+	"0x1ac0": "NVSwitch1",
+	"0x1ac1": "NVSwitch1",
+	"0x1ac2": "NVSwitch1",
+	"0x1af1": "NVSwitch2",
+	"0x22a3": "NVSwitch3",
+}
+
+// nvlink5Prerequisites lists the modules the NVLink subnet manager needs
+var nvlink5Prerequisites = []string{"mlx5_core", "ib_umad"}
+
+// NVSwitchDevice is an NVSwitch visible on the PCI bus
+type NVSwitchDevice struct {
+	Address    string `json:"address"`
+	DeviceID   string `json:"deviceID"`
+	Generation string `json:"generation"`
+}
+
+// ManagementNIC is an InfiniBand device whose VPD marks it as managing NVLink5 switches
+type ManagementNIC struct {
+	Name         string `json:"name"`
+	Address      string `json:"address"`
+	Identifier   string `json:"identifier,omitempty"`
+	PartNumber   string `json:"partNumber,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+}
+
+// Topology is the fabric topology of the node and the resulting decision
+type Topology struct {
+	Decision              TopologyDecision `json:"decision"`
+	NVSwitchDriverDevices int              `json:"nvswitchDriverDevices"`
+	NVSwitches            []NVSwitchDevice `json:"nvswitches,omitempty"`
+	ManagementNICs        []ManagementNIC  `json:"managementNICs,omitempty"`
+	MissingModules        []string         `json:"missingModules,omitempty"`
+}
+
+type topologyOptions struct {
+	sysfsRoot string
+	procRoot  string
+	output    string
+	wait      time.Duration
+}
+
+func newTopologyCommand() *cli.Command {
+	opts := topologyOptions{}
+
+	// Create the 'topology detect' subcommand
+	detect := cli.Command{}
+	detect.Name = "detect"
+	detect.Usage = "Detect NVSwitch and NVLink5 management devices and decide which fabric daemons are needed"
+	detect.UsageText = "[--sysfs-root] [--proc-root] [-o | --output] [--wait]"
+	detect.Action = func(c *cli.Context) error {
+		return DetectTopology(c, &opts)
+	}
+	detect.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "proc-root",
+			Usage:       "Mount point of procfs",
+			Value:       DefaultProcRoot,
+			Destination: &opts.procRoot,
+			EnvVars:     []string{"VGPU_PROC_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env or json",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+		&cli.DurationFlag{
+			Name:        "wait",
+			Usage:       "Time to wait for the NVLink5 module prerequisites to be loaded, fail if still missing",
+			Destination: &opts.wait,
+		},
+	}
+
+	topology := cli.Command{}
+	topology.Name = "topology"
+	topology.Usage = "Inspect the NVLink fabric topology of the node"
+	topology.Subcommands = []*cli.Command{&detect}
+	return &topology
+}
+
+// DetectTopology prints the fabric topology decision of the node
+func DetectTopology(c *cli.Context, opts *topologyOptions) error {
+	log.Infof("Starting 'topology detect' with %v", c.App.Name)
+
+	topology, err := GetTopology(opts.sysfsRoot, opts.procRoot)
+	if err != nil {
+		return err
+	}
+
+	var waitErr error
+	if topology.Decision == TopologyFabricManagerNVLSM && opts.wait > 0 {
+		deadline := time.Now().Add(opts.wait)
+		for len(topology.MissingModules) > 0 && time.Now().Before(deadline) {
+			log.Infof("waiting for the %s kernel modules to be loaded", strings.Join(topology.MissingModules, " and "))
+			time.Sleep(topologyWaitInterval)
+			topology.MissingModules = missingModules(opts.sysfsRoot, nvlink5Prerequisites)
+		}
+		if len(topology.MissingModules) > 0 {
+			waitErr = fmt.Errorf("timed out after %v waiting for the %s kernel modules to be loaded", opts.wait, strings.Join(topology.MissingModules, " and "))
+		}
+	}
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(topology, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		var generations, addresses []string
+		for _, nvswitch := range topology.NVSwitches {
+			generations = append(generations, nvswitch.Generation)
+		}
+		for _, nic := range topology.ManagementNICs {
+			addresses = append(addresses, nic.Address)
+		}
+		fmt.Printf("FABRIC_TOPOLOGY=%s\n", topology.Decision)
+		fmt.Printf("NVSWITCH_GENERATION=%s\n", strings.Join(uniqueStrings(generations), ","))
+		fmt.Printf("NVLINK5_MANAGEMENT_DEVICES=%s\n", strings.Join(addresses, ","))
+		fmt.Printf("MISSING_MODULES=%s\n", strings.Join(topology.MissingModules, ","))
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+	if waitErr != nil {
+		return waitErr
+	}
+
+	log.Infof("Completed 'topology detect' with %v, decision %s", c.App.Name, topology.Decision)
+	return nil
+}
+
+// GetTopology inspects the NVSwitch devices and the InfiniBand devices VPD. NVLink5 systems
+// manage their switch trays through dedicated NICs, and need the NVLink subnet manager
+// in addition to the fabric manager.
+func GetTopology(sysfsRoot string, procRoot string) (*Topology, error) {
+	topology := &Topology{Decision: TopologyNone}
+
+	entries, err := os.ReadDir(filepath.Join(procRoot, "driver", "nvidia-nvswitch", "devices"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	topology.NVSwitchDriverDevices = len(entries)
+
+	devices, err := ScanPCIDevices(filepath.Join(sysfsRoot, "bus", "pci", "devices"), func(d *PCIDevice) bool {
+		return d.Vendor == NvidiaVendorID && strings.HasPrefix(d.Class, PciClassBridgeOther)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan PCI devices: %v", err)
+	}
+	for _, device := range devices {
+		generation, ok := nvswitchGenerations[device.Device]
+		if !ok {
+			generation = "unknown"
+		}
+		topology.NVSwitches = append(topology.NVSwitches, NVSwitchDevice{Address: device.Address, DeviceID: device.Device, Generation: generation})
+	}
+
+	topology.ManagementNICs, err = findManagementNICs(sysfsRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(topology.ManagementNICs) > 0:
+		topology.Decision = TopologyFabricManagerNVLSM
+		topology.MissingModules = missingModules(sysfsRoot, nvlink5Prerequisites)
+	case topology.NVSwitchDriverDevices > 0 || len(topology.NVSwitches) > 0:
+		topology.Decision = TopologyFabricManager
+	}
+	return topology, nil
+}
+
+// findManagementNICs returns the InfiniBand devices with the NVLink5 management marker in their VPD
+func findManagementNICs(sysfsRoot string) ([]ManagementNIC, error) {
+	ibRoot := filepath.Join(sysfsRoot, "class", "infiniband")
+	entries, err := os.ReadDir(ibRoot)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var nics []ManagementNIC
+	for _, entry := range entries {
+		devicePath := filepath.Join(ibRoot, entry.Name(), "device")
+		data, err := os.ReadFile(filepath.Join(devicePath, "vpd"))
+		if err != nil {
+			log.Debugf("no VPD for %s: %v", entry.Name(), err)
+			continue
+		}
+		nic := ManagementNIC{Name: entry.Name()}
+		vpd, err := ParsePCIVPD(data)
+		if err != nil {
+			// the marker is still searched in the raw bytes, as the shell implementation did
+			log.Warnf("unable to parse VPD of %s, searching the raw data: %v", entry.Name(), err)
+			if !bytes.Contains(data, []byte(NVLink5ManagementVPDMarker)) {
+				continue
+			}
+		} else {
+			if !vpd.Contains(NVLink5ManagementVPDMarker) {
+				continue
+			}
+			nic.Identifier = vpd.Identifier
+			nic.PartNumber, _ = vpd.Field("PN")
+			nic.SerialNumber, _ = vpd.Field("SN")
+		}
+		if resolved, err := filepath.EvalSymlinks(devicePath); err == nil {
+			nic.Address = filepath.Base(resolved)
+		}
+		log.Infof("Detected NVLink5+ management device %s (%s)", nic.Name, nic.Address)
+		nics = append(nics, nic)
+	}
+	return nics, nil
+}
+
+// missingModules returns the modules not present in /sys/module, built-in modules included
+func missingModules(sysfsRoot string, modules []string) []string {
+	var missing []string
+	for _, module := range modules {
+		if _, err := os.Stat(filepath.Join(sysfsRoot, "module", moduleSysfsName(module))); err != nil {
+			missing = append(missing, module)
+		}
+	}
+	return missing
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
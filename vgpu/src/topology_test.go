@@ -0,0 +1,55 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindManagementNICs(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	writeVPD := func(name string, address string, data []byte) {
+		deviceDir := filepath.Join(sysfsRoot, "devices", "pci0000:00", address)
+		os.MkdirAll(deviceDir, 0755)
+		os.WriteFile(filepath.Join(deviceDir, "vpd"), data, 0644)
+		ibDir := filepath.Join(sysfsRoot, "class", "infiniband", name)
+		os.MkdirAll(ibDir, 0755)
+		os.Symlink(deviceDir, filepath.Join(ibDir, "device"))
+	}
+
+	identifier := vpdResource(PciVPDTagIdentifier, []byte("NVIDIA ConnectX-8 SuperNIC"))
+	readOnly := vpdResource(PciVPDTagReadOnly, concat(vpdField("PN", "900-9X81Q-00CN-ST0"), vpdField("V0", NVLink5ManagementVPDMarker)))
+	writeVPD("mlx5_0", "0000:03:00.0", concat(identifier, readOnly, []byte{PciVPDTagEnd}))
+	// a VPD without end tag
+	writeVPD("mlx5_1", "0000:04:00.0", concat(identifier, readOnly))
+	writeVPD("mlx5_2", "0000:05:00.0", concat(identifier, vpdResource(PciVPDTagReadOnly, vpdField("PN", "MCX75310AAS-NEAT")), []byte{PciVPDTagEnd}))
+	// an unparsable VPD without the marker
+	writeVPD("mlx5_3", "0000:06:00.0", identifier[:8])
+
+	nics, err := findManagementNICs(sysfsRoot)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nics) != 2 {
+		t.Fatalf("expected 2 management NICs, got %+v", nics)
+	}
+	if nics[0].Name != "mlx5_0" || nics[0].Address != "0000:03:00.0" || nics[0].PartNumber != "900-9X81Q-00CN-ST0" {
+		t.Errorf("unexpected NIC %+v", nics[0])
+	}
+	if nics[1].Name != "mlx5_1" || nics[1].Address != "0000:04:00.0" || nics[1].Identifier != "" {
+		t.Errorf("unexpected NIC with a malformed VPD %+v", nics[1])
+	}
+}
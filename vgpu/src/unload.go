@@ -0,0 +1,301 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultDaemonStopTimeout indicates default time to wait for a daemon to exit after SIGTERM
+	DefaultDaemonStopTimeout = 5 * time.Second
+	// NvidiaCoreModule is the module every other NVIDIA module depends on
+	NvidiaCoreModule = "nvidia"
+)
+
+// KernelModule is a loaded kernel module as seen in /sys/module
+type KernelModule struct {
+	Name    string
+	RefCnt  int
+	Holders []string
+}
+
+// UnloadPlan is the ordered list of modules to unload and what prevents it
+type UnloadPlan struct {
+	Modules  []string
+	Blockers []string
+}
+
+type unloadOptions struct {
+	sysfsRoot string
+	procRoot  string
+	root      string
+	modules   cli.StringSlice
+	timeout   time.Duration
+	dryRun    bool
+}
+
+func newUnloadCommand() *cli.Command {
+	opts := unloadOptions{}
+
+	// Create the 'unload' subcommand
+	unload := cli.Command{}
+	unload.Name = "unload"
+	unload.Usage = "Stop the NVIDIA daemons and unload the NVIDIA kernel modules, reporting what holds the driver"
+	unload.UsageText = "[--timeout] [--module] [--dry-run] [--sysfs-root] [--proc-root] [--root]"
+	unload.Action = func(c *cli.Context) error {
+		return Unload(c, &opts)
+	}
+	unload.Flags = []cli.Flag{
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Time to wait for each daemon to exit",
+			Value:       DefaultDaemonStopTimeout,
+			Destination: &opts.timeout,
+		},
+		&cli.StringSliceFlag{
+			Name:        "module",
+			Usage:       "Additional module allowed to be unloaded when it holds an NVIDIA module",
+			Destination: &opts.modules,
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Report the unload plan without stopping daemons or unloading modules",
+			Destination: &opts.dryRun,
+		},
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "proc-root",
+			Usage:       "Mount point of procfs, use the host procfs to see processes of other containers",
+			Value:       DefaultProcRoot,
+			Destination: &opts.procRoot,
+			EnvVars:     []string{"VGPU_PROC_ROOT"},
+		},
+		&cli.StringFlag{
+			Name:        "root",
+			Usage:       "Root directory the daemon pidfiles are looked up in",
+			Value:       "/",
+			Destination: &opts.root,
+		},
+	}
+	return &unload
+}
+
+// Unload stops the NVIDIA daemons and unloads the NVIDIA kernel modules in dependency order
+func Unload(c *cli.Context, opts *unloadOptions) error {
+	log.Infof("Starting 'unload' with %v", c.App.Name)
+
+	for _, daemon := range nvidiaDaemons {
+		if err := stopDaemon(daemon, opts); err != nil {
+			return err
+		}
+	}
+
+	modules, err := ReadModuleGraph(opts.sysfsRoot, NvidiaCoreModule)
+	if err != nil {
+		return fmt.Errorf("unable to read kernel modules: %v", err)
+	}
+	if len(modules) == 0 {
+		fmt.Println("NVIDIA driver kernel modules are not loaded")
+		return nil
+	}
+
+	plan := PlanUnload(modules, NvidiaCoreModule, opts.modules.Value())
+	if len(plan.Blockers) > 0 {
+		holders, err := FindGPUHolders(opts.procRoot, DefaultPodLogRoot)
+		if err != nil {
+			log.Warnf("unable to scan processes for open NVIDIA devices: %v", err)
+		}
+		fmt.Println("Could not unload NVIDIA driver kernel modules, driver is in use:")
+		for _, blocker := range plan.Blockers {
+			fmt.Printf("  %s\n", blocker)
+		}
+		for _, holder := range holders {
+			if len(holder.Devices) == 0 {
+				continue
+			}
+			owner := ""
+			if holder.PodName != "" {
+				owner = fmt.Sprintf(" in pod %s/%s", holder.PodNamespace, holder.PodName)
+			} else if holder.PodUID != "" {
+				owner = fmt.Sprintf(" in pod %s", holder.PodUID)
+			}
+			fmt.Printf("  process %d (%s)%s has %s open\n", holder.PID, holder.Command, owner, strings.Join(holder.Devices, ", "))
+		}
+		log.Errorf("driver is in use: %s", strings.Join(plan.Blockers, "; "))
+		return fmt.Errorf("driver is in use: %s", strings.Join(plan.Blockers, "; "))
+	}
+
+	fmt.Printf("Unloading NVIDIA driver kernel modules: %s\n", strings.Join(plan.Modules, " "))
+	if opts.dryRun {
+		return nil
+	}
+	out, err := exec.Command("rmmod", plan.Modules...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("unable to unload %s: %v: %s", strings.Join(plan.Modules, " "), err, strings.TrimSpace(string(out)))
+	}
+
+	log.Infof("Completed 'unload' with %v", c.App.Name)
+	return nil
+}
+
+// stopDaemon sends SIGTERM to a daemon listed in its pidfile and waits for it to exit
+func stopDaemon(daemon NvidiaDaemon, opts *unloadOptions) error {
+	pid, err := readPIDFile(filepath.Join(opts.root, daemon.PIDFile))
+	if err != nil || !processRunning(opts.procRoot, pid) {
+		return nil
+	}
+
+	fmt.Printf("Stopping %s...\n", daemon.Description)
+	if opts.dryRun {
+		return nil
+	}
+	if err := terminateProcess(opts.procRoot, pid, opts.timeout); err != nil {
+		return fmt.Errorf("Could not stop %s: %v", daemon.Description, err)
+	}
+	log.Infof("stopped %s (pid %d)", daemon.Name, pid)
+	return nil
+}
+
+func readPIDFile(pidFile string) (int, error) {
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid in %s", pidFile)
+	}
+	return pid, nil
+}
+
+// processRunning returns true if the process exists and is not a zombie
+func processRunning(procRoot string, pid int) bool {
+	stat, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// the state follows the command name, which is enclosed in parentheses
+	i := strings.LastIndex(string(stat), ")")
+	if i < 0 || i+2 >= len(stat) {
+		return true
+	}
+	return stat[i+2] != 'Z' && stat[i+2] != 'X'
+}
+
+// moduleSysfsName returns the name of a module as listed in /sys/module
+func moduleSysfsName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// ReadModuleGraph reads the module and, recursively, all modules holding it
+func ReadModuleGraph(sysfsRoot string, root string) (map[string]*KernelModule, error) {
+	modules := map[string]*KernelModule{}
+	queue := []string{moduleSysfsName(root)}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := modules[name]; ok {
+			continue
+		}
+
+		moduleDir := filepath.Join(sysfsRoot, "module", name)
+		refcnt, err := os.ReadFile(filepath.Join(moduleDir, "refcnt"))
+		if os.IsNotExist(err) {
+			// not loaded, or built-in
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		module := &KernelModule{Name: name}
+		module.RefCnt, err = strconv.Atoi(strings.TrimSpace(string(refcnt)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid refcnt for module %s: %v", name, err)
+		}
+		holders, err := os.ReadDir(filepath.Join(moduleDir, "holders"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, holder := range holders {
+			module.Holders = append(module.Holders, holder.Name())
+			queue = append(queue, holder.Name())
+		}
+		sort.Strings(module.Holders)
+		modules[name] = module
+	}
+	return modules, nil
+}
+
+// PlanUnload orders the modules so holders are unloaded before the modules they hold,
+// and reports every reference not released by unloading these modules
+func PlanUnload(modules map[string]*KernelModule, root string, extraModules []string) UnloadPlan {
+	allowed := func(name string) bool {
+		if strings.HasPrefix(name, "nvidia") {
+			return true
+		}
+		for _, extra := range extraModules {
+			if moduleSysfsName(extra) == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var plan UnloadPlan
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		module := modules[name]
+		for _, holder := range module.Holders {
+			if _, loaded := modules[holder]; !loaded {
+				continue
+			}
+			if !allowed(holder) {
+				plan.Blockers = append(plan.Blockers, fmt.Sprintf("module %s is held by module %s", name, holder))
+				continue
+			}
+			visit(holder)
+		}
+		// every holding module accounts for one reference, the others are users of the module
+		if users := module.RefCnt - len(module.Holders); users > 0 {
+			plan.Blockers = append(plan.Blockers, fmt.Sprintf("module %s has %d references not held by modules", name, users))
+		}
+		plan.Modules = append(plan.Modules, name)
+	}
+	if _, ok := modules[moduleSysfsName(root)]; ok {
+		visit(moduleSysfsName(root))
+	}
+	return plan
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// fakeModule describes a module of a fake /sys/module tree
+type fakeModule struct {
+	refcnt  int
+	holders []string
+}
+
+// writeModuleGraph creates <sysfsRoot>/module/<name>/{refcnt,holders/*} for every module
+func writeModuleGraph(t *testing.T, sysfsRoot string, modules map[string]fakeModule) {
+	t.Helper()
+	for name, module := range modules {
+		dir := filepath.Join(sysfsRoot, "module", name)
+		if err := os.MkdirAll(filepath.Join(dir, "holders"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "refcnt"), []byte(strconv.Itoa(module.refcnt)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, holder := range module.holders {
+			// holders are symlinks to the holding module in sysfs
+			if err := os.Symlink(filepath.Join("..", "..", holder), filepath.Join(dir, "holders", holder)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPlanUnload(t *testing.T) {
+	driverModules := func() map[string]fakeModule {
+		return map[string]fakeModule{
+			"nvidia":         {refcnt: 2, holders: []string{"nvidia_uvm", "nvidia_modeset"}},
+			"nvidia_modeset": {refcnt: 1, holders: []string{"nvidia_drm"}},
+			"nvidia_drm":     {refcnt: 0},
+			"nvidia_uvm":     {refcnt: 0},
+		}
+	}
+
+	testCases := []struct {
+		description string
+		modules     map[string]fakeModule
+		extra       []string
+		expected    []string
+		blockers    int
+	}{
+		{
+			description: "holders before the modules they hold",
+			modules:     driverModules(),
+			expected:    []string{"nvidia_drm", "nvidia_modeset", "nvidia_uvm", "nvidia"},
+		},
+		{
+			description: "module in use by a process",
+			modules: func() map[string]fakeModule {
+				m := driverModules()
+				m["nvidia_uvm"] = fakeModule{refcnt: 1}
+				return m
+			}(),
+			expected: []string{"nvidia_drm", "nvidia_modeset", "nvidia_uvm", "nvidia"},
+			blockers: 1,
+		},
+		{
+			description: "module held by a foreign module",
+			modules: func() map[string]fakeModule {
+				m := driverModules()
+				m["nvidia"] = fakeModule{refcnt: 3, holders: []string{"gdrdrv", "nvidia_modeset", "nvidia_uvm"}}
+				m["gdrdrv"] = fakeModule{refcnt: 0}
+				return m
+			}(),
+			expected: []string{"nvidia_drm", "nvidia_modeset", "nvidia_uvm", "nvidia"},
+			blockers: 1,
+		},
+		{
+			description: "foreign module allowed to be unloaded",
+			modules: func() map[string]fakeModule {
+				m := driverModules()
+				m["nvidia"] = fakeModule{refcnt: 3, holders: []string{"gdrdrv", "nvidia_modeset", "nvidia_uvm"}}
+				m["gdrdrv"] = fakeModule{refcnt: 0}
+				return m
+			}(),
+			extra:    []string{"gdrdrv"},
+			expected: []string{"gdrdrv", "nvidia_drm", "nvidia_modeset", "nvidia_uvm", "nvidia"},
+		},
+		{
+			description: "module held twice is unloaded once",
+			modules: func() map[string]fakeModule {
+				m := driverModules()
+				m["nvidia"] = fakeModule{refcnt: 3, holders: []string{"nvidia_modeset", "nvidia_peermem", "nvidia_uvm"}}
+				m["nvidia_uvm"] = fakeModule{refcnt: 1, holders: []string{"nvidia_peermem"}}
+				m["nvidia_peermem"] = fakeModule{refcnt: 0}
+				return m
+			}(),
+			expected: []string{"nvidia_drm", "nvidia_modeset", "nvidia_peermem", "nvidia_uvm", "nvidia"},
+		},
+		{
+			description: "driver not loaded",
+			modules:     map[string]fakeModule{"i2c_core": {refcnt: 1}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			sysfsRoot := t.TempDir()
+			writeModuleGraph(t, sysfsRoot, tc.modules)
+
+			graph, err := ReadModuleGraph(sysfsRoot, NvidiaCoreModule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			plan := PlanUnload(graph, NvidiaCoreModule, tc.extra)
+			if !reflect.DeepEqual(plan.Modules, tc.expected) {
+				t.Errorf("expected modules %v, got %v", tc.expected, plan.Modules)
+			}
+			if len(plan.Blockers) != tc.blockers {
+				t.Errorf("expected %d blockers, got %v", tc.blockers, plan.Blockers)
+			}
+		})
+	}
+}
+
+func TestReadModuleGraphInvalidRefcnt(t *testing.T) {
+	sysfsRoot := t.TempDir()
+	writeModuleGraph(t, sysfsRoot, map[string]fakeModule{"nvidia": {}})
+	os.WriteFile(filepath.Join(sysfsRoot, "module", "nvidia", "refcnt"), []byte("-\n"), 0644)
+	if _, err := ReadModuleGraph(sysfsRoot, "nvidia"); err == nil {
+		t.Errorf("expected an error for an invalid refcnt")
+	}
+}
+
+func TestProcessRunning(t *testing.T) {
+	procRoot := t.TempDir()
+	for pid, stat := range map[string]string{
+		"100": "100 (nvidia-persiste) S 1 100 100 0 -1",
+		"200": "200 (nv-fabricmanager) Z 1 200 200 0 -1",
+		// the command name may contain parentheses and spaces
+		"300": "300 (a (b) c) R 1 300 300 0 -1",
+	} {
+		os.MkdirAll(filepath.Join(procRoot, pid), 0755)
+		os.WriteFile(filepath.Join(procRoot, pid, "stat"), []byte(stat), 0644)
+	}
+
+	for pid, expected := range map[int]bool{100: true, 200: false, 300: true, 400: false} {
+		if running := processRunning(procRoot, pid); running != expected {
+			t.Errorf("process %d: expected running %t, got %t", pid, expected, running)
+		}
+	}
+}
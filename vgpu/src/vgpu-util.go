@@ -14,6 +14,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -99,6 +100,8 @@ type PCIDeviceInfo struct {
 	config           []byte
 	name             string
 	vendorCapability []byte
+	// vendor specific capabilities of the PCIe extended configuration space
+	extVendorCapabilities [][]byte
 }
 
 // VGPUConfigInfo represents vGPU config info
@@ -136,6 +139,8 @@ var (
 const (
 	// LogFile is the path for logging
 	LogFile = "/var/log/vgpu-util.log"
+	// LogFileEnvVar overrides the path for logging
+	LogFileEnvVar = "VGPU_UTIL_LOG_FILE"
 	// DefaultInstallerDirectory indicates default location where driver installers are located
 	DefaultInstallerDirectory = "/drivers"
 	// DefaultCatalogFile indicates default location where catalog file is located
@@ -205,6 +210,28 @@ func main() {
 	c.Commands = []*cli.Command{
 		&match,
 		&count,
+		newProvisionCommand(),
+		newHostInfoCommand(),
+		newWatchCommand(),
+		newMigrateCheckCommand(),
+		newModParamsCommand(),
+		newUnloadCommand(),
+		newHoldersCommand(),
+		newSupervisorCommand(),
+		newStatusCommand(),
+		newFMConfigCommand(),
+		newTopologyCommand(),
+		newRDMACommand(),
+		newStateCommand(),
+		newKernelCommand(),
+		newPrecompiledCommand(),
+		newDTKCommand(),
+		newModulesCommand(),
+		newKernelHookCommand(),
+		newToolchainCommand(),
+		newMatrixCommand(),
+		newTagsCommand(),
+		newFirmwareCommand(),
 	}
 
 	// Match command flags
@@ -237,9 +264,14 @@ func main() {
 }
 
 func initializeLogger() (*os.File, error) {
-	logFile, err := os.OpenFile(LogFile, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
+	// the CI runners, which use the matrix and tags commands, can't write to /var/log
+	filename := LogFile
+	if value, ok := os.LookupEnv(LogFileEnvVar); ok && value != "" {
+		filename = value
+	}
+	logFile, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %s: %v", LogFile, err)
+		return nil, fmt.Errorf("error opening file %s: %v", filename, err)
 	}
 	// Log as JSON instead of the default ASCII formatter.
 	log.SetFormatter(&log.JSONFormatter{})
@@ -337,191 +369,30 @@ func FindAvailableDrivers() ([]string, error) {
 
 // FindMatch matches the vgpu driver version based on host driver version and branch
 func FindMatch(driverCatalog *VGPUDriverCatalog, availbleDriverList []string, pciDeviceInfo *PCIDeviceInfo) (string, error) {
-	var hostBranchInfo BranchDescriptor
-	var hostDriverInfo DriverDescriptor
-	var guestBranchInfoList []BranchDescriptor
-	var guestDriverInfoList []DriverDescriptor
-
-	// Process branch descriptors to select one that describes the installed host driver's branch, and a list
-	// of possible matching guest branch descriptors
-
-	for _, branch := range driverCatalog.Branch {
-		foundHostBranchInfo := false
-		if branch.Type == "host" {
-			log.Debugf("checking host branch descriptor %s", branch.Name)
-			if branch.Name == hostDriverBranch {
-				if foundHostBranchInfo {
-					// already found host branch info, log warning and skip
-					log.Warnf("Duplicate host branch info found for branch name %s", branch.Name)
-					continue
-				}
-
-				// check if allowList cpu list is present and doesn't match guestGPU, or denyList cpu list is present and matches guestGPU
-				if len(branch.Allow.GPU) > 0 && !foundGPU(branch.Allow.GPU, pciDeviceInfo) {
-					continue
-				}
-				if len(branch.Deny.GPU) > 0 && foundGPU(branch.Deny.GPU, pciDeviceInfo) {
-					continue
-				}
-
-				// check if allowList cpu list is present and doesn't match guestCPU, or denyList cpu list is present and matches guestCPU
-				if len(branch.Allow.CPU) > 0 && !foundCPU(branch.Allow.CPU) {
-					continue
-				}
-				if len(branch.Deny.CPU) > 0 && foundCPU(branch.Deny.CPU) {
-					continue
-				}
-
-				hostBranchInfo = branch
-				foundHostBranchInfo = true
-			}
-		} else if branch.Type == "guest" {
-			log.Debugf("checking guest branch descriptor %s", branch.Name)
-			// check if allowList cpu list is present and doesn't match guestGPU, or denyList cpu list is present and matches guestGPU
-			if len(branch.Allow.GPU) > 0 && !foundGPU(branch.Allow.GPU, pciDeviceInfo) {
-				continue
-			}
-			if len(branch.Deny.GPU) > 0 && foundGPU(branch.Deny.GPU, pciDeviceInfo) {
-				continue
-			}
-
-			// check if allowList cpu list is present and doesn't match guestCPU, or denyList cpu list is present and matches guestCPU
-			if len(branch.Allow.CPU) > 0 && !foundCPU(branch.Allow.CPU) {
-				continue
-			}
-			if len(branch.Deny.CPU) > 0 && foundCPU(branch.Deny.CPU) {
-				continue
-			}
-
-			if len(branch.Deny.Branch) > 0 && foundBranch(branch.Deny.Branch, hostDriverBranch) {
-				log.Infof("host branch %s matches guest denied branch list for %s, ignore...", hostDriverBranch, branch.Name)
-				continue
-			}
-			if len(branch.Allow.Branch) > 0 {
-				if !foundBranch(branch.Allow.Branch, hostDriverBranch) {
-					log.Infof("host branch %s doesn't match with guest allowed branch list for %s, ignore...", hostDriverBranch, branch.Name)
-					continue
-				}
-			}
-			guestBranchInfoList = append(guestBranchInfoList, branch)
-		}
-	}
-
-	if hostBranchInfo.Name == "" {
-		return "", fmt.Errorf("Could not find matching host branch %s in catalog file", hostDriverBranch)
+	// Evaluate the catalog rules of every guest driver against the installed host driver
+	eval, err := EvaluateCatalog(driverCatalog, hostDriverVersion, hostDriverBranch, pciDeviceInfo)
+	if err != nil {
+		return "", err
 	}
-	log.Debugf("selected hostBranchInfo for %s", hostBranchInfo.Name)
 
-	if len(guestBranchInfoList) == 0 {
+	if eval.GuestBranches == 0 {
 		return "", fmt.Errorf("Could not find guest branch info matching host branch %s in catalog file", hostDriverBranch)
 	}
-	log.Debugf("filtered %d guest branch info descriptors", len(guestBranchInfoList))
+	log.Debugf("filtered %d guest branch info descriptors", eval.GuestBranches)
 
-	validGuestBranchInfoList := []BranchDescriptor{}
-	// Filter guestBranchInfoList to ignore any guest branches made ineligible by the host branch's allow / deny lists.
-	for _, guestBranch := range guestBranchInfoList {
-		if len(hostBranchInfo.Allow.Branch) > 0 && !foundBranch(hostBranchInfo.Allow.Branch, guestBranch.Name) {
-			log.Debugf("Ignoring guest branch %s as not found in allowed list of host branch", guestBranch.Name)
-			continue
-		}
-		if foundBranch(hostBranchInfo.Deny.Branch, guestBranch.Name) {
-			log.Debugf("Ignoring guest branch %s as found in denied list of host branch", guestBranch.Name)
+	// Filter guest drivers to ignore any that are unavailable, or are made ineligible by the catalog rules
+	validGuestDriverInfoList := []DriverDescriptor{}
+	for _, verdict := range eval.Guests {
+		if !verdict.Compatible() {
+			log.Debugf("Ignoring guest driver %s: %s", verdict.Driver.Version, strings.Join(verdict.Reasons, "; "))
 			continue
 		}
-		validGuestBranchInfoList = append(validGuestBranchInfoList, guestBranch)
-	}
-
-	log.Debugf("filtered %d valid guest branch info lists", len(validGuestBranchInfoList))
-
-	// Process driver descriptors to produce a filtered list of candidate guest drivers and a driver descriptor
-	// for the host driver
-	for _, driver := range driverCatalog.Driver {
-		if driver.Type == "guest" {
-			// continue if allowList cpu list is present and doesn't match guestCPU, or denyList cpu list is present and matches guestCPU
-			if len(driver.Allow.CPU) > 0 && !foundCPU(driver.Allow.CPU) {
-				continue
-			}
-			if len(driver.Deny.CPU) > 0 && foundCPU(driver.Deny.CPU) {
-				continue
-			}
-
-			//  continue if allowList gpu list is present and doesn't match guest4PartId, or denyList gpu list is present and matches guest4PartId
-			if len(driver.Allow.GPU) > 0 && !foundGPU(driver.Allow.GPU, pciDeviceInfo) {
-				continue
-			}
-			if len(driver.Deny.GPU) > 0 && foundGPU(driver.Deny.GPU, pciDeviceInfo) {
-				continue
-			}
-
-			// continue is supported driver for Linux OS is not found
-			if !foundLinuxOS(driver.OS) {
-				continue
-			}
-
-			if len(driver.Allow.Driver) > 0 && !foundDriver(driver.Allow.Driver, hostDriverVersion) {
-				continue
-			}
-			if len(driver.Deny.Driver) > 0 && foundDriver(driver.Deny.Driver, hostDriverVersion) {
-				continue
-			}
-			for _, guestBranchDescriptor := range validGuestBranchInfoList {
-				if guestBranchDescriptor.Name == driver.Branch {
-					guestDriverInfoList = append(guestDriverInfoList, driver)
-				}
-			}
-		} else if driver.Type == "host" {
-			// continue if allowList cpu list is present and doesn't match guestCPU, or denyList cpu list is present and matches guestCPU
-			if len(driver.Allow.CPU) > 0 && !foundCPU(driver.Allow.CPU) {
-				continue
-			}
-			if len(driver.Deny.CPU) > 0 && foundCPU(driver.Deny.CPU) {
-				continue
-			}
-
-			//  continue if allowList gpu list is present and doesn't match guest4PartId, or denyList gpu list is present and matches guest4PartId
-			if len(driver.Allow.GPU) > 0 && !foundGPU(driver.Allow.GPU, pciDeviceInfo) {
-				continue
-			}
-			if len(driver.Deny.GPU) > 0 && foundGPU(driver.Deny.GPU, pciDeviceInfo) {
-				continue
-			}
-			if driver.Branch == hostDriverBranch && driver.Version == hostDriverVersion {
-				if hostDriverInfo.Version != "" {
-					// already found driver info, log warning and skip
-					log.Warnf("Duplicate driver info found for branch name %s version %s", hostDriverInfo.Branch, hostDriverInfo.Version)
-					continue
-				}
-				hostDriverInfo = driver
-			}
-		}
-	}
-
-	log.Debugf("filtered %d guest driver info lists", len(guestDriverInfoList))
-	// Filter guestDriverInfoList to ignore any guest drivers that are unavailable, or are made ineligible by a host driver's
-	// allow / deny lists.
-	validGuestDriverInfoList := []DriverDescriptor{}
-	for _, guestDriver := range guestDriverInfoList {
-		if !foundAvailableDriver(availbleDriverList, guestDriver.Version) {
+		if !foundAvailableDriver(availbleDriverList, verdict.Driver.Version) {
 			// ignore guest driver info
-			log.Debugf("Ignoring guest driver %s as its not available", guestDriver.Version)
+			log.Debugf("Ignoring guest driver %s as its not available", verdict.Driver.Version)
 			continue
 		}
-		if hostDriverInfo.Version != "" {
-			if len(hostDriverInfo.Allow.Driver) > 0 {
-				if !foundDriver(hostDriverInfo.Allow.Driver, guestDriver.Version) {
-					// ignore guest driver from guest driver info list
-					log.Debugf("Ignoring guest driver %s as its not found in allowed list", guestDriver.Version)
-					continue
-				}
-			}
-			if len(hostDriverInfo.Deny.Driver) > 0 {
-				if foundDriver(hostDriverInfo.Deny.Driver, guestDriver.Version) {
-					log.Debugf("Ignoring guest driver %s as its denied", guestDriver.Version)
-					continue
-				}
-			}
-		}
-		validGuestDriverInfoList = append(validGuestDriverInfoList, guestDriver)
+		validGuestDriverInfoList = append(validGuestDriverInfoList, verdict.Driver)
 	}
 
 	log.Debugf("filtered %d valid guest driver info lists", len(validGuestDriverInfoList))
@@ -582,45 +453,55 @@ func LoadCatalog() (*VGPUDriverCatalog, error) {
 
 // GetVGPUDevices returns all vGPU devices discovered on the host
 func GetVGPUDevices() ([]*PCIDeviceInfo, error) {
+	return getVGPUDevicesFrom(SysfsBasePath)
+}
+
+// getVGPUDevicesFrom returns all vGPU devices found under the given PCI devices directory
+func getVGPUDevicesFrom(devicesRoot string) ([]*PCIDeviceInfo, error) {
+	return getVGPUDevicesWith(devicesRoot, NewSysfsPCIConfigReader(devicesRoot))
+}
+
+// getVGPUDevicesWith returns all vGPU devices found under the given PCI devices directory,
+// reading their configuration space through configReader
+func getVGPUDevicesWith(devicesRoot string, configReader PCIConfigReader) ([]*PCIDeviceInfo, error) {
 	var deviceList []*PCIDeviceInfo
-	// fetch pci devices
-	devices, err := ioutil.ReadDir(SysfsBasePath)
+	var truncatedErr error
+	// fetch nvidia pci devices
+	devices, err := ScanPCIDevices(devicesRoot, func(d *PCIDevice) bool {
+		return d.Vendor == NvidiaVendorID
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	for _, device := range devices {
-		vendor, err := ioutil.ReadFile(path.Join(SysfsBasePath, device.Name(), "vendor"))
-		if err != nil {
-			return nil, fmt.Errorf("failed to read device vendor name for %s: %v", device.Name(), err)
+		log.Debugf("found nvidia device %s", device.Address)
+		// check subsystem-id and device-id
+		if device.Device == "" {
+			return nil, fmt.Errorf("failed to read device id for %s", device.Address)
 		}
-		if strings.TrimSpace(string(vendor)) != NvidiaVendorID {
-			continue
+		log.Debugf("got pci device id as %s for device %s", device.Device, device.Address)
+		if device.SubsystemDevice == "" {
+			return nil, fmt.Errorf("failed to read device subsystem device id for %s", device.Address)
 		}
-		log.Debugf("found nvidia device %s", device.Name())
-		// fetch subsystem-id and device-id
-		deviceID, err := ioutil.ReadFile(path.Join(SysfsBasePath, device.Name(), "device"))
-		if err != nil {
-			return nil, fmt.Errorf("failed to read device id for %s: %v", device.Name(), err)
-		}
-		deviceIDStr := strings.TrimSpace(string(deviceID))
-		log.Debugf("got pci device id as %s for device %s", deviceIDStr, device.Name())
-		subsystemID, err := ioutil.ReadFile(path.Join(SysfsBasePath, device.Name(), "subsystem_device"))
-		if err != nil {
-			return nil, fmt.Errorf("failed to read device subsystem device id for %s: %v", device.Name(), err)
-		}
-		subsystemIDStr := strings.TrimSpace(string(subsystemID))
-		log.Debugf("got pci subsystem device id as %s for device %s", subsystemIDStr, device.Name())
+		log.Debugf("got pci subsystem device id as %s for device %s", device.SubsystemDevice, device.Address)
 
 		// fetch config space
-		config, err := ioutil.ReadFile(path.Join(SysfsBasePath, device.Name(), "config"))
+		config, err := configReader.ReadConfig(device.Address)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to read PCI configuration space for %s: %v", device.Name(), err)
+			return nil, fmt.Errorf("Unable to read PCI configuration space for %s: %v", device.Address, err)
 		}
-		vgpuDevice := &PCIDeviceInfo{name: device.Name(), vendor: NvidiaVendorID, deviceID: deviceIDStr, subsystemID: subsystemIDStr, config: config}
+		vgpuDevice := &PCIDeviceInfo{name: device.Address, vendor: NvidiaVendorID, deviceID: device.Device, subsystemID: device.SubsystemDevice, config: config}
 		capability, err := getVendorSpecificCapability(vgpuDevice)
-		if err != nil {
-			return nil, fmt.Errorf("Unable to read PCI configuration space for %s: %v", device.Name(), err)
+		var truncated *PCIConfigTruncatedError
+		if errors.As(err, &truncated) && capability == nil {
+			// unprivileged, keep looking at other devices and only fail if no vGPU device is found
+			log.Warnf("%v", err)
+			truncatedErr = err
+			continue
+		}
+		if err != nil && capability == nil {
+			return nil, fmt.Errorf("Unable to read PCI configuration space for %s: %v", device.Address, err)
 		}
 		vgpuDevice.vendorCapability = capability
 
@@ -628,9 +509,20 @@ func GetVGPUDevices() ([]*PCIDeviceInfo, error) {
 		if !isVGPUDevice(vgpuDevice) {
 			continue
 		}
+
+		extCapabilities, err := getExtendedVendorSpecificCapabilities(vgpuDevice)
+		if err != nil {
+			log.Debugf("unable to walk extended capabilities of %s: %v", device.Address, err)
+		}
+		vgpuDevice.extVendorCapabilities = extCapabilities
+		log.Debugf("found %d extended vendor specific capabilities for device %s", len(extCapabilities), device.Address)
+
 		// add device to the vgpu device list
 		deviceList = append(deviceList, vgpuDevice)
 	}
+	if len(deviceList) == 0 && truncatedErr != nil {
+		return nil, truncatedErr
+	}
 	return deviceList, nil
 }
 
@@ -692,38 +584,28 @@ func foundAvailableDriver(availableDriverList []string, requiredDriver string) b
 
 // getVendorSpecificCapability returns the vendor specific capability from configuration space
 func getVendorSpecificCapability(p *PCIDeviceInfo) ([]byte, error) {
-	if len(p.config) < 256 {
-		return nil, fmt.Errorf("Entire PCI configuration is not read for device %s. Please run with privileged mode to read complete PCI configuration data", p.name)
+	capability, err := NewPCIConfigSpace(p.name, p.config).FindCapability(PciCapabilityVendorSpecificID)
+	if capability == nil {
+		return nil, err
 	}
+	return capability.Data, nil
+}
 
-	if p.config[PciStatusByte]&PciStatusCapabilityList == 0 {
+// getExtendedVendorSpecificCapabilities returns the vendor specific capabilities from the PCIe extended configuration space
+func getExtendedVendorSpecificCapabilities(p *PCIDeviceInfo) ([][]byte, error) {
+	config := NewPCIConfigSpace(p.name, p.config)
+	if !config.HasExtended() {
 		return nil, nil
 	}
-
-	var visited [256]byte
-	pos := int(getByte(p.config, PciCapabilityList))
-	for pos != 0 {
-		id := int(getByte(p.config, pos+PciCapabilityListID))
-		next := int(getByte(p.config, pos+PciCapabilityListNext))
-		length := int(getByte(p.config, pos+PciCapabilityLength))
-
-		if visited[pos] != 0 {
-			// chain looped
-			break
-		}
-		if id == 0xff {
-			// chain broken
-			break
-		}
-		if id == PciCapabilityVendorSpecificID {
-			capability := p.config[pos+PciCapabilityListID : pos+PciCapabilityListID+length]
-			return capability, nil
-		}
-
-		visited[pos]++
-		pos = next
+	capabilities, err := config.FindExtendedCapabilities(PciExtCapabilityVendorSpecificID)
+	if err != nil {
+		return nil, err
+	}
+	var data [][]byte
+	for _, capability := range capabilities {
+		data = append(data, capability.Data)
 	}
-	return nil, nil
+	return data, nil
 }
 
 // getByte returns a single byte of data at specified position
@@ -0,0 +1,475 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// vmlinuxBannerPrefix starts the version banner of the kernel, as in /proc/version
+	vmlinuxBannerPrefix = "Linux version "
+	// vmlinuxMaxSize bounds the decompressed size read while looking for the banner
+	vmlinuxMaxSize = 512 << 20
+	// lz4LegacyMagic starts the lz4 legacy frame the kernel is compressed with
+	lz4LegacyMagic = 0x184c2102
+	// lz4LegacyBlockSize is the maximum decompressed size of a lz4 legacy block
+	lz4LegacyBlockSize = 8 << 20
+)
+
+// vmlinuxCompression is a payload format a kernel image may be compressed with
+type vmlinuxCompression struct {
+	name  string
+	magic []byte
+	// open returns the decompressed stream of a payload starting with the magic
+	open func(payload []byte) (io.ReadCloser, error)
+}
+
+// vmlinuxCompressions are tried in order on every occurrence of their magic, as extract-vmlinux does.
+// The standard library decodes gzip and bzip2, the other formats are decoded here: lz4 legacy
+// frames below, xz and lzma in lzma.go and zstd in zstd.go.
+var vmlinuxCompressions = []vmlinuxCompression{
+	{"gzip", []byte{0x1f, 0x8b, 0x08}, openGzip},
+	{"xz", xzHeaderMagic, openXz},
+	{"bzip2", []byte("BZh"), openBzip2},
+	{"lzma", []byte{0x5d, 0x00, 0x00, 0x00}, openLZMA},
+	{"lz4", []byte{0x02, 0x21, 0x4c, 0x18}, openLZ4Legacy},
+	{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd}, openZstd},
+}
+
+var (
+	// clang version 17.0.6 or Ubuntu clang version 18.1.3 (1ubuntu1)
+	clangVersionRegex = regexp.MustCompile(`clang version (\d+\.\d+(?:\.\d+)?)`)
+	// gcc (GCC) 11.4.1 20231218, x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0 or gcc version 4.8.5
+	gccVersionRegex = regexp.MustCompile(`gcc(?:-[0-9.]+)?(?: version| \([^)]*\)) (\d+\.\d+(?:\.\d+)?)`)
+)
+
+// KernelVersionString is the version banner found in a kernel image
+type KernelVersionString struct {
+	Image           string `json:"image"`
+	Compression     string `json:"compression"`
+	Banner          string `json:"banner"`
+	Compiler        string `json:"compiler,omitempty"`
+	CompilerVersion string `json:"compilerVersion,omitempty"`
+}
+
+type versionStringOptions struct {
+	output string
+}
+
+func newVersionStringCommand() *cli.Command {
+	opts := versionStringOptions{}
+
+	// Create the 'kernel version-string' subcommand
+	versionString := cli.Command{}
+	versionString.Name = "version-string"
+	versionString.Usage = "Print the version banner of a kernel image and the compiler it was built with"
+	versionString.UsageText = "[-o | --output] [VMLINUZ]"
+	versionString.Description = "Uncompressed, EFI zboot and self-decompressing images compressed with gzip, bzip2, xz, lzma, lz4 or zstd are read in process"
+	versionString.Action = func(c *cli.Context) error {
+		return PrintKernelVersionString(c, &opts)
+	}
+	versionString.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Output format, env, json or banner",
+			Value:       "env",
+			Destination: &opts.output,
+		},
+	}
+	return &versionString
+}
+
+// PrintKernelVersionString prints the banner of the image given, the running kernel image if none
+func PrintKernelVersionString(c *cli.Context, opts *versionStringOptions) error {
+	image := c.Args().First()
+	if image == "" {
+		release, err := kernelReleaseOrRunning("")
+		if err != nil {
+			return err
+		}
+		image = filepath.Join(DefaultModulesRoot, release, "vmlinuz")
+	}
+	data, err := os.ReadFile(image)
+	if err != nil {
+		return fmt.Errorf("unable to read kernel image: %v", err)
+	}
+
+	v, err := ReadKernelVersionString(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", image, err)
+	}
+	v.Image = image
+	log.Debugf("found banner of %s in %s payload", image, v.Compression)
+
+	switch opts.output {
+	case "json":
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "banner":
+		fmt.Println(v.Banner)
+	case "env":
+		fmt.Printf("KERNEL_VERSION_STRING=%s\n", shellQuote(v.Banner))
+		fmt.Printf("KERNEL_IMAGE_COMPRESSION=%s\n", v.Compression)
+		fmt.Printf("KERNEL_COMPILER=%s\n", v.Compiler)
+		fmt.Printf("KERNEL_COMPILER_VERSION=%s\n", v.CompilerVersion)
+	default:
+		return fmt.Errorf("unsupported output format %s", opts.output)
+	}
+	return nil
+}
+
+// ReadKernelVersionString finds the version banner in an uncompressed kernel, an EFI zboot image
+// or a self-decompressing image, trying every payload found in the image
+func ReadKernelVersionString(data []byte) (*KernelVersionString, error) {
+	if banner, ok := findBanner(bytes.NewReader(data)); ok {
+		return newKernelVersionString(banner, "none"), nil
+	}
+
+	if payload, compression, ok := efiZbootPayload(data); ok {
+		for _, comp := range vmlinuxCompressions {
+			if comp.name == compression {
+				if banner, ok := bannerFromPayload(comp, payload); ok {
+					return newKernelVersionString(banner, "zboot+"+compression), nil
+				}
+				return nil, fmt.Errorf("no version banner in the %s payload of the EFI zboot image", compression)
+			}
+		}
+		return nil, fmt.Errorf("unsupported EFI zboot compression %q", compression)
+	}
+
+	for _, comp := range vmlinuxCompressions {
+		for offset := 0; offset < len(data); {
+			i := bytes.Index(data[offset:], comp.magic)
+			if i < 0 {
+				break
+			}
+			offset += i
+			if banner, ok := bannerFromPayload(comp, data[offset:]); ok {
+				return newKernelVersionString(banner, comp.name), nil
+			}
+			offset++
+		}
+	}
+	return nil, errors.New("no version banner found")
+}
+
+func newKernelVersionString(banner string, compression string) *KernelVersionString {
+	v := &KernelVersionString{Banner: banner, Compression: compression}
+	v.Compiler, v.CompilerVersion = parseCompilerVersion(banner)
+	return v
+}
+
+// parseCompilerVersion returns the compiler, gcc or clang, and its version named in a kernel
+// banner, a CONFIG_CC_VERSION_TEXT or the first line of 'cc --version'
+func parseCompilerVersion(s string) (string, string) {
+	if m := clangVersionRegex.FindStringSubmatch(s); m != nil {
+		return "clang", m[1]
+	}
+	if m := gccVersionRegex.FindStringSubmatch(s); m != nil {
+		return "gcc", m[1]
+	}
+	return "", ""
+}
+
+// efiZbootPayload returns the compressed payload of an EFI zboot image, as laid out by
+// drivers/firmware/efi/libstub/zboot-header.S: "MZ", "zimg" at offset 4, the payload offset and
+// size at 8 and 12 and the NUL terminated compression type at 24
+func efiZbootPayload(data []byte) ([]byte, string, bool) {
+	if len(data) < 56 || string(data[0:2]) != "MZ" || string(data[4:8]) != "zimg" {
+		return nil, "", false
+	}
+	offset := binary.LittleEndian.Uint32(data[8:12])
+	size := binary.LittleEndian.Uint32(data[12:16])
+	if uint64(offset)+uint64(size) > uint64(len(data)) {
+		return nil, "", false
+	}
+	compression := string(data[24:56])
+	if i := strings.IndexByte(compression, 0); i >= 0 {
+		compression = compression[:i]
+	}
+	return data[offset : offset+size], compression, true
+}
+
+func bannerFromPayload(comp vmlinuxCompression, payload []byte) (string, bool) {
+	r, err := comp.open(payload)
+	if err != nil {
+		return "", false
+	}
+	defer r.Close()
+	return findBanner(r)
+}
+
+// findBanner scans a stream for the version banner, stopping at the first one found. Decompression
+// errors past the banner, e.g. trailing data after the payload, are irrelevant.
+func findBanner(r io.Reader) (string, bool) {
+	const chunkSize = 1 << 20
+	prefix := []byte(vmlinuxBannerPrefix)
+	// keep the tail of the previous chunk, a banner may span two reads
+	window := make([]byte, 0, 2*chunkSize)
+	read := 0
+	buf := make([]byte, chunkSize)
+	for read < vmlinuxMaxSize {
+		n, err := io.ReadFull(r, buf)
+		window = append(window, buf[:n]...)
+		read += n
+
+		for search := 0; ; {
+			i := bytes.Index(window[search:], prefix)
+			if i < 0 {
+				break
+			}
+			start := search + i
+			end := bytes.IndexAny(window[start:], "\x00\n")
+			if end < 0 {
+				if err == nil && len(window)-start < 4096 {
+					// incomplete banner, read more
+					break
+				}
+				end = len(window) - start
+			}
+			if banner := string(window[start : start+end]); isKernelBanner(banner) {
+				return banner, true
+			}
+			search = start + 1
+		}
+
+		if err != nil {
+			return "", false
+		}
+		if len(window) > 4096 {
+			window = append(window[:0], window[len(window)-4096:]...)
+		}
+	}
+	return "", false
+}
+
+// isKernelBanner rejects the "Linux version " strings which are not a banner, e.g. format strings
+func isKernelBanner(s string) bool {
+	rest := strings.TrimPrefix(s, vmlinuxBannerPrefix)
+	return len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' && !strings.Contains(s, "%s") && strings.Contains(s, "#")
+}
+
+func openGzip(payload []byte) (io.ReadCloser, error) {
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	r.Multistream(false)
+	return r, nil
+}
+
+func openBzip2(payload []byte) (io.ReadCloser, error) {
+	return io.NopCloser(bzip2.NewReader(bytes.NewReader(payload))), nil
+}
+
+// decodeHistory holds the output of the in-process decoders: the bytes not read yet and the
+// window the matches copy from, the bytes both read and out of the window are dropped
+type decodeHistory struct {
+	data []byte
+	// read is the offset in data of the first byte not read yet
+	read int
+	// window is the distance the matches may reach back
+	window int
+	// total is the number of bytes produced
+	total int64
+}
+
+// decodeHistoryCompaction is the size of the bytes dropped at once
+const decodeHistoryCompaction = 4 << 20
+
+func newDecodeHistory() *decodeHistory {
+	return &decodeHistory{}
+}
+
+func (h *decodeHistory) put(b byte) {
+	h.data = append(h.data, b)
+	h.total++
+}
+
+func (h *decodeHistory) write(p []byte) {
+	h.data = append(h.data, p...)
+	h.total += int64(len(p))
+}
+
+// get returns the byte dist+1 bytes back, zero out of the history
+func (h *decodeHistory) get(dist uint32) byte {
+	if int64(dist) >= int64(len(h.data)) {
+		return 0
+	}
+	return h.data[len(h.data)-int(dist)-1]
+}
+
+// copyMatch appends length bytes copied from dist bytes back
+func (h *decodeHistory) copyMatch(dist int64, length int) error {
+	if dist <= 0 || dist > int64(len(h.data)) {
+		return errors.New("match distance out of bounds")
+	}
+	start := len(h.data) - int(dist)
+	if int(dist) >= length {
+		h.data = append(h.data, h.data[start:start+length]...)
+	} else {
+		// byte by byte, the match overlaps the bytes it produces
+		for i := 0; i < length; i++ {
+			h.data = append(h.data, h.data[start+i])
+		}
+	}
+	h.total += int64(length)
+	return nil
+}
+
+func (h *decodeHistory) compact() {
+	drop := min(h.read, len(h.data)-h.window)
+	if drop < decodeHistoryCompaction {
+		return
+	}
+	h.data = h.data[:copy(h.data, h.data[drop:])]
+	h.read -= drop
+}
+
+// decodeReader streams the history of a decoder, fill decodes the next step of the payload and
+// returns io.EOF once it is done
+type decodeReader struct {
+	h    *decodeHistory
+	fill func() error
+	err  error
+}
+
+func (r *decodeReader) Read(p []byte) (int, error) {
+	for r.h.read == len(r.h.data) && r.err == nil {
+		r.err = r.fill()
+	}
+	n := copy(p, r.h.data[r.h.read:])
+	r.h.read += n
+	r.h.compact()
+	if n == 0 {
+		return 0, r.err
+	}
+	return n, nil
+}
+
+func (r *decodeReader) Close() error {
+	return nil
+}
+
+// openLZ4Legacy decompresses a lz4 legacy frame: the magic, then blocks of at most 8MiB each
+// prefixed with their little endian compressed size, until the end of the data or another magic
+func openLZ4Legacy(payload []byte) (io.ReadCloser, error) {
+	if len(payload) < 4 || binary.LittleEndian.Uint32(payload) != lz4LegacyMagic {
+		return nil, errors.New("invalid lz4 legacy frame")
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		in := payload[4:]
+		out := make([]byte, 0, lz4LegacyBlockSize)
+		for len(in) >= 4 {
+			size := binary.LittleEndian.Uint32(in)
+			if size == lz4LegacyMagic || size == 0 || uint64(size) > uint64(len(in)-4) {
+				break
+			}
+			block, err := decodeLZ4Block(in[4:4+size], out[:0], lz4LegacyBlockSize)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := pw.Write(block); err != nil {
+				return
+			}
+			in = in[4+size:]
+		}
+		pw.Close()
+	}()
+	return pr, nil
+}
+
+// decodeLZ4Block decodes a lz4 block: sequences of a token, literals and a match copied from
+// the output already decoded
+func decodeLZ4Block(src, dst []byte, maxSize int) ([]byte, error) {
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literals := int(token >> 4)
+		if literals == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errors.New("lz4: truncated literal length")
+				}
+				literals += int(src[i])
+				i++
+				if src[i-1] != 255 {
+					break
+				}
+			}
+		}
+		if i+literals > len(src) || len(dst)+literals > maxSize {
+			return nil, errors.New("lz4: literals out of bounds")
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if i == len(src) {
+			// the last sequence has no match
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errors.New("lz4: truncated match offset")
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return nil, errors.New("lz4: invalid match offset")
+		}
+		length := int(token&0x0f) + 4
+		if token&0x0f == 15 {
+			for {
+				if i >= len(src) {
+					return nil, errors.New("lz4: truncated match length")
+				}
+				length += int(src[i])
+				i++
+				if src[i-1] != 255 {
+					break
+				}
+			}
+		}
+		if len(dst)+length > maxSize {
+			return nil, errors.New("lz4: match out of bounds")
+		}
+		// byte by byte, the match may overlap the bytes it produces
+		start := len(dst) - offset
+		for j := 0; j < length; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+	return dst, nil
+}
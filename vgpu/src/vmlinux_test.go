@@ -0,0 +1,228 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testBanner is the banner of the testdata/vmlinux images, each the same vmlinux compressed with
+// the tool named by its extension, 'lz4 -l' for lz4 and 'xz --format=lzma -9' for lzma as the kernel does.
+// The -x86 and -arm64 xz images use the branch filter and options of the kernel's scripts/xz_wrap.sh.
+const testBanner = "Linux version 6.8.0-45-generic (buildd@lcy02-amd64-075) (x86_64-linux-gnu-gcc-13 (Ubuntu 13.2.0-23ubuntu4) 13.2.0, GNU ld (GNU Binutils for Ubuntu) 2.42) #45-Ubuntu SMP PREEMPT_DYNAMIC Fri Aug 30 12:02:04 UTC 2024"
+
+func readVmlinuxFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "vmlinux", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// testVmlinux returns the uncompressed vmlinux of the fixtures
+func testVmlinux(t *testing.T) []byte {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(readVmlinuxFixture(t, "vmlinux.gz")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// selfDecompressingImage mimics a bzImage: a setup header and decompressor holding false magics,
+// then the payload and trailing data
+func selfDecompressingImage(payload []byte) []byte {
+	var image []byte
+	image = append(image, "MZ\x00\x00HdrS"...)
+	image = append(image, 0x1f, 0x8b, 0x08, 0x00, 0xff, 0xff)
+	image = append(image, "BZh9 not a bzip2 stream"...)
+	image = append(image, 0x02, 0x21, 0x4c, 0x18, 0xff, 0xff, 0xff, 0x7f)
+	image = append(image, make([]byte, 512)...)
+	image = append(image, payload...)
+	return append(image, make([]byte, 64)...)
+}
+
+// zbootImage lays out an EFI zboot image as drivers/firmware/efi/libstub/zboot-header.S does
+func zbootImage(payload []byte, compression string) []byte {
+	header := make([]byte, 64)
+	copy(header, "MZ")
+	copy(header[4:], "zimg")
+	binary.LittleEndian.PutUint32(header[8:], uint32(len(header)))
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(payload)))
+	copy(header[24:56], compression)
+	return append(append(header, payload...), make([]byte, 128)...)
+}
+
+func TestReadKernelVersionString(t *testing.T) {
+	testCases := []struct {
+		description string
+		fixture     string
+		compression string
+	}{
+		{"gzip", "vmlinux.gz", "gzip"},
+		{"bzip2", "vmlinux.bz2", "bzip2"},
+		{"lz4 legacy", "vmlinux.lz4", "lz4"},
+		{"xz", "vmlinux.xz", "xz"},
+		{"xz with the x86 branch filter", "vmlinux-x86.xz", "xz"},
+		{"xz with the arm64 branch filter", "vmlinux-arm64.xz", "xz"},
+		{"lzma", "vmlinux.lzma", "lzma"},
+		{"zstd", "vmlinux.zst", "zstd"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			payload := readVmlinuxFixture(t, tc.fixture)
+
+			v, err := ReadKernelVersionString(selfDecompressingImage(payload))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v.Banner != testBanner || v.Compression != tc.compression || v.Compiler != "gcc" || v.CompilerVersion != "13.2.0" {
+				t.Errorf("unexpected version string %+v", v)
+			}
+
+			v, err = ReadKernelVersionString(zbootImage(payload, tc.compression))
+			if err != nil {
+				t.Fatalf("zboot: unexpected error: %v", err)
+			}
+			if v.Banner != testBanner || v.Compression != "zboot+"+tc.compression {
+				t.Errorf("zboot: unexpected version string %+v", v)
+			}
+		})
+	}
+}
+
+func TestReadKernelVersionStringUncompressed(t *testing.T) {
+	v, err := ReadKernelVersionString(testVmlinux(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Banner != testBanner || v.Compression != "none" {
+		t.Errorf("unexpected version string %+v", v)
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		selfDecompressingImage(nil),
+		zbootImage(readVmlinuxFixture(t, "vmlinux.gz"), "lzo"),
+		// a corrupt payload
+		zbootImage(readVmlinuxFixture(t, "vmlinux.gz")[:200], "gzip"),
+	} {
+		if v, err := ReadKernelVersionString(data); err == nil {
+			t.Errorf("expected an error, got %+v", v)
+		}
+	}
+}
+
+func TestEFIZbootPayload(t *testing.T) {
+	payload := []byte("compressed payload")
+	image := zbootImage(payload, "zstd")
+	got, compression, ok := efiZbootPayload(image)
+	if !ok || compression != "zstd" || !bytes.Equal(got, payload) {
+		t.Errorf("unexpected payload %q %q %t", got, compression, ok)
+	}
+
+	// a payload past the end of the image
+	binary.LittleEndian.PutUint32(image[12:], uint32(len(image)))
+	if _, _, ok := efiZbootPayload(image); ok {
+		t.Errorf("expected an out of bounds payload to be rejected")
+	}
+	for _, image := range [][]byte{image[:40], selfDecompressingImage(payload), []byte(strings.Repeat("MZ", 64))} {
+		if _, _, ok := efiZbootPayload(image); ok {
+			t.Errorf("expected %q not to be a zboot image", image[:8])
+		}
+	}
+}
+
+func TestFindBanner(t *testing.T) {
+	const chunkSize = 1 << 20
+	banner := "Linux version 5.14.0-427.13.1.el9_4.x86_64 (mockbuild@x86-64-01.build.eng.rdu2.redhat.com) (gcc (GCC) 11.4.1 20231218 (Red Hat 11.4.1-3), GNU ld version 2.35.2-43.el9) #1 SMP PREEMPT_DYNAMIC Wed Apr 10 10:29:16 EDT 2024"
+	at := func(offset int, tail string) []byte {
+		return append(append(make([]byte, offset), banner...), tail...)
+	}
+
+	testCases := []struct {
+		description string
+		data        []byte
+		found       bool
+	}{
+		{"in the first chunk", at(100, "\n"), true},
+		{"spanning two chunks", at(chunkSize-20, "\x00"), true},
+		{"prefix split across chunks", at(chunkSize-5, "\x00"), true},
+		{"in a later chunk", at(3*chunkSize+7, "\n"), true},
+		{"unterminated at the end", at(chunkSize-20, ""), true},
+		{"format string only", append(make([]byte, 10), "Linux version %s (%s)\n"...), false},
+		{"format string before the banner", append([]byte("Linux version %s\x00"), at(chunkSize, "\n")...), true},
+		{"no banner", make([]byte, 2*chunkSize), false},
+	}
+	for _, tc := range testCases {
+		got, found := findBanner(bytes.NewReader(tc.data))
+		if found != tc.found || found && got != banner {
+			t.Errorf("%s: expected %t, got %t %q", tc.description, tc.found, found, got)
+		}
+	}
+}
+
+func TestDecodeLZ4Block(t *testing.T) {
+	testCases := []struct {
+		description string
+		src         []byte
+		maxSize     int
+		expected    string
+		expectError bool
+	}{
+		{"literals only", []byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 64, "hello", false},
+		// 1 literal, then a match of 4+2 bytes at offset 1 overlapping its output
+		{"overlapping match", []byte{0x12, 'a', 0x01, 0x00, 0x00}, 64, "aaaaaaa", false},
+		{"match then literals", []byte{0x20, 'a', 'b', 0x02, 0x00, 0x10, 'c'}, 64, "abababc", false},
+		// 15+3 literals and a 15+4+1 bytes match
+		{"extended lengths", append(append([]byte{0xff, 3}, strings.Repeat("x", 18)...), 0x01, 0x00, 1), 64, strings.Repeat("x", 38), false},
+		{"empty block", nil, 64, "", false},
+		{"zero offset", []byte{0x10, 'a', 0x00, 0x00}, 64, "", true},
+		{"offset before the output", []byte{0x10, 'a', 0x02, 0x00}, 64, "", true},
+		{"truncated literals", []byte{0x50, 'h', 'e'}, 64, "", true},
+		{"truncated literal length", []byte{0xf0, 0xff}, 64, "", true},
+		{"truncated offset", []byte{0x10, 'a', 0x01}, 64, "", true},
+		{"truncated match length", []byte{0x1f, 'a', 0x01, 0x00}, 64, "", true},
+		{"literals past the maximum size", []byte{0x50, 'h', 'e', 'l', 'l', 'o'}, 4, "", true},
+		{"match past the maximum size", []byte{0x12, 'a', 0x01, 0x00, 0x00}, 4, "", true},
+	}
+	for _, tc := range testCases {
+		got, err := decodeLZ4Block(tc.src, nil, tc.maxSize)
+		if tc.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tc.description, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.description, err)
+			continue
+		}
+		if string(got) != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.description, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+)
+
+const (
+	// DefaultWatchStateFile indicates default location of the vGPU state written by watch
+	DefaultWatchStateFile = "/run/nvidia/vgpu/state.json"
+	// DefaultWatchInterval indicates default interval between two vGPU state reads
+	DefaultWatchInterval = 30 * time.Second
+)
+
+// VGPUEventType is the kind of change detected by watch
+type VGPUEventType string
+
+const (
+	// VGPUDeviceAdded is emitted when a vGPU device is hot-plugged
+	VGPUDeviceAdded VGPUEventType = "device-added"
+	// VGPUDeviceRemoved is emitted when a vGPU device is hot-unplugged
+	VGPUDeviceRemoved VGPUEventType = "device-removed"
+	// VGPUDeviceChanged is emitted when another vGPU device, e.g. of another vGPU type, replaces one at the same address
+	VGPUDeviceChanged VGPUEventType = "device-changed"
+	// VGPUHostVersionChanged is emitted when the vGPU manager version advertised by the host changes
+	VGPUHostVersionChanged VGPUEventType = "host-version-changed"
+	// VGPUHostBranchChanged is emitted when the vGPU manager branch advertised by the host changes
+	VGPUHostBranchChanged VGPUEventType = "host-branch-changed"
+)
+
+// VGPUEvent is a structured change event printed as a JSON line
+type VGPUEvent struct {
+	Time     time.Time     `json:"time"`
+	Type     VGPUEventType `json:"type"`
+	Device   string        `json:"device,omitempty"`
+	Previous string        `json:"previous,omitempty"`
+	Current  string        `json:"current,omitempty"`
+}
+
+// VGPUState is the vGPU device set and host vGPU manager info seen by the guest
+type VGPUState struct {
+	Devices     []VGPUStateDevice `json:"devices"`
+	HostVersion string            `json:"hostVersion,omitempty"`
+	HostBranch  string            `json:"hostBranch,omitempty"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// VGPUStateDevice is a vGPU device of the state
+type VGPUStateDevice struct {
+	Address     string `json:"address"`
+	DeviceID    string `json:"deviceID"`
+	SubsystemID string `json:"subsystemID"`
+	HostVersion string `json:"hostVersion,omitempty"`
+	HostBranch  string `json:"hostBranch,omitempty"`
+}
+
+type watchOptions struct {
+	stateFile    string
+	interval     time.Duration
+	netlink      bool
+	exitOnChange bool
+	once         bool
+	sysfsRoot    string
+}
+
+func newWatchCommand() *cli.Command {
+	opts := watchOptions{}
+
+	// Create the 'watch' subcommand
+	watch := cli.Command{}
+	watch.Name = "watch"
+	watch.Usage = "Watch for vGPU hot-add/hot-remove and vGPU manager version or branch changes"
+	watch.UsageText = "[-s | --state-file] [--interval] [--netlink] [--exit-on-change] [--once] [--sysfs-root]"
+	watch.Action = func(c *cli.Context) error {
+		return Watch(c, &opts)
+	}
+	watch.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "state-file",
+			Aliases:     []string{"s"},
+			Usage:       "File the current vGPU state is written to, empty to disable",
+			Value:       DefaultWatchStateFile,
+			Destination: &opts.stateFile,
+			EnvVars:     []string{"VGPU_WATCH_STATE_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:        "interval",
+			Usage:       "Interval between two reads of the vGPU state",
+			Value:       DefaultWatchInterval,
+			Destination: &opts.interval,
+			EnvVars:     []string{"VGPU_WATCH_INTERVAL"},
+		},
+		&cli.BoolFlag{
+			Name:        "netlink",
+			Usage:       "Also re-read the vGPU state on PCI add/remove kernel uevents",
+			Destination: &opts.netlink,
+			EnvVars:     []string{"VGPU_WATCH_NETLINK"},
+		},
+		&cli.BoolFlag{
+			Name:        "exit-on-change",
+			Usage:       "Exit with a non-zero code after the first change is detected",
+			Destination: &opts.exitOnChange,
+		},
+		&cli.BoolFlag{
+			Name:        "once",
+			Usage:       "Compare the current vGPU state with the state file once and exit",
+			Destination: &opts.once,
+		},
+		&cli.StringFlag{
+			Name:        "sysfs-root",
+			Usage:       "Mount point of sysfs",
+			Value:       DefaultSysfsRoot,
+			Destination: &opts.sysfsRoot,
+			EnvVars:     []string{"VGPU_SYSFS_ROOT"},
+		},
+	}
+	return &watch
+}
+
+// Watch re-reads the vGPU devices and their vendor capability, and reports changes
+func Watch(c *cli.Context, opts *watchOptions) error {
+	log.Infof("Starting 'watch' with %v", c.App.Name)
+
+	devicesRoot := filepath.Join(opts.sysfsRoot, "bus", "pci", "devices")
+
+	previous, err := loadVGPUState(opts.stateFile)
+	if err != nil {
+		log.Warnf("Ignoring previous vGPU state: %v", err)
+	}
+
+	var uevents <-chan string
+	if opts.netlink && !opts.once {
+		uevents, err = watchPCIUevents()
+		if err != nil {
+			return fmt.Errorf("unable to listen to kernel uevents: %v", err)
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	for {
+		current, err := ReadVGPUState(devicesRoot)
+		if err != nil && opts.once {
+			return fmt.Errorf("unable to read vGPU state: %v", err)
+		}
+		if err != nil {
+			// e.g. a device hot-removed while it was read, the next read sees the settled state
+			log.Warnf("Unable to read vGPU state, retrying: %v", err)
+		} else {
+			var events []VGPUEvent
+			if previous != nil {
+				events = DiffVGPUState(previous, current)
+			}
+			for _, event := range events {
+				log.Infof("vGPU event %s device=%s previous=%s current=%s", event.Type, event.Device, event.Previous, event.Current)
+				data, _ := json.Marshal(event)
+				fmt.Println(string(data))
+			}
+			if previous == nil || len(events) > 0 {
+				if err := saveVGPUState(opts.stateFile, current); err != nil {
+					return err
+				}
+			}
+			previous = current
+
+			if len(events) > 0 && opts.exitOnChange {
+				return cli.Exit("vGPU state changed", 2)
+			}
+		}
+		if opts.once {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case uevent, ok := <-uevents:
+			if !ok {
+				log.Warnf("Kernel uevents are no longer received, falling back to polling")
+				uevents = nil
+				continue
+			}
+			log.Debugf("received PCI uevent %s", uevent)
+		case sig := <-signals:
+			log.Infof("Completed 'watch' with %v on signal %v", c.App.Name, sig)
+			return nil
+		}
+	}
+}
+
+// ReadVGPUState returns the current vGPU devices and the host vGPU manager info they advertise
+func ReadVGPUState(devicesRoot string) (*VGPUState, error) {
+	vgpuDevices, err := getVGPUDevicesFrom(devicesRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &VGPUState{Devices: []VGPUStateDevice{}, UpdatedAt: time.Now().UTC()}
+	for _, device := range vgpuDevices {
+		stateDevice := VGPUStateDevice{Address: device.name, DeviceID: device.deviceID, SubsystemID: device.subsystemID}
+		info, err := GetVGPUInfo(device)
+		if err != nil {
+			log.Warnf("unable to fetch vgpu device info for %s: %v", device.name, err)
+		} else {
+			stateDevice.HostVersion = info.version
+			stateDevice.HostBranch = info.branch
+			if state.HostVersion == "" {
+				state.HostVersion = info.version
+				state.HostBranch = info.branch
+			}
+		}
+		state.Devices = append(state.Devices, stateDevice)
+	}
+	sort.Slice(state.Devices, func(i, j int) bool { return state.Devices[i].Address < state.Devices[j].Address })
+	return state, nil
+}
+
+// DiffVGPUState returns the events that turn the previous state into the current one
+func DiffVGPUState(previous, current *VGPUState) []VGPUEvent {
+	now := time.Now().UTC()
+	var events []VGPUEvent
+
+	devices := map[string]VGPUStateDevice{}
+	for _, device := range previous.Devices {
+		devices[device.Address] = device
+	}
+	for _, device := range current.Devices {
+		if old, ok := devices[device.Address]; ok {
+			delete(devices, device.Address)
+			if old.DeviceID != device.DeviceID || old.SubsystemID != device.SubsystemID {
+				events = append(events, VGPUEvent{Time: now, Type: VGPUDeviceChanged, Device: device.Address,
+					Previous: old.DeviceID + "/" + old.SubsystemID, Current: device.DeviceID + "/" + device.SubsystemID})
+			}
+			continue
+		}
+		events = append(events, VGPUEvent{Time: now, Type: VGPUDeviceAdded, Device: device.Address, Current: device.DeviceID})
+	}
+	for _, device := range previous.Devices {
+		if _, ok := devices[device.Address]; ok {
+			events = append(events, VGPUEvent{Time: now, Type: VGPUDeviceRemoved, Device: device.Address, Previous: device.DeviceID})
+		}
+	}
+
+	// host info is only meaningful while vGPU devices are present
+	if previous.HostVersion != "" && current.HostVersion != "" {
+		if previous.HostVersion != current.HostVersion {
+			events = append(events, VGPUEvent{Time: now, Type: VGPUHostVersionChanged, Previous: previous.HostVersion, Current: current.HostVersion})
+		}
+		if previous.HostBranch != current.HostBranch {
+			events = append(events, VGPUEvent{Time: now, Type: VGPUHostBranchChanged, Previous: previous.HostBranch, Current: current.HostBranch})
+		}
+	}
+	return events
+}
+
+func loadVGPUState(stateFile string) (*VGPUState, error) {
+	if stateFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state VGPUState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", stateFile, err)
+	}
+	return &state, nil
+}
+
+func saveVGPUState(stateFile string, state *VGPUState) error {
+	if stateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(stateFile, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write vGPU state: %v", err)
+	}
+	return nil
+}
+
+// watchPCIUevents listens to kernel uevents and forwards the devpath of PCI add/remove events
+func watchPCIUevents() (<-chan string, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Pid: 0, Groups: 1}); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	events := make(chan string, 16)
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 64*1024)
+		for {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if err == syscall.EINTR || err == syscall.ENOBUFS {
+					continue
+				}
+				log.Errorf("unable to receive kernel uevent: %v", err)
+				close(events)
+				return
+			}
+			env := parseUevent(buf[:n])
+			if env["SUBSYSTEM"] != "pci" || (env["ACTION"] != "add" && env["ACTION"] != "remove") {
+				continue
+			}
+			select {
+			case events <- env["ACTION"] + "@" + env["DEVPATH"]:
+			default:
+				// a re-read is already pending
+			}
+		}
+	}()
+	return events, nil
+}
+
+// parseUevent parses the NUL separated KEY=VALUE pairs of a kernel uevent
+func parseUevent(msg []byte) map[string]string {
+	env := map[string]string{}
+	for _, field := range bytes.Split(msg, []byte{0}) {
+		kv := strings.SplitN(string(field), "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+	return env
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDiffVGPUState(t *testing.T) {
+	a4 := VGPUStateDevice{Address: "0000:00:04.0", DeviceID: "0x2236", SubsystemID: "0x1482"}
+	a5 := VGPUStateDevice{Address: "0000:00:05.0", DeviceID: "0x2236", SubsystemID: "0x1482"}
+	a5Changed := VGPUStateDevice{Address: "0000:00:05.0", DeviceID: "0x2236", SubsystemID: "0x1483"}
+
+	testCases := []struct {
+		description string
+		previous    *VGPUState
+		current     *VGPUState
+		expected    []VGPUEvent
+	}{
+		{
+			description: "no change",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4}, HostVersion: "550.54.16", HostBranch: "r550"},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a4}, HostVersion: "550.54.16", HostBranch: "r550"},
+		},
+		{
+			description: "device added",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4}},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a4, a5}},
+			expected: []VGPUEvent{
+				{Type: VGPUDeviceAdded, Device: "0000:00:05.0", Current: "0x2236"},
+			},
+		},
+		{
+			description: "device removed",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4, a5}},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a5}},
+			expected: []VGPUEvent{
+				{Type: VGPUDeviceRemoved, Device: "0000:00:04.0", Previous: "0x2236"},
+			},
+		},
+		{
+			description: "device changed at the same address",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4, a5}},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a4, a5Changed}},
+			expected: []VGPUEvent{
+				{Type: VGPUDeviceChanged, Device: "0000:00:05.0", Previous: "0x2236/0x1482", Current: "0x2236/0x1483"},
+			},
+		},
+		{
+			description: "all devices replaced",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4}},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a5}},
+			expected: []VGPUEvent{
+				{Type: VGPUDeviceAdded, Device: "0000:00:05.0", Current: "0x2236"},
+				{Type: VGPUDeviceRemoved, Device: "0000:00:04.0", Previous: "0x2236"},
+			},
+		},
+		{
+			description: "host version and branch changed",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4}, HostVersion: "550.54.16", HostBranch: "r550"},
+			current:     &VGPUState{Devices: []VGPUStateDevice{a4}, HostVersion: "570.124.03", HostBranch: "r570"},
+			expected: []VGPUEvent{
+				{Type: VGPUHostVersionChanged, Previous: "550.54.16", Current: "570.124.03"},
+				{Type: VGPUHostBranchChanged, Previous: "r550", Current: "r570"},
+			},
+		},
+		{
+			description: "host info ignored without devices",
+			previous:    &VGPUState{Devices: []VGPUStateDevice{a4}, HostVersion: "550.54.16", HostBranch: "r550"},
+			current:     &VGPUState{},
+			expected: []VGPUEvent{
+				{Type: VGPUDeviceRemoved, Device: "0000:00:04.0", Previous: "0x2236"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			events := DiffVGPUState(tc.previous, tc.current)
+			if len(events) != len(tc.expected) {
+				t.Fatalf("expected %d events, got %d: %+v", len(tc.expected), len(events), events)
+			}
+			for i, event := range events {
+				event.Time = tc.expected[i].Time
+				if event != tc.expected[i] {
+					t.Errorf("event %d: expected %+v, got %+v", i, tc.expected[i], event)
+				}
+			}
+		})
+	}
+}
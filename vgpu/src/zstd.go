@@ -0,0 +1,819 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// The zstd decoder follows RFC 8878 as far as reading a kernel image: a single frame without a
+// dictionary, the content checksum following the last block is not verified.
+
+const (
+	zstdMagic        = 0xfd2fb528
+	zstdMaxBlockSize = 128 << 10
+	zstdMaxWindowLog = 31
+
+	zstdLiteralsRaw        = 0
+	zstdLiteralsRLE        = 1
+	zstdLiteralsCompressed = 2
+	zstdLiteralsTreeless   = 3
+
+	zstdModePredefined = 0
+	zstdModeRLE        = 1
+	zstdModeCompressed = 2
+	zstdModeRepeat     = 3
+
+	huffmanMaxBits       = 11
+	huffmanMaxWeightsLog = 6
+
+	zstdMaxLiteralLengthCode = 35
+	zstdMaxMatchLengthCode   = 52
+	zstdMaxOffsetCode        = 31
+	zstdMaxLiteralLengthLog  = 9
+	zstdMaxMatchLengthLog    = 9
+	zstdMaxOffsetLog         = 8
+)
+
+var (
+	zstdLiteralLengthBase = [zstdMaxLiteralLengthCode + 1]uint32{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536,
+	}
+	zstdLiteralLengthBits = [zstdMaxLiteralLengthCode + 1]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16,
+	}
+	zstdMatchLengthBase = [zstdMaxMatchLengthCode + 1]uint32{
+		3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539,
+	}
+	zstdMatchLengthBits = [zstdMaxMatchLengthCode + 1]uint8{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16,
+	}
+
+	// the predefined distributions of the sequence codes, -1 is a probability lower than one
+	zstdLiteralLengthTable = mustBuildFSETable([]int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1,
+	}, 6)
+	zstdMatchLengthTable = mustBuildFSETable([]int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1,
+	}, 6)
+	zstdOffsetTable = mustBuildFSETable([]int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+	}, 5)
+)
+
+// forwardBitReader reads the bits of a FSE table description, least significant bit first
+type forwardBitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *forwardBitReader) peek(n uint8) uint32 {
+	var v uint64
+	for i := 0; i < 5 && r.pos/8+i < len(r.data); i++ {
+		v |= uint64(r.data[r.pos/8+i]) << (8 * i)
+	}
+	return uint32(v>>(r.pos%8)) & (1<<n - 1)
+}
+
+func (r *forwardBitReader) read(n uint8) uint32 {
+	v := r.peek(n)
+	r.pos += int(n)
+	return v
+}
+
+// backwardBitReader reads a bitstream from its end: the highest set bit of the last byte marks
+// the start, then the bits are read from the most significant down to the first bit of the data.
+// Past the first bit zeros are read and bits turns negative.
+type backwardBitReader struct {
+	data []byte
+	bits int
+}
+
+func newBackwardBitReader(data []byte) (*backwardBitReader, error) {
+	if len(data) == 0 || data[len(data)-1] == 0 {
+		return nil, errors.New("zstd: invalid bitstream padding")
+	}
+	return &backwardBitReader{data: data, bits: len(data)*8 - 8 + bits.Len8(data[len(data)-1]) - 1}, nil
+}
+
+// valueAt returns the n bits at start, zeros before the first bit of the data
+func (r *backwardBitReader) valueAt(start int, n uint8) uint64 {
+	if n == 0 {
+		return 0
+	}
+	shift := 0
+	if start < 0 {
+		shift = -start
+		if shift >= int(n) {
+			return 0
+		}
+		n -= uint8(shift)
+		start = 0
+	}
+	i := start / 8
+	var v uint64
+	if i+8 <= len(r.data) {
+		v = binary.LittleEndian.Uint64(r.data[i:])
+	} else {
+		for j := 0; i+j < len(r.data); j++ {
+			v |= uint64(r.data[i+j]) << (8 * j)
+		}
+	}
+	return (v >> (start % 8)) & (1<<n - 1) << shift
+}
+
+func (r *backwardBitReader) peek(n uint8) uint64 {
+	return r.valueAt(r.bits-int(n), n)
+}
+
+func (r *backwardBitReader) read(n uint8) uint64 {
+	r.bits -= int(n)
+	return r.valueAt(r.bits, n)
+}
+
+type fseEntry struct {
+	symbol uint8
+	bits   uint8
+	base   uint16
+}
+
+// fseTable decodes the symbols of a finite state entropy coded stream, the state is an index in
+// entries
+type fseTable struct {
+	log     uint8
+	entries []fseEntry
+}
+
+func (t *fseTable) init(r *backwardBitReader) uint16 {
+	return uint16(r.read(t.log))
+}
+
+func (t *fseTable) next(state uint16, r *backwardBitReader) uint16 {
+	e := t.entries[state]
+	return e.base + uint16(r.read(e.bits))
+}
+
+func mustBuildFSETable(probs []int16, log uint8) *fseTable {
+	t, err := buildFSETable(probs, log)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// buildFSETable spreads the symbols over the states by their probability
+func buildFSETable(probs []int16, log uint8) (*fseTable, error) {
+	size := 1 << log
+	t := &fseTable{log: log, entries: make([]fseEntry, size)}
+	next := make([]uint16, len(probs))
+	high := size - 1
+	for s, p := range probs {
+		if p == -1 {
+			t.entries[high].symbol = uint8(s)
+			high--
+			next[s] = 1
+		} else {
+			next[s] = uint16(p)
+		}
+	}
+	step := size>>1 + size>>3 + 3
+	pos := 0
+	for s, p := range probs {
+		for i := 0; i < int(p); i++ {
+			t.entries[pos].symbol = uint8(s)
+			for {
+				pos = (pos + step) & (size - 1)
+				if pos <= high {
+					break
+				}
+			}
+		}
+	}
+	if pos != 0 {
+		return nil, errors.New("zstd: invalid FSE distribution")
+	}
+	for i := range t.entries {
+		e := &t.entries[i]
+		state := next[e.symbol]
+		next[e.symbol]++
+		e.bits = log - uint8(bits.Len16(state)-1)
+		e.base = state<<e.bits - uint16(size)
+	}
+	return t, nil
+}
+
+// readFSETable decodes a FSE table description, returning the table and the bytes it takes
+func readFSETable(data []byte, maxSymbol int, maxLog uint8) (*fseTable, int, error) {
+	r := &forwardBitReader{data: data}
+	log := uint8(r.read(4)) + 5
+	if log > maxLog {
+		return nil, 0, fmt.Errorf("zstd: FSE accuracy %d too large", log)
+	}
+	remaining := 1<<log + 1
+	threshold := 1 << log
+	nbBits := log + 1
+	var probs []int16
+	for remaining > 1 && len(probs) <= maxSymbol {
+		max := 2*threshold - 1 - remaining
+		var count int
+		if low := int(r.peek(nbBits - 1)); low < max {
+			count = low
+			r.pos += int(nbBits) - 1
+		} else {
+			count = int(r.peek(nbBits))
+			if count >= threshold {
+				count -= max
+			}
+			r.pos += int(nbBits)
+		}
+		count--
+		if count < 0 {
+			remaining--
+		} else {
+			remaining -= count
+		}
+		probs = append(probs, int16(count))
+		if count == 0 {
+			// a run of zero probabilities, in steps of up to three
+			for {
+				repeat := r.read(2)
+				for i := uint32(0); i < repeat; i++ {
+					probs = append(probs, 0)
+				}
+				if repeat != 3 {
+					break
+				}
+			}
+		}
+		if remaining < 1 {
+			break
+		}
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if remaining != 1 || len(probs) > maxSymbol+1 || r.pos > len(data)*8 {
+		return nil, 0, errors.New("zstd: invalid FSE table description")
+	}
+	t, err := buildFSETable(probs, log)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, (r.pos + 7) / 8, nil
+}
+
+func newRLETable(symbol uint8) *fseTable {
+	return &fseTable{entries: []fseEntry{{symbol: symbol}}}
+}
+
+type huffmanEntry struct {
+	symbol uint8
+	bits   uint8
+}
+
+// huffmanTable decodes the literals, indexed by the next maxBits bits of the stream
+type huffmanTable struct {
+	maxBits uint8
+	entries []huffmanEntry
+}
+
+// readHuffmanTable decodes a Huffman tree description, returning the table and the bytes it takes
+func readHuffmanTable(data []byte) (*huffmanTable, int, error) {
+	if len(data) == 0 {
+		return nil, 0, errors.New("zstd: truncated Huffman tree description")
+	}
+	header := int(data[0])
+	var weights []uint8
+	size := 1
+	if header >= 128 {
+		// the weights stored directly, four bits each
+		count := header - 127
+		size += (count + 1) / 2
+		if size > len(data) {
+			return nil, 0, errors.New("zstd: truncated Huffman weights")
+		}
+		for i := 0; i < count; i++ {
+			b := data[1+i/2]
+			if i%2 == 0 {
+				weights = append(weights, b>>4)
+			} else {
+				weights = append(weights, b&0x0f)
+			}
+		}
+	} else {
+		size += header
+		if size > len(data) {
+			return nil, 0, errors.New("zstd: truncated Huffman weights")
+		}
+		var err error
+		if weights, err = decodeHuffmanWeights(data[1:size]); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// the weight of the last symbol completes the sum to a power of two
+	var sum uint32
+	for _, w := range weights {
+		if w > huffmanMaxBits {
+			return nil, 0, errors.New("zstd: invalid Huffman weight")
+		}
+		if w > 0 {
+			sum += 1 << (w - 1)
+		}
+	}
+	if sum == 0 {
+		return nil, 0, errors.New("zstd: invalid Huffman weights")
+	}
+	maxBits := uint8(bits.Len32(sum))
+	left := uint32(1)<<maxBits - sum
+	if maxBits > huffmanMaxBits || left&(left-1) != 0 {
+		return nil, 0, errors.New("zstd: invalid Huffman weights")
+	}
+	weights = append(weights, uint8(bits.Len32(left)))
+
+	// the longest codes come first, the symbols of a weight in order
+	t := &huffmanTable{maxBits: maxBits, entries: make([]huffmanEntry, 1<<maxBits)}
+	pos := 0
+	for w := uint8(1); w <= maxBits; w++ {
+		for s, sw := range weights {
+			if sw != w {
+				continue
+			}
+			e := huffmanEntry{symbol: uint8(s), bits: maxBits + 1 - w}
+			for i := 0; i < 1<<(w-1); i++ {
+				t.entries[pos] = e
+				pos++
+			}
+		}
+	}
+	return t, size, nil
+}
+
+// decodeHuffmanWeights decodes FSE compressed weights, two interleaved states sharing a table
+func decodeHuffmanWeights(data []byte) ([]uint8, error) {
+	t, n, err := readFSETable(data, 255, huffmanMaxWeightsLog)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newBackwardBitReader(data[n:])
+	if err != nil {
+		return nil, err
+	}
+	states := [2]uint16{t.init(r), t.init(r)}
+	var weights []uint8
+	for i := 0; ; i ^= 1 {
+		if len(weights) >= 254 {
+			return nil, errors.New("zstd: too many Huffman weights")
+		}
+		weights = append(weights, t.entries[states[i]].symbol)
+		states[i] = t.next(states[i], r)
+		if r.bits < 0 {
+			weights = append(weights, t.entries[states[i^1]].symbol)
+			return weights, nil
+		}
+	}
+}
+
+// decodeStream appends the n literals of a Huffman coded stream
+func (t *huffmanTable) decodeStream(dst []byte, n int, src []byte) ([]byte, error) {
+	r, err := newBackwardBitReader(src)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		e := t.entries[r.peek(t.maxBits)]
+		r.bits -= int(e.bits)
+		dst = append(dst, e.symbol)
+	}
+	if r.bits != 0 {
+		return nil, errors.New("zstd: corrupt literals stream")
+	}
+	return dst, nil
+}
+
+// zstdDecoder decodes the blocks of a zstd frame into the history
+type zstdDecoder struct {
+	in       []byte
+	pos      int
+	h        *decodeHistory
+	last     bool
+	literals []byte
+	// the tables and offsets the following blocks may repeat
+	huffman       *huffmanTable
+	literalLength *fseTable
+	offset        *fseTable
+	matchLength   *fseTable
+	reps          [3]uint32
+}
+
+// openZstd decompresses the first frame of a zstd payload
+func openZstd(payload []byte) (io.ReadCloser, error) {
+	if len(payload) < 6 || binary.LittleEndian.Uint32(payload) != zstdMagic {
+		return nil, errors.New("zstd: invalid frame magic")
+	}
+	descriptor := payload[4]
+	if descriptor&0x08 != 0 {
+		return nil, errors.New("zstd: reserved frame header bit set")
+	}
+	singleSegment := descriptor&0x20 != 0
+	pos := 5
+	window := 0
+	if !singleSegment {
+		exponent := int(payload[pos] >> 3)
+		mantissa := int(payload[pos] & 0x07)
+		pos++
+		if 10+exponent > zstdMaxWindowLog {
+			return nil, errors.New("zstd: window too large")
+		}
+		base := 1 << (10 + exponent)
+		window = base + base/8*mantissa
+	}
+	dictionaryIDSize := [4]int{0, 1, 2, 4}[descriptor&0x03]
+	contentSizeSize := [4]int{0, 2, 4, 8}[descriptor>>6]
+	if contentSizeSize == 0 && singleSegment {
+		contentSizeSize = 1
+	}
+	if pos+dictionaryIDSize+contentSizeSize > len(payload) {
+		return nil, errors.New("zstd: truncated frame header")
+	}
+	for _, b := range payload[pos : pos+dictionaryIDSize] {
+		if b != 0 {
+			return nil, errors.New("zstd: dictionaries are not supported")
+		}
+	}
+	pos += dictionaryIDSize
+	var contentSize uint64
+	for i, b := range payload[pos : pos+contentSizeSize] {
+		contentSize |= uint64(b) << (8 * i)
+	}
+	if contentSizeSize == 2 {
+		contentSize += 256
+	}
+	pos += contentSizeSize
+	if singleSegment {
+		if contentSize > 1<<zstdMaxWindowLog {
+			return nil, errors.New("zstd: window too large")
+		}
+		window = int(contentSize)
+	}
+
+	d := &zstdDecoder{in: payload, pos: pos, h: newDecodeHistory(), reps: [3]uint32{1, 4, 8}}
+	d.h.window = window
+	return &decodeReader{h: d.h, fill: d.fill}, nil
+}
+
+// fill decodes the next block, returning io.EOF after the last one
+func (d *zstdDecoder) fill() error {
+	if d.last {
+		return io.EOF
+	}
+	if d.pos+3 > len(d.in) {
+		return errors.New("zstd: truncated block header")
+	}
+	header := uint32(d.in[d.pos]) | uint32(d.in[d.pos+1])<<8 | uint32(d.in[d.pos+2])<<16
+	d.pos += 3
+	d.last = header&1 != 0
+	size := int(header >> 3)
+	if size > zstdMaxBlockSize {
+		return errors.New("zstd: block too large")
+	}
+
+	switch (header >> 1) & 0x03 {
+	case 0:
+		if d.pos+size > len(d.in) {
+			return errors.New("zstd: truncated raw block")
+		}
+		d.h.write(d.in[d.pos : d.pos+size])
+		d.pos += size
+	case 1:
+		if d.pos >= len(d.in) {
+			return errors.New("zstd: truncated RLE block")
+		}
+		for i := 0; i < size; i++ {
+			d.h.put(d.in[d.pos])
+		}
+		d.pos++
+	case 2:
+		if d.pos+size > len(d.in) {
+			return errors.New("zstd: truncated compressed block")
+		}
+		if err := d.decodeBlock(d.in[d.pos : d.pos+size]); err != nil {
+			return err
+		}
+		d.pos += size
+	default:
+		return errors.New("zstd: reserved block type")
+	}
+	return nil
+}
+
+// decodeBlock decodes the literals section, then executes the sequences copying the literals and
+// the matches
+func (d *zstdDecoder) decodeBlock(block []byte) error {
+	n, err := d.decodeLiterals(block)
+	if err != nil {
+		return err
+	}
+	data := block[n:]
+	if len(data) == 0 {
+		return errors.New("zstd: truncated sequences section")
+	}
+
+	count := int(data[0])
+	switch {
+	case count < 128:
+		data = data[1:]
+	case count < 255:
+		if len(data) < 2 {
+			return errors.New("zstd: truncated sequences section")
+		}
+		count = (count-128)<<8 + int(data[1])
+		data = data[2:]
+	default:
+		if len(data) < 3 {
+			return errors.New("zstd: truncated sequences section")
+		}
+		count = int(data[1]) + int(data[2])<<8 + 0x7f00
+		data = data[3:]
+	}
+	if count == 0 {
+		d.h.write(d.literals)
+		return nil
+	}
+
+	if len(data) == 0 {
+		return errors.New("zstd: truncated sequences section")
+	}
+	modes := data[0]
+	if modes&0x03 != 0 {
+		return errors.New("zstd: reserved sequences modes bits set")
+	}
+	data = data[1:]
+	ll, n, err := sequenceTable(data, modes>>6, &d.literalLength, zstdLiteralLengthTable, zstdMaxLiteralLengthCode, zstdMaxLiteralLengthLog)
+	if err != nil {
+		return err
+	}
+	data = data[n:]
+	of, n, err := sequenceTable(data, (modes>>4)&0x03, &d.offset, zstdOffsetTable, zstdMaxOffsetCode, zstdMaxOffsetLog)
+	if err != nil {
+		return err
+	}
+	data = data[n:]
+	ml, n, err := sequenceTable(data, (modes>>2)&0x03, &d.matchLength, zstdMatchLengthTable, zstdMaxMatchLengthCode, zstdMaxMatchLengthLog)
+	if err != nil {
+		return err
+	}
+	data = data[n:]
+
+	r, err := newBackwardBitReader(data)
+	if err != nil {
+		return err
+	}
+	llState := ll.init(r)
+	ofState := of.init(r)
+	mlState := ml.init(r)
+	literals := d.literals
+	for i := 0; i < count; i++ {
+		llCode := ll.entries[llState].symbol
+		ofCode := of.entries[ofState].symbol
+		mlCode := ml.entries[mlState].symbol
+		if llCode > zstdMaxLiteralLengthCode || ofCode > zstdMaxOffsetCode || mlCode > zstdMaxMatchLengthCode {
+			return errors.New("zstd: invalid sequence code")
+		}
+		offset := uint32(1)<<ofCode + uint32(r.read(ofCode))
+		matchLength := zstdMatchLengthBase[mlCode] + uint32(r.read(zstdMatchLengthBits[mlCode]))
+		literalLength := zstdLiteralLengthBase[llCode] + uint32(r.read(zstdLiteralLengthBits[llCode]))
+		if i < count-1 {
+			llState = ll.next(llState, r)
+			mlState = ml.next(mlState, r)
+			ofState = of.next(ofState, r)
+		}
+
+		offset, err = d.repeatOffset(offset, literalLength)
+		if err != nil {
+			return err
+		}
+		if int(literalLength) > len(literals) {
+			return errors.New("zstd: literal length out of bounds")
+		}
+		d.h.write(literals[:literalLength])
+		literals = literals[literalLength:]
+		if err := d.h.copyMatch(int64(offset), int(matchLength)); err != nil {
+			return fmt.Errorf("zstd: %v", err)
+		}
+	}
+	if r.bits != 0 {
+		return errors.New("zstd: corrupt sequences bitstream")
+	}
+	d.h.write(literals)
+	return nil
+}
+
+// repeatOffset resolves an offset value to an offset, values up to 3 select a repeated offset
+func (d *zstdDecoder) repeatOffset(value uint32, literalLength uint32) (uint32, error) {
+	if value > 3 {
+		offset := value - 3
+		d.reps = [3]uint32{offset, d.reps[0], d.reps[1]}
+		return offset, nil
+	}
+	i := value - 1
+	if literalLength == 0 {
+		i++
+	}
+	var offset uint32
+	switch i {
+	case 0:
+		return d.reps[0], nil
+	case 1:
+		offset = d.reps[1]
+		d.reps = [3]uint32{offset, d.reps[0], d.reps[2]}
+	case 2:
+		offset = d.reps[2]
+		d.reps = [3]uint32{offset, d.reps[0], d.reps[1]}
+	default:
+		offset = d.reps[0] - 1
+		d.reps = [3]uint32{offset, d.reps[0], d.reps[1]}
+	}
+	if offset == 0 {
+		return 0, errors.New("zstd: invalid repeated offset")
+	}
+	return offset, nil
+}
+
+// sequenceTable returns the table of a sequence code for its mode, and saves it for the blocks
+// repeating it
+func sequenceTable(data []byte, mode uint8, saved **fseTable, predefined *fseTable, maxSymbol int, maxLog uint8) (*fseTable, int, error) {
+	switch mode {
+	case zstdModePredefined:
+		*saved = predefined
+		return predefined, 0, nil
+	case zstdModeRLE:
+		if len(data) == 0 || int(data[0]) > maxSymbol {
+			return nil, 0, errors.New("zstd: invalid RLE sequence code")
+		}
+		*saved = newRLETable(data[0])
+		return *saved, 1, nil
+	case zstdModeCompressed:
+		t, n, err := readFSETable(data, maxSymbol, maxLog)
+		if err != nil {
+			return nil, 0, err
+		}
+		*saved = t
+		return t, n, nil
+	default:
+		if *saved == nil {
+			return nil, 0, errors.New("zstd: no sequence table to repeat")
+		}
+		return *saved, 0, nil
+	}
+}
+
+// decodeLiterals decodes the literals section of a block, returning the bytes it takes
+func (d *zstdDecoder) decodeLiterals(block []byte) (int, error) {
+	if len(block) == 0 {
+		return 0, errors.New("zstd: truncated literals section")
+	}
+	kind := block[0] & 0x03
+	sizeFormat := (block[0] >> 2) & 0x03
+
+	if kind == zstdLiteralsRaw || kind == zstdLiteralsRLE {
+		var size, header int
+		switch sizeFormat {
+		case 0, 2:
+			size, header = int(block[0]>>3), 1
+		case 1:
+			if len(block) < 2 {
+				return 0, errors.New("zstd: truncated literals header")
+			}
+			size, header = int(block[0]>>4)|int(block[1])<<4, 2
+		default:
+			if len(block) < 3 {
+				return 0, errors.New("zstd: truncated literals header")
+			}
+			size, header = int(block[0]>>4)|int(block[1])<<4|int(block[2])<<12, 3
+		}
+		if size > zstdMaxBlockSize {
+			return 0, errors.New("zstd: too many literals")
+		}
+		if kind == zstdLiteralsRaw {
+			if header+size > len(block) {
+				return 0, errors.New("zstd: truncated raw literals")
+			}
+			d.literals = append(d.literals[:0], block[header:header+size]...)
+			return header + size, nil
+		}
+		if header >= len(block) {
+			return 0, errors.New("zstd: truncated RLE literals")
+		}
+		d.literals = d.literals[:0]
+		for i := 0; i < size; i++ {
+			d.literals = append(d.literals, block[header])
+		}
+		return header + 1, nil
+	}
+
+	var regenerated, compressed, header int
+	streams := 4
+	switch sizeFormat {
+	case 0, 1:
+		if len(block) < 3 {
+			return 0, errors.New("zstd: truncated literals header")
+		}
+		v := uint32(block[0]) | uint32(block[1])<<8 | uint32(block[2])<<16
+		regenerated, compressed, header = int(v>>4)&0x3ff, int(v>>14)&0x3ff, 3
+		if sizeFormat == 0 {
+			streams = 1
+		}
+	case 2:
+		if len(block) < 4 {
+			return 0, errors.New("zstd: truncated literals header")
+		}
+		v := binary.LittleEndian.Uint32(block)
+		regenerated, compressed, header = int(v>>4)&0x3fff, int(v>>18)&0x3fff, 4
+	default:
+		if len(block) < 5 {
+			return 0, errors.New("zstd: truncated literals header")
+		}
+		v := uint64(binary.LittleEndian.Uint32(block)) | uint64(block[4])<<32
+		regenerated, compressed, header = int(v>>4)&0x3ffff, int(v>>22)&0x3ffff, 5
+	}
+	if regenerated > zstdMaxBlockSize {
+		return 0, errors.New("zstd: too many literals")
+	}
+	if header+compressed > len(block) {
+		return 0, errors.New("zstd: truncated compressed literals")
+	}
+	data := block[header : header+compressed]
+	if kind == zstdLiteralsCompressed {
+		t, n, err := readHuffmanTable(data)
+		if err != nil {
+			return 0, err
+		}
+		d.huffman = t
+		data = data[n:]
+	} else if d.huffman == nil {
+		return 0, errors.New("zstd: no Huffman table to repeat")
+	}
+
+	var err error
+	d.literals = d.literals[:0]
+	if streams == 1 {
+		d.literals, err = d.huffman.decodeStream(d.literals, regenerated, data)
+		return header + compressed, err
+	}
+
+	// a jump table of the sizes of the first three streams, the fourth takes the rest
+	if len(data) < 6 {
+		return 0, errors.New("zstd: truncated literals jump table")
+	}
+	sizes := [4]int{int(binary.LittleEndian.Uint16(data)), int(binary.LittleEndian.Uint16(data[2:])), int(binary.LittleEndian.Uint16(data[4:]))}
+	data = data[6:]
+	sizes[3] = len(data) - sizes[0] - sizes[1] - sizes[2]
+	segment := (regenerated + 3) / 4
+	if sizes[3] < 0 || regenerated < 3*segment {
+		return 0, errors.New("zstd: invalid literals jump table")
+	}
+	for i, size := range sizes {
+		n := segment
+		if i == 3 {
+			n = regenerated - 3*segment
+		}
+		if d.literals, err = d.huffman.decodeStream(d.literals, n, data[:size]); err != nil {
+			return 0, err
+		}
+		data = data[size:]
+	}
+	return header + compressed, nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2026, NVIDIA CORPORATION.  All rights reserved.
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+func TestOpenZstd(t *testing.T) {
+	testDecompress(t, openZstd, "decoder.zst")
+
+	for _, data := range [][]byte{
+		nil,
+		{0x28, 0xb5, 0x2f, 0xfd},
+		// a reserved frame header bit
+		{0x28, 0xb5, 0x2f, 0xfd, 0x08, 0x00, 0x01, 0x00, 0x00},
+		// a dictionary
+		{0x28, 0xb5, 0x2f, 0xfd, 0x01, 0x00, 0x01, 0x01, 0x00, 0x00},
+	} {
+		if _, err := openZstd(data); err == nil {
+			t.Errorf("expected an error for %x", data)
+		}
+	}
+}
+
+func TestOpenZstdRawAndRLEBlocks(t *testing.T) {
+	frame := []byte{
+		// single segment, content size 8
+		0x28, 0xb5, 0x2f, 0xfd, 0x20, 0x08,
+		// raw block of 3 bytes
+		0x18, 0x00, 0x00, 'a', 'b', 'c',
+		// last block, RLE of 5 bytes
+		0x2b, 0x00, 0x00, 'x',
+	}
+	r, err := openZstd(frame)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(data) != "abcxxxxx" {
+		t.Errorf("unexpected output %q", data)
+	}
+}